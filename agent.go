@@ -9,6 +9,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/go-kratos/blades/skills"
 	"github.com/go-kratos/blades/tools"
@@ -58,13 +59,29 @@ func WithInputSchema(schema *jsonschema.Schema) AgentOption {
 	}
 }
 
-// WithOutputSchema sets the output schema for the Agent.
+// WithOutputSchema sets the output schema for the Agent. The schema is sent to
+// the model, and the final answer is validated against it: an answer that is
+// not a conforming JSON value, optionally wrapped in a Markdown code fence, is
+// sent back for repair as set by WithOutputRepair, and fails the run with
+// ErrInvalidOutput once the repairs are used up.
 func WithOutputSchema(schema *jsonschema.Schema) AgentOption {
 	return func(a *agent) {
 		a.outputSchema = schema
 	}
 }
 
+// WithOutputRepair sets how many times the Agent re-prompts the model when its
+// final answer does not conform to the output schema. Each repair attempt
+// sends the validation errors back to the model and counts as an iteration.
+// The default is one repair; with 0 a non-conforming answer fails the run with
+// ErrInvalidOutput at once. In streaming mode the chunks of a rejected answer
+// have already been yielded when the repair starts.
+func WithOutputRepair(attempts int) AgentOption {
+	return func(a *agent) {
+		a.outputRepairs = attempts
+	}
+}
+
 // WithOutputKey sets the output key for storing the Agent's output in the session state.
 func WithOutputKey(key string) AgentOption {
 	return func(a *agent) {
@@ -102,6 +119,56 @@ func WithMiddleware(ms ...Middleware) AgentOption {
 	}
 }
 
+// WithModelMiddleware adds hooks that run around every model call of the
+// Agent loop. Unlike WithMiddleware, which wraps a whole run, the hooks see
+// each ModelRequest and ModelResponse and can rewrite or short-circuit them.
+func WithModelMiddleware(hooks ...ModelHooks) AgentOption {
+	return func(a *agent) {
+		a.modelHooks = append(a.modelHooks, hooks...)
+	}
+}
+
+// WithToolCallMiddleware adds hooks that run around every tool call executed
+// by the Agent. They can rewrite a call, veto or mock it, or rewrite its result.
+func WithToolCallMiddleware(hooks ...ToolCallHooks) AgentOption {
+	return func(a *agent) {
+		a.toolHooks = append(a.toolHooks, hooks...)
+	}
+}
+
+// WithToolApproval sets a policy that selects tool calls requiring human
+// approval. Selected calls are not executed: the run stops with an
+// InterruptError holding the pending calls, which stay in the session with
+// Completed=false until the run is resumed with WithToolDecisions.
+func WithToolApproval(policy ToolApprovalPolicy) AgentOption {
+	return func(a *agent) {
+		a.toolApproval = policy
+	}
+}
+
+// WithToolValidation controls whether tool-call arguments are validated against
+// the tool's InputSchema before the tool is invoked. Invalid arguments are not
+// passed to the tool; the model receives a structured error describing the
+// violations as the tool response, so it can correct the call.
+func WithToolValidation(enabled bool) AgentOption {
+	return func(a *agent) {
+		a.toolValidation = enabled
+	}
+}
+
+// WithToolArgumentCoercion controls whether simple type mismatches in tool-call
+// arguments are repaired before validation: numeric and boolean values sent as
+// strings are converted, and missing optional properties receive their schema
+// defaults. Enabling coercion also enables WithToolValidation.
+func WithToolArgumentCoercion(enabled bool) AgentOption {
+	return func(a *agent) {
+		a.toolCoercion = enabled
+		if enabled {
+			a.toolValidation = true
+		}
+	}
+}
+
 // WithMaxIterations sets the maximum number of iterations for the Agent.
 // By default, it is set to 10.
 func WithMaxIterations(n int) AgentOption {
@@ -122,6 +189,16 @@ func WithContext(enabled bool) AgentOption {
 	}
 }
 
+// WithGenerationConfig sets the generation settings sent with every model
+// request of the Agent, such as its temperature. Providers merge them over
+// their own configuration. A BeforeModel hook may change req.Generation to
+// adjust them for a single iteration.
+func WithGenerationConfig(config GenerationConfig) AgentOption {
+	return func(a *agent) {
+		a.generation = config.Clone()
+	}
+}
+
 // agent is a struct that represents an AI agent.
 type agent struct {
 	name                string
@@ -129,16 +206,32 @@ type agent struct {
 	instruction         string
 	instructionProvider InstructionProvider
 	outputKey           string
+	outputRepairs       int
 	maxIterations       int
 	model               ModelProvider
 	inputSchema         *jsonschema.Schema
 	outputSchema        *jsonschema.Schema
 	middlewares         []Middleware
+	modelHooks          []ModelHooks
+	toolHooks           []ToolCallHooks
 	tools               []tools.Tool
 	skills              []skills.Skill
 	skillToolset        *skills.Toolset
 	toolsResolver       tools.Resolver // Optional resolver for dynamic tools (e.g., MCP servers)
+	toolApproval        ToolApprovalPolicy
+	toolValidation      bool           // Whether to validate tool arguments against InputSchema
+	toolCoercion        bool           // Whether to coerce simple type mismatches in tool arguments
+	schemas             schemaResolver // Cache of resolved input and output schemas
 	useContext          bool           // Whether to load session history into each model call
+	toolTimeout         time.Duration
+	toolSettings        map[string]ToolSettings
+	toolConcurrency     int
+	toolTimeoutPolicy   ToolTimeoutPolicy
+	toolOutput          ToolOutputPolicy
+	toolOutputStore     ArtifactStore
+	generation          *GenerationConfig
+	toolChoice          *ToolChoice
+	toolChoiceProvider  ToolChoiceProvider
 }
 
 // NewAgent creates a new Agent with the given name and options.
@@ -146,6 +239,7 @@ func NewAgent(name string, opts ...AgentOption) (Agent, error) {
 	a := &agent{
 		name:          name,
 		maxIterations: 10,
+		outputRepairs: 1,
 	}
 	for _, opt := range opts {
 		opt(a)
@@ -204,6 +298,7 @@ func (a *agent) prepareInvocation(ctx context.Context, invocation *Invocation) e
 		finalTools = a.skillToolset.ComposeTools(resolvedTools)
 		invocation.Instruction = MergeParts(SystemMessage(a.skillToolset.Instruction()), invocation.Instruction)
 	}
+	finalTools = a.withReadArtifactTool(finalTools)
 	invocation.Tools = append(invocation.Tools, finalTools...)
 	// order of precedence: static instruction > instruction provider > skills instruction > invocation instruction
 	if a.instructionProvider != nil {
@@ -261,12 +356,19 @@ func (a *agent) Run(ctx context.Context, invocation *Invocation) Generator[*Mess
 			}
 		}
 		ctx = NewAgentContext(ctx, a)
+		start := time.Now()
+		emitEvent(ctx, &Event{Type: EventAgentEnter, InvocationID: invocation.ID})
+		var runErr error
+		defer func() {
+			emitEvent(ctx, &Event{Type: EventAgentExit, InvocationID: invocation.ID, Duration: time.Since(start), Err: runErr})
+		}()
 		handler := Handler(HandleFunc(func(ctx context.Context, invocation *Invocation) Generator[*Message, error] {
 			req := &ModelRequest{
 				Tools:        invocation.Tools,
 				Instruction:  invocation.Instruction,
 				InputSchema:  a.inputSchema,
 				OutputSchema: a.outputSchema,
+				Generation:   a.generation.Clone(),
 			}
 			return a.handle(ctx, session, invocation, req)
 		}))
@@ -275,6 +377,14 @@ func (a *agent) Run(ctx context.Context, invocation *Invocation) Generator[*Mess
 		}
 		stream := handler.Handle(ctx, invocation)
 		for m, err := range stream {
+			switch {
+			case err != nil:
+				runErr = err
+			case m.Status == StatusCompleted:
+				emitEvent(ctx, &Event{Type: EventMessage, InvocationID: invocation.ID, Message: m})
+			case m.Role == RoleAssistant:
+				emitEvent(ctx, &Event{Type: EventTextDelta, InvocationID: invocation.ID, Message: m, Text: m.Text()})
+			}
 			if !yield(m, err) {
 				break
 			}
@@ -295,7 +405,15 @@ func (a *agent) handleTools(ctx context.Context, invocation *Invocation, part To
 	// Search through all available tools (static + resolved)
 	for _, tool := range invocation.Tools {
 		if tool.Name() == part.Name {
-			response, err := tool.Handle(ctx, part.Request)
+			arguments := part.Request
+			if a.toolValidation {
+				var invalid string
+				if arguments, invalid = a.validateToolArguments(tool, arguments); invalid != "" {
+					part.Response = invalid
+					return part, nil
+				}
+			}
+			response, err := tool.Handle(ctx, arguments)
 			if err != nil {
 				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 					return part, err
@@ -311,13 +429,26 @@ func (a *agent) handleTools(ctx context.Context, invocation *Invocation, part To
 	return part, nil
 }
 
-// executeTools executes the tools specified in the tool parts.
+// executeTools executes the tools specified in the tool parts. Calls selected by
+// the approval policy run only once an approving decision is present; without
+// a decision they are left incomplete. Calls run concurrently, up to the
+// configured concurrency, except for exclusive tools, which run alone.
 func (a *agent) executeTools(ctx context.Context, invocation *Invocation, message *Message) (*Message, error) {
 	var (
-		m sync.Mutex
+		m     sync.Mutex
+		calls int
 	)
-	actions := maps.New(message.Actions)
+	for _, part := range message.Parts {
+		if _, ok := part.(ToolPart); ok {
+			calls++
+		}
+	}
+	slots := newToolSlots(calls)
+	base := MergeActions(message.Actions, nil)
 	eg, ctx := errgroup.WithContext(ctx)
+	if a.toolConcurrency > 0 {
+		eg.SetLimit(a.toolConcurrency)
+	}
 	for i, part := range message.Parts {
 		switch v := any(part).(type) {
 		case ToolPart:
@@ -325,19 +456,49 @@ func (a *agent) executeTools(ctx context.Context, invocation *Invocation, messag
 				if v.Completed {
 					return nil
 				}
+				if a.toolApproval != nil && a.toolApproval(ctx, v) {
+					decision, ok := invocation.ToolDecisions[v.ID]
+					if !ok {
+						// Leave the call pending until a decision is supplied.
+						return nil
+					}
+					if !decision.Approved {
+						v.Response = rejectedToolResponse(decision)
+						v.Completed = true
+						m.Lock()
+						message.Parts[i] = v
+						m.Unlock()
+						return nil
+					}
+				}
+				// Each call records its actions apart, so those of a call that
+				// times out are never merged into the message.
+				actions := maps.New[string, any]()
 				toolCtx := tools.NewContext(ctx, &toolContext{
 					id:      v.ID,
 					name:    v.Name,
+					base:    base,
 					actions: actions,
 				})
-				part, err := a.handleTools(toolCtx, invocation, v)
+				if store := a.artifactStore(ctx); store != nil {
+					toolCtx = NewArtifactContext(toolCtx, store)
+				}
+				start := time.Now()
+				emitEvent(ctx, &Event{Type: EventToolCallStart, InvocationID: invocation.ID, Tool: &v})
+				part, timedOut, err := a.runTool(toolCtx, invocation, v, slots)
+				if err == nil {
+					part, err = a.offloadToolOutput(ctx, part)
+				}
 				if err != nil {
+					emitEvent(ctx, &Event{Type: EventToolCallEnd, InvocationID: invocation.ID, Tool: &v, Duration: time.Since(start), Err: err})
 					return err
 				}
-				part.Completed = true
+				emitEvent(ctx, &Event{Type: EventToolCallEnd, InvocationID: invocation.ID, Tool: &part, Duration: time.Since(start)})
 				m.Lock()
 				message.Parts[i] = part
-				message.Actions = MergeActions(message.Actions, actions.ToMap())
+				if !timedOut {
+					message.Actions = MergeActions(message.Actions, actions.ToMap())
+				}
 				m.Unlock()
 				return nil
 			})
@@ -346,6 +507,41 @@ func (a *agent) executeTools(ctx context.Context, invocation *Invocation, messag
 	return message, eg.Wait()
 }
 
+// finishTools persists an executed tool message and reports whether the run
+// must stop. It returns an InterruptError when calls are awaiting approval.
+func (a *agent) finishTools(ctx context.Context, session Session, invocation *Invocation, message *Message) (bool, error) {
+	// Persist the tool response to the session for logging.
+	if err := session.Append(ctx, message); err != nil {
+		return true, err
+	}
+	if pending := pendingToolParts(message); len(pending) > 0 {
+		return true, &InterruptError{
+			InvocationID: invocation.ID,
+			Message:      message,
+			Pending:      pending,
+		}
+	}
+	if _, ok := message.Actions[tools.ActionLoopExit]; ok {
+		return true, nil
+	}
+	return false, nil
+}
+
+// resumeTools applies the invocation's tool decisions to the calls that paused
+// it. It returns nil when the session holds no pending calls for the invocation.
+func (a *agent) resumeTools(ctx context.Context, session Session, invocation *Invocation) (*Message, error) {
+	history, err := session.History(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := findPendingTools(history, invocation.ID)
+	if pending == nil {
+		return nil, nil
+	}
+	// Decide on a copy so the stored pending message stays intact.
+	return a.executeTools(ctx, invocation, pending.Clone())
+}
+
 func messageFromResponse(response *ModelResponse) (*Message, error) {
 	if response == nil || response.Message == nil {
 		return nil, ErrNoFinalResponse
@@ -357,10 +553,39 @@ func messageFromResponse(response *ModelResponse) (*Message, error) {
 func (a *agent) handle(ctx context.Context, session Session, invocation *Invocation, req *ModelRequest) Generator[*Message, error] {
 	return func(yield func(*Message, error) bool) {
 		var (
-			loadHistory   = a.useContext || invocation.Resume
-			localMessages = []*Message{invocation.Message}
+			loadHistory    = a.useContext || invocation.Resume
+			localMessages  = []*Message{invocation.Message}
+			repairMessages []*Message
+			repairs        int
+			toolCalled     bool
 		)
+		if invocation.Resume {
+			toolMessage, err := a.resumeTools(ctx, session, invocation)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if toolMessage != nil {
+				toolCalled = true
+				if !yield(toolMessage, nil) {
+					return
+				}
+				if stop, err := a.finishTools(ctx, session, invocation, toolMessage); stop {
+					if err != nil {
+						yield(nil, err)
+					}
+					return
+				}
+			}
+		}
+		tracker, trackUsage := usageFromContext(ctx)
 		for i := 0; i < a.maxIterations; i++ {
+			if trackUsage {
+				if err := tracker.check(); err != nil {
+					yield(nil, err)
+					return
+				}
+			}
 			// Rebuild req.Messages each iteration.
 			if loadHistory {
 				// Load the full session history (may be compressed).
@@ -369,7 +594,7 @@ func (a *agent) handle(ctx context.Context, session Session, invocation *Invocat
 					yield(nil, err)
 					return
 				}
-				req.Messages = prepared
+				req.Messages = withoutPendingTools(prepared)
 			} else {
 				// Stateless mode: only include messages from this invocation.
 				req.Messages = localMessages
@@ -377,9 +602,28 @@ func (a *agent) handle(ctx context.Context, session Session, invocation *Invocat
 			if i == 0 && len(invocation.EphemeralMessages) > 0 {
 				req.Messages = append(slices.Clone(req.Messages), invocation.EphemeralMessages...)
 			}
+			if len(repairMessages) > 0 {
+				req.Messages = append(slices.Clone(req.Messages), repairMessages...)
+			}
+			// Artifacts referred to by messages, such as files saved by tools,
+			// are sent to the model inline.
+			if store := a.artifactStore(ctx); store != nil {
+				resolved, err := resolveArtifacts(ctx, store, session.ID(), req.Messages)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				req.Messages = resolved
+			}
+			toolChoice, err := a.nextToolChoice(ctx, i, toolCalled, req)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			req.ToolChoice = toolChoice
 			var finalMessage *Message
 			if !invocation.Stream {
-				finalResponse, err := a.model.Generate(ctx, req)
+				finalResponse, err := a.generate(ctx, invocation, req)
 				if err != nil {
 					yield(nil, err)
 					return
@@ -393,15 +637,8 @@ func (a *agent) handle(ctx context.Context, session Session, invocation *Invocat
 					finalMessage.Author = a.name
 				}
 				finalMessage.InvocationID = invocation.ID
-				// Skip saving tool intermediate states
-				if finalMessage.Role == RoleAssistant {
-					a.saveOutputState(ctx, invocation, finalMessage)
-					if !yield(finalMessage, nil) {
-						return
-					}
-				}
 			} else {
-				streaming := a.model.NewStreaming(ctx, req)
+				streaming := a.newStreaming(ctx, invocation, req)
 				for response, err := range streaming {
 					if err != nil {
 						yield(nil, err)
@@ -420,7 +657,6 @@ func (a *agent) handle(ctx context.Context, session Session, invocation *Invocat
 					if finalMessage.Role == RoleTool && finalMessage.Status == StatusCompleted {
 						continue
 					}
-					a.saveOutputState(ctx, invocation, finalMessage)
 					if !yield(finalMessage, nil) {
 						return // early termination
 					}
@@ -434,21 +670,29 @@ func (a *agent) handle(ctx context.Context, session Session, invocation *Invocat
 				yield(nil, ErrNoFinalResponse)
 				return
 			}
+			if trackUsage {
+				if err := tracker.add(finalMessage.TokenUsage); err != nil {
+					yield(nil, err)
+					return
+				}
+			}
 			if finalMessage.Role == RoleTool {
 				toolMessage, err := a.executeTools(ctx, invocation, finalMessage)
 				if err != nil {
 					yield(nil, err)
 					return
 				}
+				toolCalled = true
+				// The model moved on from a rejected answer; the tool turn now
+				// follows it in the history, so the repair exchange is dropped.
+				repairMessages = nil
 				if !yield(toolMessage, nil) {
 					return
 				}
-				// Persist the tool response to the session for logging.
-				if err := session.Append(ctx, toolMessage); err != nil {
-					yield(nil, err)
-					return
-				}
-				if _, ok := toolMessage.Actions[tools.ActionLoopExit]; ok {
+				if stop, err := a.finishTools(ctx, session, invocation, toolMessage); stop {
+					if err != nil {
+						yield(nil, err)
+					}
 					return
 				}
 				// In stateless mode, accumulate the tool result into the local slice
@@ -460,6 +704,27 @@ func (a *agent) handle(ctx context.Context, session Session, invocation *Invocat
 				}
 				continue // continue to the next iteration
 			}
+			if a.outputSchema != nil && finalMessage.Role == RoleAssistant {
+				if err := a.validateOutput(finalMessage); err != nil {
+					if repairs >= a.outputRepairs {
+						yield(nil, err)
+						return
+					}
+					// Ask the model to fix its answer; the failed attempt and the
+					// feedback are sent with the next request but never persisted.
+					repairs++
+					repairMessages = append(repairMessages, finalMessage, outputRepairMessage(err))
+					continue
+				}
+			}
+			// Save the output only once it passed validation; a streamed
+			// answer was already yielded above.
+			a.saveOutputState(ctx, invocation, finalMessage)
+			if !invocation.Stream && finalMessage.Role == RoleAssistant {
+				if !yield(finalMessage, nil) {
+					return
+				}
+			}
 			// Persist the final assistant message so future invocations can
 			// access the full conversation history via session.History().
 			if err := session.Append(ctx, finalMessage); err != nil {
@@ -0,0 +1,268 @@
+package blades
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	bladestools "github.com/go-kratos/blades/tools"
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+type scoreOutput struct {
+	Score int    `json:"score"`
+	Note  string `json:"note"`
+}
+
+// scriptedOutputModel answers each request with the next scripted text.
+type scriptedOutputModel struct {
+	mu       sync.Mutex
+	answers  []string
+	requests []*ModelRequest
+}
+
+func (m *scriptedOutputModel) Name() string { return "scripted-output" }
+
+func (m *scriptedOutputModel) Generate(_ context.Context, req *ModelRequest) (*ModelResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	answer := m.answers[min(len(m.requests), len(m.answers)-1)]
+	m.requests = append(m.requests, req)
+	return &ModelResponse{Message: AssistantMessage(answer)}, nil
+}
+
+func (m *scriptedOutputModel) NewStreaming(ctx context.Context, req *ModelRequest) Generator[*ModelResponse, error] {
+	return func(yield func(*ModelResponse, error) bool) {
+		res, err := m.Generate(ctx, req)
+		if err == nil {
+			res.Message.Status = StatusCompleted
+		}
+		yield(res, err)
+	}
+}
+
+func newScoreAgent(t *testing.T, model ModelProvider, opts ...AgentOption) Agent {
+	t.Helper()
+	schema, err := jsonschema.For[scoreOutput](nil)
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	agent, err := NewAgent("score-agent", append(opts, WithModel(model), WithOutputSchema(schema))...)
+	if err != nil {
+		t.Fatalf("new agent: %v", err)
+	}
+	return agent
+}
+
+func TestAgentOutputSchemaValid(t *testing.T) {
+	t.Parallel()
+
+	model := &scriptedOutputModel{answers: []string{`{"score":3,"note":"ok"}`}}
+	output, err := NewRunner(newScoreAgent(t, model)).Run(context.Background(), UserMessage("rate"))
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	got, err := DecodeOutput[scoreOutput](output)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got != (scoreOutput{Score: 3, Note: "ok"}) {
+		t.Fatalf("output = %+v", got)
+	}
+}
+
+func TestAgentOutputSchemaFencedAnswer(t *testing.T) {
+	t.Parallel()
+
+	model := &scriptedOutputModel{answers: []string{"```json\n{\"score\":3,\"note\":\"ok\"}\n```"}}
+	output, err := NewRunner(newScoreAgent(t, model)).Run(context.Background(), UserMessage("rate"))
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	got, err := DecodeOutput[scoreOutput](output)
+	if err != nil || got != (scoreOutput{Score: 3, Note: "ok"}) {
+		t.Fatalf("output = %+v, %v", got, err)
+	}
+	if len(model.requests) != 1 {
+		t.Fatalf("model requests = %d, want 1", len(model.requests))
+	}
+}
+
+func TestAgentOutputSchemaRepairsOnceByDefault(t *testing.T) {
+	t.Parallel()
+
+	model := &scriptedOutputModel{answers: []string{"Here is the score: 5", `{"score":5,"note":"fixed"}`}}
+	if _, err := NewRunner(newScoreAgent(t, model)).Run(context.Background(), UserMessage("rate")); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(model.requests) != 2 {
+		t.Fatalf("model requests = %d, want 2", len(model.requests))
+	}
+}
+
+func TestOutputJSON(t *testing.T) {
+	t.Parallel()
+
+	for text, want := range map[string]string{
+		`{"a":1}`:                 `{"a":1}`,
+		"  {\"a\":1}\n":           `{"a":1}`,
+		"```json\n{\"a\":1}\n```": `{"a":1}`,
+		"```\n[1, 2]\n```\n":      `[1, 2]`,
+		"```{\"a\":1}```":         "```{\"a\":1}```",
+		"see ```json\n{}\n```":    "see ```json\n{}\n```",
+	} {
+		if got := outputJSON(text); got != want {
+			t.Errorf("outputJSON(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestAgentOutputSchemaRepair(t *testing.T) {
+	t.Parallel()
+
+	model := &scriptedOutputModel{answers: []string{`{"score":"high"}`, `{"score":5,"note":"fixed"}`}}
+	session := NewSession()
+	output, err := NewRunner(newScoreAgent(t, model, WithOutputRepair(1))).Run(context.Background(),
+		UserMessage("rate"), WithSession(session))
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got, want := output.Text(), `{"score":5,"note":"fixed"}`; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	if len(model.requests) != 2 {
+		t.Fatalf("model requests = %d, want 2", len(model.requests))
+	}
+	repair := model.requests[1].Messages
+	if len(repair) < 2 || !strings.Contains(repair[len(repair)-1].Text(), "invalid") {
+		t.Fatalf("repair request does not carry the validation error: %v", repair)
+	}
+	history, err := session.History(context.Background())
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	for _, message := range history {
+		if strings.Contains(message.Text(), `"high"`) || strings.Contains(message.Text(), "previous response") {
+			t.Fatalf("repair exchange was persisted: %v", message)
+		}
+	}
+}
+
+func TestAgentOutputSchemaRepairExhausted(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		repairs int
+		calls   int
+	}{
+		{name: "no repair", repairs: 0, calls: 1},
+		{name: "two repairs", repairs: 2, calls: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			model := &scriptedOutputModel{answers: []string{"not json"}}
+			_, err := NewRunner(newScoreAgent(t, model, WithOutputRepair(tt.repairs))).Run(context.Background(), UserMessage("rate"))
+			if !errors.Is(err, ErrInvalidOutput) {
+				t.Fatalf("run error = %v, want ErrInvalidOutput", err)
+			}
+			if len(model.requests) != tt.calls {
+				t.Fatalf("model requests = %d, want %d", len(model.requests), tt.calls)
+			}
+		})
+	}
+}
+
+func TestAgentOutputSchemaRejectedStreamNotSaved(t *testing.T) {
+	t.Parallel()
+
+	model := &scriptedOutputModel{answers: []string{"not json"}}
+	session := NewSession()
+	agent := newScoreAgent(t, model, WithOutputKey("score"))
+	for _, err := range NewRunner(agent).RunStream(context.Background(), UserMessage("rate"), WithSession(session)) {
+		if err != nil && !errors.Is(err, ErrInvalidOutput) {
+			t.Fatalf("run error = %v, want ErrInvalidOutput", err)
+		}
+	}
+	if value, ok := session.State()["score"]; ok {
+		t.Fatalf("rejected output was saved: %v", value)
+	}
+}
+
+// repairToolModel answers with invalid output, then calls a tool after the
+// repair prompt, then answers validly. It records the messages of every request.
+type repairToolModel struct {
+	requests [][]*Message
+}
+
+func (m *repairToolModel) Name() string { return "repair-tool" }
+
+func (m *repairToolModel) Generate(_ context.Context, req *ModelRequest) (*ModelResponse, error) {
+	m.requests = append(m.requests, slices.Clone(req.Messages))
+	switch len(m.requests) {
+	case 1:
+		return &ModelResponse{Message: AssistantMessage("not json")}, nil
+	case 2:
+		msg := NewAssistantMessage(StatusCompleted)
+		msg.Role = RoleTool
+		msg.Parts = append(msg.Parts, NewToolPart("call_1", "lookup", "{}"))
+		return &ModelResponse{Message: msg}, nil
+	default:
+		msg := AssistantMessage(`{"score":4,"note":"looked up"}`)
+		msg.Status = StatusCompleted
+		return &ModelResponse{Message: msg}, nil
+	}
+}
+
+func (m *repairToolModel) NewStreaming(context.Context, *ModelRequest) Generator[*ModelResponse, error] {
+	return nil
+}
+
+func TestAgentOutputSchemaRepairThenTool(t *testing.T) {
+	t.Parallel()
+
+	lookup := bladestools.NewTool("lookup", "looks up", bladestools.HandleFunc(func(context.Context, string) (string, error) {
+		return "4", nil
+	}))
+	model := &repairToolModel{}
+	session := NewSession()
+	agent := newScoreAgent(t, model, WithTools(lookup), WithOutputRepair(1), WithOutputKey("score"))
+	output, err := NewRunner(agent).Run(context.Background(), UserMessage("rate"), WithSession(session))
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got, want := output.Text(), `{"score":4,"note":"looked up"}`; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	if got := session.State()["score"]; got != output.Text() {
+		t.Fatalf("saved output = %v", got)
+	}
+	if len(model.requests) != 3 {
+		t.Fatalf("model requests = %d, want 3", len(model.requests))
+	}
+	last := model.requests[2]
+	if len(last) == 0 || last[len(last)-1].Role != RoleTool {
+		t.Fatalf("last request does not end with the tool turn: %v", last)
+	}
+	for _, message := range last {
+		if message.Text() == "not json" {
+			t.Fatalf("rejected answer is sent after the tool turn: %v", last)
+		}
+	}
+}
+
+func TestDecodeOutput(t *testing.T) {
+	t.Parallel()
+
+	if _, err := DecodeOutput[scoreOutput](nil); !errors.Is(err, ErrNoFinalResponse) {
+		t.Fatalf("nil message error = %v", err)
+	}
+	if _, err := DecodeOutput[scoreOutput](AssistantMessage("{")); !errors.Is(err, ErrInvalidOutput) {
+		t.Fatalf("malformed error = %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package blades
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	bladestools "github.com/go-kratos/blades/tools"
+)
+
+// approvalModel requests a tool call until it sees a tool result, then answers
+// with the result text.
+type approvalModel struct {
+	mu       sync.Mutex
+	requests []*ModelRequest
+}
+
+func (m *approvalModel) Name() string { return "approval" }
+
+func (m *approvalModel) Generate(_ context.Context, req *ModelRequest) (*ModelResponse, error) {
+	m.mu.Lock()
+	m.requests = append(m.requests, req)
+	m.mu.Unlock()
+	for _, message := range req.Messages {
+		if message.Role != RoleTool {
+			continue
+		}
+		for _, part := range message.Parts {
+			if tool, ok := part.(ToolPart); ok && tool.Completed {
+				return &ModelResponse{Message: AssistantMessage("result: " + tool.Response)}, nil
+			}
+		}
+	}
+	msg := NewAssistantMessage(StatusCompleted)
+	msg.Role = RoleTool
+	msg.Parts = append(msg.Parts, NewToolPart("call_1", "delete_file", `{"path":"a.txt"}`))
+	return &ModelResponse{Message: msg}, nil
+}
+
+func (m *approvalModel) NewStreaming(context.Context, *ModelRequest) Generator[*ModelResponse, error] {
+	return nil
+}
+
+func newApprovalAgent(t *testing.T, model ModelProvider, calls *int) Agent {
+	t.Helper()
+	tool := bladestools.NewTool("delete_file", "delete a file", bladestools.HandleFunc(func(context.Context, string) (string, error) {
+		*calls++
+		return "deleted", nil
+	}))
+	agent, err := NewAgent("approval-agent",
+		WithModel(model),
+		WithTools(tool),
+		WithToolApproval(RequireToolApproval("delete_file")),
+	)
+	if err != nil {
+		t.Fatalf("new agent: %v", err)
+	}
+	return agent
+}
+
+func runUntilInterrupted(t *testing.T, runner *Runner, session Session, invocationID string) *InterruptError {
+	t.Helper()
+	_, err := runner.Run(context.Background(), UserMessage("delete a.txt"),
+		WithSession(session),
+		WithInvocationID(invocationID),
+	)
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("run error = %v, want ErrInterrupted", err)
+	}
+	var interrupt *InterruptError
+	if !errors.As(err, &interrupt) {
+		t.Fatalf("run error = %T, want *InterruptError", err)
+	}
+	return interrupt
+}
+
+func TestAgentToolApprovalPausesAndResumesApproved(t *testing.T) {
+	t.Parallel()
+
+	var (
+		calls   int
+		model   = &approvalModel{}
+		session = NewSession()
+		runner  = NewRunner(newApprovalAgent(t, model, &calls))
+	)
+	interrupt := runUntilInterrupted(t, runner, session, "inv-approve")
+	if calls != 0 {
+		t.Fatalf("tool calls before approval = %d, want 0", calls)
+	}
+	if got, want := interrupt.InvocationID, "inv-approve"; got != want {
+		t.Fatalf("interrupt invocation = %q, want %q", got, want)
+	}
+	if len(interrupt.Pending) != 1 || interrupt.Pending[0].ID != "call_1" || interrupt.Pending[0].Completed {
+		t.Fatalf("pending = %+v, want incomplete call_1", interrupt.Pending)
+	}
+	history, err := session.History(context.Background())
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(pendingToolParts(history[len(history)-1])) != 1 {
+		t.Fatalf("pending tool message was not persisted: %v", history)
+	}
+
+	output, err := runner.Run(context.Background(), UserMessage("ignored on resume"),
+		WithSession(session),
+		WithInvocationID("inv-approve"),
+		WithResume(true),
+		WithToolDecisions(ApproveToolCall("call_1")),
+	)
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("tool calls after approval = %d, want 1", calls)
+	}
+	if got, want := output.Text(), "result: deleted"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	last := model.requests[len(model.requests)-1]
+	for _, message := range last.Messages {
+		if len(pendingToolParts(message)) > 0 {
+			t.Fatalf("model request contains pending tool calls: %v", message)
+		}
+	}
+}
+
+func TestAgentToolApprovalRejected(t *testing.T) {
+	t.Parallel()
+
+	var (
+		calls   int
+		session = NewSession()
+		runner  = NewRunner(newApprovalAgent(t, &approvalModel{}, &calls))
+	)
+	runUntilInterrupted(t, runner, session, "inv-reject")
+
+	output, err := runner.Run(context.Background(), nil,
+		WithSession(session),
+		WithInvocationID("inv-reject"),
+		WithResume(true),
+		WithToolDecisions(RejectToolCall("call_1", "keep the file")),
+	)
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if calls != 0 {
+		t.Fatalf("tool calls after rejection = %d, want 0", calls)
+	}
+	if got := output.Text(); !strings.Contains(got, "rejected") || !strings.Contains(got, "keep the file") {
+		t.Fatalf("output = %q, want rejection with reason", got)
+	}
+}
+
+func TestAgentToolApprovalResumeWithoutDecisionStaysPaused(t *testing.T) {
+	t.Parallel()
+
+	var (
+		calls   int
+		session = NewSession()
+		runner  = NewRunner(newApprovalAgent(t, &approvalModel{}, &calls))
+	)
+	runUntilInterrupted(t, runner, session, "inv-pending")
+
+	_, err := runner.Run(context.Background(), nil,
+		WithSession(session),
+		WithInvocationID("inv-pending"),
+		WithResume(true),
+	)
+	var interrupt *InterruptError
+	if !errors.As(err, &interrupt) {
+		t.Fatalf("resume error = %v, want *InterruptError", err)
+	}
+	if calls != 0 {
+		t.Fatalf("tool calls = %d, want 0", calls)
+	}
+}
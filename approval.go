@@ -0,0 +1,100 @@
+package blades
+
+import (
+	"context"
+	"slices"
+)
+
+// ToolApprovalPolicy reports whether a tool call must be approved by a human
+// before it is executed. Calls that require approval pause the run with an
+// InterruptError until they are resumed with WithToolDecisions.
+type ToolApprovalPolicy func(ctx context.Context, part ToolPart) bool
+
+// RequireToolApproval returns a ToolApprovalPolicy that requires approval for
+// calls to any of the named tools.
+func RequireToolApproval(names ...string) ToolApprovalPolicy {
+	return func(_ context.Context, part ToolPart) bool {
+		return slices.Contains(names, part.Name)
+	}
+}
+
+// ToolDecision is a human decision on a tool call that is awaiting approval.
+type ToolDecision struct {
+	// ID is the ToolPart.ID of the pending call.
+	ID string
+	// Approved executes the call when true; otherwise it is rejected.
+	Approved bool
+	// Reason is reported back to the model when the call is rejected.
+	Reason string
+}
+
+// ApproveToolCall returns a decision that approves the tool call with the given ID.
+func ApproveToolCall(id string) ToolDecision {
+	return ToolDecision{ID: id, Approved: true}
+}
+
+// RejectToolCall returns a decision that rejects the tool call with the given ID.
+func RejectToolCall(id, reason string) ToolDecision {
+	return ToolDecision{ID: id, Reason: reason}
+}
+
+// rejectedToolResponse is the tool response the model sees for a rejected call.
+func rejectedToolResponse(decision ToolDecision) string {
+	if decision.Reason == "" {
+		return "Tool error: tool call was rejected by the user"
+	}
+	return "Tool error: tool call was rejected by the user: " + decision.Reason
+}
+
+// pendingToolParts returns the tool calls of the message that have not completed.
+func pendingToolParts(message *Message) []ToolPart {
+	var pending []ToolPart
+	for _, part := range message.Parts {
+		if v, ok := part.(ToolPart); ok && !v.Completed {
+			pending = append(pending, v)
+		}
+	}
+	return pending
+}
+
+// withoutPendingTools drops tool messages that still hold calls awaiting
+// approval. Providers reject tool calls without results, and a resumed call is
+// stored again as a new tool message once it has been decided.
+func withoutPendingTools(messages []*Message) []*Message {
+	var (
+		dropped  bool
+		filtered []*Message
+	)
+	for i, message := range messages {
+		if message.Role == RoleTool && len(pendingToolParts(message)) > 0 {
+			if !dropped {
+				dropped = true
+				filtered = append(make([]*Message, 0, len(messages)), messages[:i]...)
+			}
+			continue
+		}
+		if dropped {
+			filtered = append(filtered, message)
+		}
+	}
+	if !dropped {
+		return messages
+	}
+	return filtered
+}
+
+// findPendingTools returns the latest tool message of the invocation that
+// still holds calls awaiting approval, or nil if there is none.
+func findPendingTools(messages []*Message, invocationID string) *Message {
+	for i := len(messages) - 1; i >= 0; i-- {
+		message := messages[i]
+		if message.Role != RoleTool || message.InvocationID != invocationID {
+			continue
+		}
+		if len(pendingToolParts(message)) > 0 {
+			return message
+		}
+		return nil
+	}
+	return nil
+}
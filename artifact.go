@@ -0,0 +1,309 @@
+package blades
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// artifactScheme prefixes the URI of a FilePart that refers to an artifact.
+const artifactScheme = "artifact://"
+
+// Artifact is a named blob kept outside the message history, such as a large
+// tool output or a file produced by a tool. Saving an artifact under an
+// existing name creates a new version.
+type Artifact struct {
+	// Name identifies the artifact within a session.
+	Name string
+	// Version is assigned by the store on save, starting at 1.
+	Version int
+	// MIMEType is the media type of Data.
+	MIMEType MIMEType
+	// Data is the artifact content.
+	Data []byte
+	// CreatedAt is set by the store on save when zero.
+	CreatedAt time.Time
+}
+
+// ArtifactInfo describes a version of an artifact without its content.
+type ArtifactInfo struct {
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	MIMEType  MIMEType  `json:"mimeType"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Info returns the description of the artifact.
+func (a *Artifact) Info() ArtifactInfo {
+	return ArtifactInfo{
+		Name:      a.Name,
+		Version:   a.Version,
+		MIMEType:  a.MIMEType,
+		Size:      int64(len(a.Data)),
+		CreatedAt: a.CreatedAt,
+	}
+}
+
+// ArtifactStore saves versioned artifacts keyed by session.
+type ArtifactStore interface {
+	// Save stores the artifact as the next version of its name and returns
+	// the version assigned.
+	Save(ctx context.Context, sessionID string, artifact *Artifact) (int, error)
+	// Load returns the given version of the named artifact, or the latest
+	// version when version is 0. It returns ErrArtifactNotFound when missing.
+	Load(ctx context.Context, sessionID, name string, version int) (*Artifact, error)
+	// List returns the latest version of every artifact of the session,
+	// ordered by name.
+	List(ctx context.Context, sessionID string) ([]ArtifactInfo, error)
+	// Versions returns every version of the named artifact, oldest first.
+	// It returns ErrArtifactNotFound when the artifact does not exist.
+	Versions(ctx context.Context, sessionID, name string) ([]ArtifactInfo, error)
+}
+
+type ctxArtifactKey struct{}
+
+// NewArtifactContext returns a new Context that carries the artifact store.
+// Agents use it to offload large tool outputs, see WithToolOutputPolicy.
+func NewArtifactContext(ctx context.Context, store ArtifactStore) context.Context {
+	return context.WithValue(ctx, ctxArtifactKey{}, store)
+}
+
+// ArtifactStoreFromContext retrieves the ArtifactStore from the context.
+func ArtifactStoreFromContext(ctx context.Context) (ArtifactStore, bool) {
+	store, ok := ctx.Value(ctxArtifactKey{}).(ArtifactStore)
+	return store, ok
+}
+
+// ArtifactURI returns the URI of a FilePart referring to the given version of
+// the named artifact of the session.
+func ArtifactURI(name string, version int) string {
+	return fmt.Sprintf("%s%s?version=%d", artifactScheme, name, version)
+}
+
+// ParseArtifactURI returns the artifact name and version of a URI made by
+// ArtifactURI. It reports false for URIs that do not refer to an artifact.
+func ParseArtifactURI(uri string) (string, int, bool) {
+	rest, ok := strings.CutPrefix(uri, artifactScheme)
+	if !ok {
+		return "", 0, false
+	}
+	name, v, ok := strings.Cut(rest, "?version=")
+	if !ok || name == "" {
+		return "", 0, false
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version < 1 {
+		return "", 0, false
+	}
+	return name, version, true
+}
+
+// SessionArtifacts gives access to the artifacts of a single session.
+type SessionArtifacts struct {
+	store     ArtifactStore
+	sessionID string
+}
+
+// ArtifactsFromContext returns the artifacts of the session running in ctx.
+// Tools use it to save the files they produce; it reports false when the
+// context carries no ArtifactStore or no session.
+func ArtifactsFromContext(ctx context.Context) (*SessionArtifacts, bool) {
+	store, ok := ArtifactStoreFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	session, ok := SessionFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	return &SessionArtifacts{store: store, sessionID: session.ID()}, true
+}
+
+// Save stores the artifact as the next version of its name and returns a
+// FilePart referring to it, which can be added to a message.
+func (s *SessionArtifacts) Save(ctx context.Context, artifact *Artifact) (FilePart, error) {
+	version, err := s.store.Save(ctx, s.sessionID, artifact)
+	if err != nil {
+		return FilePart{}, err
+	}
+	return FilePart{
+		Name:     artifact.Name,
+		URI:      ArtifactURI(artifact.Name, version),
+		MIMEType: artifact.MIMEType,
+	}, nil
+}
+
+// Load returns the given version of the named artifact, or the latest version
+// when version is 0.
+func (s *SessionArtifacts) Load(ctx context.Context, name string, version int) (*Artifact, error) {
+	return s.store.Load(ctx, s.sessionID, name, version)
+}
+
+// List returns the latest version of every artifact of the session.
+func (s *SessionArtifacts) List(ctx context.Context) ([]ArtifactInfo, error) {
+	return s.store.List(ctx, s.sessionID)
+}
+
+// Versions returns every version of the named artifact, oldest first.
+func (s *SessionArtifacts) Versions(ctx context.Context, name string) ([]ArtifactInfo, error) {
+	return s.store.Versions(ctx, s.sessionID, name)
+}
+
+// artifactSession moves large DataParts out of the messages of a session.
+type artifactSession struct {
+	Session
+	store    ArtifactStore
+	minBytes int
+}
+
+// NewArtifactSession returns a Session that saves every DataPart of at least
+// minBytes bytes to the store and appends messages to the given session with
+// a FilePart referring to the artifact in its place, so the session keeps
+// only small references. History resolves the references back into
+// DataParts, so models still receive the content.
+func NewArtifactSession(session Session, store ArtifactStore, minBytes int) Session {
+	return &artifactSession{Session: session, store: store, minBytes: minBytes}
+}
+
+func (s *artifactSession) Append(ctx context.Context, message *Message) error {
+	var offloaded *Message
+	for i, part := range message.Parts {
+		data, ok := part.(DataPart)
+		if !ok || len(data.Bytes) < s.minBytes {
+			continue
+		}
+		if offloaded == nil {
+			offloaded = message.Clone()
+		}
+		name := data.Name
+		if name == "" {
+			name = fmt.Sprintf("data/%s/%d", message.ID, i)
+		}
+		version, err := s.store.Save(ctx, s.ID(), &Artifact{Name: name, MIMEType: data.MIMEType, Data: data.Bytes})
+		if err != nil {
+			return err
+		}
+		offloaded.Parts[i] = FilePart{Name: data.Name, URI: ArtifactURI(name, version), MIMEType: data.MIMEType}
+	}
+	if offloaded == nil {
+		offloaded = message
+	}
+	return s.Session.Append(ctx, offloaded)
+}
+
+func (s *artifactSession) History(ctx context.Context) ([]*Message, error) {
+	history, err := s.Session.History(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resolveArtifacts(ctx, s.store, s.ID(), history)
+}
+
+// resolveArtifacts replaces every FilePart referring to an artifact of the
+// session with a DataPart holding the artifact's content, since providers
+// cannot fetch artifact URIs. Messages are cloned before they are changed.
+func resolveArtifacts(ctx context.Context, store ArtifactStore, sessionID string, messages []*Message) ([]*Message, error) {
+	resolved := make([]*Message, len(messages))
+	for i, message := range messages {
+		resolved[i] = message
+		for j, part := range message.Parts {
+			file, ok := part.(FilePart)
+			if !ok {
+				continue
+			}
+			name, version, ok := ParseArtifactURI(file.URI)
+			if !ok {
+				continue
+			}
+			artifact, err := store.Load(ctx, sessionID, name, version)
+			if err != nil {
+				return nil, fmt.Errorf("load artifact %s: %w", file.URI, err)
+			}
+			if resolved[i] == message {
+				resolved[i] = message.Clone()
+			}
+			resolved[i].Parts[j] = DataPart{Name: file.Name, Bytes: artifact.Data, MIMEType: artifact.MIMEType}
+		}
+	}
+	return resolved, nil
+}
+
+// artifactStoreInMemory keeps artifacts in memory for the life of the process.
+type artifactStoreInMemory struct {
+	mu       sync.RWMutex
+	sessions map[string]map[string][]*Artifact
+}
+
+// NewInMemoryArtifactStore returns an ArtifactStore that keeps artifacts in
+// memory. It is safe for concurrent use.
+func NewInMemoryArtifactStore() ArtifactStore {
+	return &artifactStoreInMemory{sessions: make(map[string]map[string][]*Artifact)}
+}
+
+func (s *artifactStoreInMemory) Save(_ context.Context, sessionID string, artifact *Artifact) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	artifacts, ok := s.sessions[sessionID]
+	if !ok {
+		artifacts = make(map[string][]*Artifact)
+		s.sessions[sessionID] = artifacts
+	}
+	saved := copyArtifact(artifact)
+	saved.Version = len(artifacts[artifact.Name]) + 1
+	if saved.CreatedAt.IsZero() {
+		saved.CreatedAt = time.Now()
+	}
+	artifacts[artifact.Name] = append(artifacts[artifact.Name], saved)
+	return saved.Version, nil
+}
+
+func (s *artifactStoreInMemory) Load(_ context.Context, sessionID, name string, version int) (*Artifact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions := s.sessions[sessionID][name]
+	if version == 0 {
+		version = len(versions)
+	}
+	if version < 1 || version > len(versions) {
+		return nil, ErrArtifactNotFound
+	}
+	return copyArtifact(versions[version-1]), nil
+}
+
+func (s *artifactStoreInMemory) List(_ context.Context, sessionID string) ([]ArtifactInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	infos := make([]ArtifactInfo, 0, len(s.sessions[sessionID]))
+	for _, versions := range s.sessions[sessionID] {
+		infos = append(infos, versions[len(versions)-1].Info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+func (s *artifactStoreInMemory) Versions(_ context.Context, sessionID, name string) ([]ArtifactInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions := s.sessions[sessionID][name]
+	if len(versions) == 0 {
+		return nil, ErrArtifactNotFound
+	}
+	infos := make([]ArtifactInfo, 0, len(versions))
+	for _, artifact := range versions {
+		infos = append(infos, artifact.Info())
+	}
+	return infos, nil
+}
+
+// copyArtifact returns a copy of the artifact that does not share its data.
+func copyArtifact(artifact *Artifact) *Artifact {
+	clone := *artifact
+	clone.Data = slices.Clone(artifact.Data)
+	return &clone
+}
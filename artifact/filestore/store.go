@@ -0,0 +1,239 @@
+// Package filestore provides a blades.ArtifactStore that keeps artifacts in a
+// local directory, so they survive restarts along with their sessions.
+package filestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/blades"
+)
+
+const (
+	dataExt = ".data"
+	infoExt = ".json"
+)
+
+// ErrInvalidName is returned when an artifact name is empty or reserved.
+var ErrInvalidName = errors.New("filestore: invalid artifact name")
+
+// Store is a blades.ArtifactStore backed by a directory. Every version of an
+// artifact is stored in <dir>/<session>/<name>/<version>.data, next to a JSON
+// file describing it; names are path-escaped, so they may contain slashes.
+// Files are written atomically, so a crash never leaves a partial version.
+type Store struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+var _ blades.ArtifactStore = (*Store)(nil)
+
+// NewStore creates a Store rooted at dir, creating the directory if needed.
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("filestore: mkdir: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Save writes the artifact as the next version of its name.
+func (s *Store) Save(ctx context.Context, sessionID string, artifact *blades.Artifact) (int, error) {
+	dir, err := s.artifactDir(sessionID, artifact.Name)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, fmt.Errorf("filestore: mkdir: %w", err)
+	}
+	versions, err := readVersions(dir)
+	if err != nil {
+		return 0, err
+	}
+	info := artifact.Info()
+	info.Version = len(versions) + 1
+	if info.CreatedAt.IsZero() {
+		info.CreatedAt = time.Now()
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return 0, fmt.Errorf("filestore: marshal %s: %w", artifact.Name, err)
+	}
+	// The description is written last: a version exists once it is present.
+	base := filepath.Join(dir, strconv.Itoa(info.Version))
+	if err := writeFile(base+dataExt, artifact.Data); err != nil {
+		return 0, fmt.Errorf("filestore: write %s: %w", artifact.Name, err)
+	}
+	if err := writeFile(base+infoExt, data); err != nil {
+		return 0, fmt.Errorf("filestore: write %s: %w", artifact.Name, err)
+	}
+	return info.Version, nil
+}
+
+// Load reads the given version of the named artifact, or the latest version
+// when version is 0.
+func (s *Store) Load(ctx context.Context, sessionID, name string, version int) (*blades.Artifact, error) {
+	dir, err := s.artifactDir(sessionID, name)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions, err := readVersions(dir)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		version = len(versions)
+	}
+	if version < 1 || version > len(versions) {
+		return nil, blades.ErrArtifactNotFound
+	}
+	info := versions[version-1]
+	data, err := os.ReadFile(filepath.Join(dir, strconv.Itoa(version)+dataExt))
+	if err != nil {
+		return nil, fmt.Errorf("filestore: read %s: %w", name, err)
+	}
+	return &blades.Artifact{
+		Name:      info.Name,
+		Version:   info.Version,
+		MIMEType:  info.MIMEType,
+		Data:      data,
+		CreatedAt: info.CreatedAt,
+	}, nil
+}
+
+// List returns the latest version of every artifact of the session, ordered
+// by name.
+func (s *Store) List(ctx context.Context, sessionID string) ([]blades.ArtifactInfo, error) {
+	if err := blades.ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries, err := os.ReadDir(filepath.Join(s.dir, sessionID))
+	if errors.Is(err, os.ErrNotExist) {
+		return []blades.ArtifactInfo{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("filestore: read dir: %w", err)
+	}
+	infos := make([]blades.ArtifactInfo, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		versions, err := readVersions(filepath.Join(s.dir, sessionID, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) > 0 {
+			infos = append(infos, versions[len(versions)-1])
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// Versions returns every version of the named artifact, oldest first.
+func (s *Store) Versions(ctx context.Context, sessionID, name string) ([]blades.ArtifactInfo, error) {
+	dir, err := s.artifactDir(sessionID, name)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions, err := readVersions(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, blades.ErrArtifactNotFound
+	}
+	return versions, nil
+}
+
+// Delete removes every artifact of the session.
+func (s *Store) Delete(ctx context.Context, sessionID string) error {
+	if err := blades.ValidateSessionID(sessionID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.RemoveAll(filepath.Join(s.dir, sessionID)); err != nil {
+		return fmt.Errorf("filestore: delete %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+func (s *Store) artifactDir(sessionID, name string) (string, error) {
+	if err := blades.ValidateSessionID(sessionID); err != nil {
+		return "", err
+	}
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return filepath.Join(s.dir, sessionID, url.PathEscape(name)), nil
+}
+
+// readVersions returns the descriptions of the versions stored in dir,
+// oldest first. A missing directory has no versions.
+func readVersions(dir string) ([]blades.ArtifactInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("filestore: read dir: %w", err)
+	}
+	var versions []blades.ArtifactInfo
+	for _, entry := range entries {
+		v, ok := strings.CutSuffix(entry.Name(), infoExt)
+		if !ok {
+			continue
+		}
+		if _, err := strconv.Atoi(v); err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("filestore: read %s: %w", entry.Name(), err)
+		}
+		var info blades.ArtifactInfo
+		if err := json.Unmarshal(data, &info); err != nil {
+			return nil, fmt.Errorf("filestore: decode %s: %w", entry.Name(), err)
+		}
+		versions = append(versions, info)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	return versions, nil
+}
+
+// writeFile writes data to a temporary file and renames it into place.
+func writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,125 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-kratos/blades"
+)
+
+func TestStoreVersionsSurviveRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	for i, data := range []string{"one", "two"} {
+		version, err := store.Save(ctx, "s1", &blades.Artifact{Name: "tool_output/read/call_1", MIMEType: blades.MIMEText, Data: []byte(data)})
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		if version != i+1 {
+			t.Fatalf("version = %d, want %d", version, i+1)
+		}
+	}
+	if _, err := store.Save(ctx, "s1", &blades.Artifact{Name: "chart.png", MIMEType: blades.MIMEImagePNG, Data: []byte{1, 2, 3}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	reopened, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("reopen store: %v", err)
+	}
+	latest, err := reopened.Load(ctx, "s1", "tool_output/read/call_1", 0)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if string(latest.Data) != "two" || latest.Version != 2 || latest.MIMEType != blades.MIMEText || latest.CreatedAt.IsZero() {
+		t.Fatalf("latest = %+v", latest)
+	}
+	first, err := reopened.Load(ctx, "s1", "tool_output/read/call_1", 1)
+	if err != nil {
+		t.Fatalf("load version 1: %v", err)
+	}
+	if string(first.Data) != "one" {
+		t.Fatalf("version 1 = %q, want one", first.Data)
+	}
+
+	infos, err := reopened.List(ctx, "s1")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(infos) != 2 || infos[0].Name != "chart.png" || infos[0].Size != 3 ||
+		infos[1].Name != "tool_output/read/call_1" || infos[1].Version != 2 {
+		t.Fatalf("list = %+v", infos)
+	}
+	versions, err := reopened.Versions(ctx, "s1", "tool_output/read/call_1")
+	if err != nil {
+		t.Fatalf("versions: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 1 || versions[1].Version != 2 {
+		t.Fatalf("versions = %+v", versions)
+	}
+}
+
+func TestStoreNotFound(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	if _, err := store.Save(ctx, "s1", &blades.Artifact{Name: "a", Data: []byte("x")}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if _, err := store.Load(ctx, "s1", "a", 2); !errors.Is(err, blades.ErrArtifactNotFound) {
+		t.Fatalf("missing version: err = %v", err)
+	}
+	if _, err := store.Load(ctx, "s2", "a", 0); !errors.Is(err, blades.ErrArtifactNotFound) {
+		t.Fatalf("other session: err = %v", err)
+	}
+	if _, err := store.Versions(ctx, "s1", "b"); !errors.Is(err, blades.ErrArtifactNotFound) {
+		t.Fatalf("missing name: err = %v", err)
+	}
+	infos, err := store.List(ctx, "s2")
+	if err != nil || len(infos) != 0 {
+		t.Fatalf("list = %+v, %v", infos, err)
+	}
+}
+
+func TestStoreDelete(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	if _, err := store.Save(ctx, "s1", &blades.Artifact{Name: "a", Data: []byte("x")}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := store.Delete(ctx, "s1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := store.Load(ctx, "s1", "a", 0); !errors.Is(err, blades.ErrArtifactNotFound) {
+		t.Fatalf("load after delete: err = %v", err)
+	}
+}
+
+func TestStoreRejectsInvalidNames(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	for _, id := range []string{"", "..", "../escape", `a\b`} {
+		if _, err := store.Save(ctx, id, &blades.Artifact{Name: "a"}); !errors.Is(err, blades.ErrInvalidSessionID) {
+			t.Fatalf("Save(%q) err = %v, want ErrInvalidSessionID", id, err)
+		}
+	}
+	for _, name := range []string{"", ".", ".."} {
+		if _, err := store.Save(ctx, "s1", &blades.Artifact{Name: name}); !errors.Is(err, ErrInvalidName) {
+			t.Fatalf("Save name %q err = %v, want ErrInvalidName", name, err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package blades
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	bladestools "github.com/go-kratos/blades/tools"
+)
+
+func TestInMemoryArtifactStoreVersions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewInMemoryArtifactStore()
+	for i, data := range []string{"one", "two"} {
+		version, err := store.Save(ctx, "s1", &Artifact{Name: "report", MIMEType: MIMEText, Data: []byte(data)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != i+1 {
+			t.Fatalf("version = %d, want %d", version, i+1)
+		}
+	}
+	if _, err := store.Save(ctx, "s1", &Artifact{Name: "chart", MIMEType: MIMEImagePNG, Data: []byte{1, 2, 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := store.Load(ctx, "s1", "report", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(latest.Data) != "two" || latest.Version != 2 || latest.CreatedAt.IsZero() {
+		t.Fatalf("latest = %+v", latest)
+	}
+	first, err := store.Load(ctx, "s1", "report", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first.Data) != "one" {
+		t.Fatalf("version 1 = %q", first.Data)
+	}
+	// Loaded artifacts do not share data with the store.
+	first.Data[0] = 'X'
+	if again, _ := store.Load(ctx, "s1", "report", 1); string(again.Data) != "one" {
+		t.Fatalf("stored data changed to %q", again.Data)
+	}
+
+	infos, err := store.List(ctx, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Name != "chart" || infos[1].Name != "report" || infos[1].Version != 2 || infos[0].Size != 3 {
+		t.Fatalf("list = %+v", infos)
+	}
+	versions, err := store.Versions(ctx, "s1", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].Version != 1 || versions[1].Version != 2 {
+		t.Fatalf("versions = %+v", versions)
+	}
+}
+
+func TestInMemoryArtifactStoreNotFound(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewInMemoryArtifactStore()
+	if _, err := store.Save(ctx, "s1", &Artifact{Name: "report", Data: []byte("x")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(ctx, "s1", "report", 2); !errors.Is(err, ErrArtifactNotFound) {
+		t.Fatalf("missing version: err = %v", err)
+	}
+	if _, err := store.Load(ctx, "s2", "report", 0); !errors.Is(err, ErrArtifactNotFound) {
+		t.Fatalf("other session: err = %v", err)
+	}
+	if _, err := store.Versions(ctx, "s1", "chart"); !errors.Is(err, ErrArtifactNotFound) {
+		t.Fatalf("missing name: err = %v", err)
+	}
+	infos, err := store.List(ctx, "s2")
+	if err != nil || len(infos) != 0 {
+		t.Fatalf("list = %+v, %v", infos, err)
+	}
+}
+
+func TestArtifactURI(t *testing.T) {
+	t.Parallel()
+
+	uri := ArtifactURI("images/chart.png", 3)
+	name, version, ok := ParseArtifactURI(uri)
+	if !ok || name != "images/chart.png" || version != 3 {
+		t.Fatalf("ParseArtifactURI(%q) = %q, %d, %v", uri, name, version, ok)
+	}
+	for _, uri := range []string{"https://example.com/a.png", "artifact://a", "artifact://a?version=0", "artifact://?version=1"} {
+		if _, _, ok := ParseArtifactURI(uri); ok {
+			t.Fatalf("ParseArtifactURI(%q) reported an artifact", uri)
+		}
+	}
+}
+
+func TestArtifactSessionOffloadsDataParts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewInMemoryArtifactStore()
+	base := NewSession()
+	session := NewArtifactSession(base, store, 4)
+	image := []byte{1, 2, 3, 4, 5}
+	message := UserMessage("look", DataPart{Name: "photo.png", Bytes: image, MIMEType: MIMEImagePNG}, DataPart{Bytes: []byte{9}, MIMEType: MIMEImagePNG})
+	if err := session.Append(ctx, message); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := message.Parts[1].(DataPart); !ok {
+		t.Fatalf("appended message was modified: %T", message.Parts[1])
+	}
+
+	stored, err := base.History(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, ok := stored[0].Parts[1].(FilePart)
+	if !ok || file.URI != ArtifactURI("photo.png", 1) || file.MIMEType != MIMEImagePNG {
+		t.Fatalf("stored part = %#v", stored[0].Parts[1])
+	}
+	if _, ok := stored[0].Parts[2].(DataPart); !ok {
+		t.Fatalf("small part = %T, want DataPart", stored[0].Parts[2])
+	}
+
+	history, err := session.History(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, ok := history[0].Parts[1].(DataPart)
+	if !ok || string(data.Bytes) != string(image) || data.Name != "photo.png" {
+		t.Fatalf("resolved part = %#v", history[0].Parts[1])
+	}
+	if _, ok := stored[0].Parts[1].(FilePart); !ok {
+		t.Fatal("resolving history modified the stored message")
+	}
+}
+
+func TestArtifactsFromToolContext(t *testing.T) {
+	t.Parallel()
+
+	store := NewInMemoryArtifactStore()
+	var saved FilePart
+	chart := bladestools.NewTool("chart", "draws a chart", bladestools.HandleFunc(func(ctx context.Context, _ string) (string, error) {
+		artifacts, ok := ArtifactsFromContext(ctx)
+		if !ok {
+			return "", errors.New("no artifacts in context")
+		}
+		part, err := artifacts.Save(ctx, &Artifact{Name: "chart.png", MIMEType: MIMEImagePNG, Data: []byte{1}})
+		if err != nil {
+			return "", err
+		}
+		saved = part
+		return "saved " + part.URI, nil
+	}))
+	session := NewSession()
+	agent, err := NewAgent("charts", WithModel(&multiToolModel{calls: []string{"chart"}}), WithTools(chart))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := NewRunner(agent, WithArtifactStore(store)).Run(context.Background(), UserMessage("go"), WithSession(session))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "saved " + ArtifactURI("chart.png", 1); output.Text() != want {
+		t.Fatalf("output = %q, want %q", output.Text(), want)
+	}
+	if saved.Name != "chart.png" || saved.MIMEType != MIMEImagePNG {
+		t.Fatalf("saved part = %+v", saved)
+	}
+	if _, err := store.Load(context.Background(), session.ID(), "chart.png", 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunnerDataPartOffload(t *testing.T) {
+	t.Parallel()
+
+	store := NewInMemoryArtifactStore()
+	model := &captureMessagesModel{}
+	agent, err := NewAgent("vision", WithModel(model), WithContext(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := NewSession()
+	runner := NewRunner(agent, WithArtifactStore(store), WithDataPartOffload(2))
+	input := UserMessage("describe", DataPart{Name: "photo.png", Bytes: []byte{1, 2, 3}, MIMEType: MIMEImagePNG})
+	if _, err := runner.Run(context.Background(), input, WithSession(session)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := model.captured[0][0].Parts[1].(DataPart); !ok {
+		t.Fatalf("model received %T, want DataPart", model.captured[0][0].Parts[1])
+	}
+	history, err := session.History(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := history[0].Parts[1].(FilePart); !ok {
+		t.Fatalf("persisted part = %T, want FilePart", history[0].Parts[1])
+	}
+	infos, err := store.List(context.Background(), session.ID())
+	if err != nil || len(infos) != 1 || infos[0].Name != "photo.png" {
+		t.Fatalf("artifacts = %+v, %v", infos, err)
+	}
+}
+
+func TestAgentResolvesArtifactFileParts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewInMemoryArtifactStore()
+	session := NewSession()
+	part, err := (&SessionArtifacts{store: store, sessionID: session.ID()}).Save(ctx,
+		&Artifact{Name: "report.pdf", MIMEType: MIMEPDF, Data: []byte("%PDF")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &captureMessagesModel{}
+	agent, err := NewAgent("reader", WithModel(model))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := UserMessage("summarize", part)
+	if _, err := NewRunner(agent, WithArtifactStore(store)).Run(ctx, input, WithSession(session)); err != nil {
+		t.Fatal(err)
+	}
+	data, ok := model.captured[0][0].Parts[1].(DataPart)
+	if !ok || data.Name != "report.pdf" || string(data.Bytes) != "%PDF" || data.MIMEType != MIMEPDF {
+		t.Fatalf("model received %+v, want the artifact content", model.captured[0][0].Parts[1])
+	}
+	if _, ok := input.Parts[1].(FilePart); !ok {
+		t.Fatalf("input part = %T, want the FilePart left in place", input.Parts[1])
+	}
+}
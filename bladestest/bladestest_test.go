@@ -0,0 +1,97 @@
+package bladestest_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/go-kratos/blades"
+	"github.com/go-kratos/blades/bladestest"
+)
+
+func newWeatherAgent(t *testing.T, model blades.ModelProvider, tool *bladestest.Tool) *blades.Runner {
+	t.Helper()
+	agent, err := blades.NewAgent("weather",
+		blades.WithModel(model),
+		blades.WithInstruction("You report the weather."),
+		blades.WithTools(tool),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blades.NewRunner(agent)
+}
+
+func TestScriptedToolCall(t *testing.T) {
+	t.Parallel()
+
+	tool := bladestest.NewTool("get_weather", `{"temp":21}`)
+	model := bladestest.NewModel("mock",
+		bladestest.ToolCall("get_weather", `{"city":"Paris"}`).
+			Expect(bladestest.ExpectInstructionContains("weather"), bladestest.ExpectTools("get_weather")),
+		bladestest.Reply("It is 21 degrees.").
+			Expect(bladestest.ExpectToolCall("get_weather"), bladestest.ExpectToolResult("get_weather", "21")),
+	)
+	output, err := newWeatherAgent(t, model, tool).Run(context.Background(), blades.UserMessage("Weather in Paris?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Text() != "It is 21 degrees." {
+		t.Fatalf("output = %q", output.Text())
+	}
+	if calls := tool.Calls(); len(calls) != 1 || calls[0].Arguments != `{"city":"Paris"}` {
+		t.Fatalf("tool calls = %+v", calls)
+	}
+	if err := model.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	requests := model.Requests()
+	if n := len(requests); n != 2 {
+		t.Fatalf("requests = %d; want 2", n)
+	}
+	if requests[0] == requests[1] {
+		t.Fatal("requests share the same pointer")
+	}
+	if n := len(requests[0].Messages); n != 1 {
+		t.Fatalf("first request messages = %d; want 1", n)
+	}
+	if n := len(requests[1].Messages); n != 2 {
+		t.Fatalf("second request messages = %d; want 2", n)
+	}
+}
+
+func TestScriptedStream(t *testing.T) {
+	t.Parallel()
+
+	model := bladestest.NewModel("mock", bladestest.StreamReply("Hel", "lo"))
+	runner := newWeatherAgent(t, model, bladestest.NewTool("get_weather", "{}"))
+	messages, err := bladestest.Collect(runner.RunStream(context.Background(), blades.UserMessage("hi")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := bladestest.Texts(messages), []string{"Hel", "lo", "Hello"}; !slices.Equal(got, want) {
+		t.Fatalf("texts = %q; want %q", got, want)
+	}
+}
+
+func TestScriptFailures(t *testing.T) {
+	t.Parallel()
+
+	tool := bladestest.NewTool("get_weather", "{}")
+	model := bladestest.NewModel("mock", bladestest.Reply("hi").Expect(bladestest.ExpectToolCall("get_weather")))
+	_, err := newWeatherAgent(t, model, tool).Run(context.Background(), blades.UserMessage("hi"))
+	if !errors.Is(err, bladestest.ErrExpectationFailed) {
+		t.Fatalf("err = %v; want ErrExpectationFailed", err)
+	}
+	_, err = newWeatherAgent(t, model, tool).Run(context.Background(), blades.UserMessage("hi"))
+	if !errors.Is(err, bladestest.ErrScriptExhausted) {
+		t.Fatalf("err = %v; want ErrScriptExhausted", err)
+	}
+
+	boom := errors.New("boom")
+	model.Enqueue(bladestest.Fail(boom))
+	if _, err := bladestest.Last(model.NewStreaming(context.Background(), &blades.ModelRequest{})); !errors.Is(err, boom) {
+		t.Fatalf("err = %v; want boom", err)
+	}
+}
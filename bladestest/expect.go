@@ -0,0 +1,102 @@
+package bladestest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-kratos/blades"
+)
+
+// Expectation checks a request received by a Model and describes how it
+// differs from what was expected.
+type Expectation func(req *blades.ModelRequest) error
+
+// ExpectToolCall expects the request to carry a completed call of the named
+// tool, that is the agent executed the tool and sent its result back.
+func ExpectToolCall(name string) Expectation {
+	return func(req *blades.ModelRequest) error {
+		var seen []string
+		for _, message := range req.Messages {
+			for _, part := range message.Parts {
+				if call, ok := part.(blades.ToolPart); ok && call.Completed {
+					if call.Name == name {
+						return nil
+					}
+					seen = append(seen, call.Name)
+				}
+			}
+		}
+		return fmt.Errorf("no result of tool %q in request, got tool results %v", name, seen)
+	}
+}
+
+// ExpectToolResult expects the request to carry a completed call of the
+// named tool whose response contains substr.
+func ExpectToolResult(name, substr string) Expectation {
+	return func(req *blades.ModelRequest) error {
+		for _, message := range req.Messages {
+			for _, part := range message.Parts {
+				if call, ok := part.(blades.ToolPart); ok && call.Completed && call.Name == name &&
+					strings.Contains(call.Response, substr) {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("no result of tool %q containing %q in request", name, substr)
+	}
+}
+
+// ExpectInstructionContains expects the system instruction to contain substr.
+func ExpectInstructionContains(substr string) Expectation {
+	return func(req *blades.ModelRequest) error {
+		var instruction string
+		if req.Instruction != nil {
+			instruction = req.Instruction.Text()
+		}
+		if !strings.Contains(instruction, substr) {
+			return fmt.Errorf("instruction %q does not contain %q", instruction, substr)
+		}
+		return nil
+	}
+}
+
+// ExpectLastMessageContains expects the text of the last message to contain substr.
+func ExpectLastMessageContains(substr string) Expectation {
+	return func(req *blades.ModelRequest) error {
+		var text string
+		if n := len(req.Messages); n > 0 && req.Messages[n-1] != nil {
+			text = req.Messages[n-1].Text()
+		}
+		if !strings.Contains(text, substr) {
+			return fmt.Errorf("last message %q does not contain %q", text, substr)
+		}
+		return nil
+	}
+}
+
+// ExpectTools expects the request to offer the named tools, among others.
+func ExpectTools(names ...string) Expectation {
+	return func(req *blades.ModelRequest) error {
+		offered := make([]string, 0, len(req.Tools))
+		for _, tool := range req.Tools {
+			offered = append(offered, tool.Name())
+		}
+		for _, name := range names {
+			if !slices.Contains(offered, name) {
+				return fmt.Errorf("tool %q not offered, got %v", name, offered)
+			}
+		}
+		return nil
+	}
+}
+
+// ExpectMessages expects the request to hold n messages.
+func ExpectMessages(n int) Expectation {
+	return func(req *blades.ModelRequest) error {
+		if len(req.Messages) != n {
+			return fmt.Errorf("request has %d messages, want %d", len(req.Messages), n)
+		}
+		return nil
+	}
+}
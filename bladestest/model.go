@@ -0,0 +1,220 @@
+// Package bladestest provides test doubles for unit-testing agents and
+// workflows built with blades without calling a real model: a scripted
+// ModelProvider, request expectations, a recording tool and helpers to drain
+// Generator streams.
+package bladestest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-kratos/blades"
+)
+
+var (
+	// ErrScriptExhausted is returned when the model is called after all of
+	// its turns were consumed.
+	ErrScriptExhausted = errors.New("bladestest: no scripted turn left")
+	// ErrExpectationFailed is returned when a request does not meet the
+	// expectations of its turn.
+	ErrExpectationFailed = errors.New("bladestest: expectation failed")
+)
+
+// Turn is a scripted model response.
+type Turn struct {
+	message  *blades.Message
+	chunks   []string
+	err      error
+	expects  []Expectation
+	usage    blades.TokenUsage
+	toolCall bool
+}
+
+// Reply returns a turn that answers with an assistant text message.
+func Reply(text string) *Turn {
+	return &Turn{message: blades.AssistantMessage(text)}
+}
+
+// StreamReply returns a turn that answers with an assistant text message. A
+// streaming call yields every chunk as an incomplete message followed by the
+// completed message holding the whole text; Generate returns the completed
+// message only.
+func StreamReply(chunks ...string) *Turn {
+	return &Turn{
+		message: blades.AssistantMessage(strings.Join(chunks, "")),
+		chunks:  chunks,
+	}
+}
+
+// ToolCall returns a turn that asks the agent to call the named tool with
+// JSON arguments.
+func ToolCall(name, arguments string) *Turn {
+	return ToolCalls(blades.NewToolPart("", name, arguments))
+}
+
+// ToolCalls returns a turn that asks the agent to call several tools. Calls
+// without an ID are numbered by the model.
+func ToolCalls(calls ...blades.ToolPart) *Turn {
+	message := &blades.Message{Role: blades.RoleTool}
+	for _, call := range calls {
+		message.Parts = append(message.Parts, call)
+	}
+	return &Turn{message: message, toolCall: true}
+}
+
+// Respond returns a turn that answers with a copy of message.
+func Respond(message *blades.Message) *Turn {
+	return &Turn{message: message, toolCall: message.Role == blades.RoleTool}
+}
+
+// Fail returns a turn that fails the model call with err.
+func Fail(err error) *Turn {
+	return &Turn{err: err}
+}
+
+// Expect adds expectations the request answered by this turn must meet.
+func (t *Turn) Expect(expectations ...Expectation) *Turn {
+	t.expects = append(t.expects, expectations...)
+	return t
+}
+
+// WithUsage sets the token usage reported with the response.
+func (t *Turn) WithUsage(usage blades.TokenUsage) *Turn {
+	t.usage = usage
+	return t
+}
+
+// Model is a ModelProvider that answers each call with the next turn of its
+// script, and records the requests it receives. It is safe for concurrent use.
+type Model struct {
+	name string
+
+	mu       sync.Mutex
+	turns    []*Turn
+	requests []*blades.ModelRequest
+	calls    int
+}
+
+// NewModel returns a scripted model that answers with turns in order.
+func NewModel(name string, turns ...*Turn) *Model {
+	return &Model{name: name, turns: turns}
+}
+
+// Enqueue appends turns to the script.
+func (m *Model) Enqueue(turns ...*Turn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.turns = append(m.turns, turns...)
+}
+
+// Name implements blades.ModelProvider.
+func (m *Model) Name() string {
+	return m.name
+}
+
+// Requests returns copies of the requests received so far, as they were when
+// the model was called.
+func (m *Model) Requests() []*blades.ModelRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*blades.ModelRequest(nil), m.requests...)
+}
+
+// Remaining returns the number of turns not consumed yet.
+func (m *Model) Remaining() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.turns)
+}
+
+// Verify returns an error when some turns were not consumed.
+func (m *Model) Verify() error {
+	if n := m.Remaining(); n > 0 {
+		return fmt.Errorf("bladestest: %d scripted turns were not consumed", n)
+	}
+	return nil
+}
+
+// next pops the next turn after checking its expectations against req.
+func (m *Model) next(req *blades.ModelRequest) (*Turn, *blades.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, snapshotRequest(req))
+	m.calls++
+	if len(m.turns) == 0 {
+		return nil, nil, fmt.Errorf("%w: call %d to %s", ErrScriptExhausted, m.calls, m.name)
+	}
+	turn := m.turns[0]
+	m.turns = m.turns[1:]
+	for _, expect := range turn.expects {
+		if err := expect(req); err != nil {
+			return nil, nil, fmt.Errorf("%w: call %d to %s: %v", ErrExpectationFailed, m.calls, m.name, err)
+		}
+	}
+	if turn.err != nil {
+		return nil, nil, turn.err
+	}
+	message := turn.message.Clone()
+	message.ID = blades.NewMessageID()
+	message.Status = blades.StatusCompleted
+	message.TokenUsage = turn.usage
+	if turn.toolCall {
+		for i, part := range message.Parts {
+			if call, ok := part.(blades.ToolPart); ok && call.ID == "" {
+				call.ID = "call_" + strconv.Itoa(m.calls) + "_" + strconv.Itoa(i)
+				message.Parts[i] = call
+			}
+		}
+	}
+	return turn, message, nil
+}
+
+// snapshotRequest copies req, since the agent loop keeps appending to and
+// reusing the request it passes to the model.
+func snapshotRequest(req *blades.ModelRequest) *blades.ModelRequest {
+	r := *req
+	r.Messages = slices.Clone(req.Messages)
+	r.Tools = slices.Clone(req.Tools)
+	r.Generation = req.Generation.Clone()
+	if req.ToolChoice != nil {
+		choice := *req.ToolChoice
+		r.ToolChoice = &choice
+	}
+	return &r
+}
+
+// Generate implements blades.ModelProvider.
+func (m *Model) Generate(_ context.Context, req *blades.ModelRequest) (*blades.ModelResponse, error) {
+	_, message, err := m.next(req)
+	if err != nil {
+		return nil, err
+	}
+	return &blades.ModelResponse{Message: message}, nil
+}
+
+// NewStreaming implements blades.ModelProvider.
+func (m *Model) NewStreaming(_ context.Context, req *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
+	return func(yield func(*blades.ModelResponse, error) bool) {
+		turn, message, err := m.next(req)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, chunk := range turn.chunks {
+			if !yield(&blades.ModelResponse{Message: &blades.Message{
+				ID:     message.ID,
+				Role:   message.Role,
+				Status: blades.StatusIncomplete,
+				Parts:  []blades.Part{blades.TextPart{Text: chunk}},
+			}}, nil) {
+				return
+			}
+		}
+		yield(&blades.ModelResponse{Message: message}, nil)
+	}
+}
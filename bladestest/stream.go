@@ -0,0 +1,38 @@
+package bladestest
+
+import "github.com/go-kratos/blades"
+
+// Collect drains a stream and returns every value it yielded before the
+// first error, along with that error.
+func Collect[T any](stream blades.Generator[T, error]) ([]T, error) {
+	var values []T
+	for v, err := range stream {
+		if err != nil {
+			return values, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// Last drains a stream and returns the last value it yielded, or the first error.
+func Last[T any](stream blades.Generator[T, error]) (T, error) {
+	var last T
+	for v, err := range stream {
+		if err != nil {
+			return last, err
+		}
+		last = v
+	}
+	return last, nil
+}
+
+// Texts returns the text of each message, which is handy to compare the
+// chunks of an agent stream.
+func Texts(messages []*blades.Message) []string {
+	texts := make([]string, 0, len(messages))
+	for _, message := range messages {
+		texts = append(texts, message.Text())
+	}
+	return texts
+}
@@ -0,0 +1,81 @@
+package bladestest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-kratos/blades/tools"
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// Call is a call recorded by a Tool.
+type Call struct {
+	Arguments string
+	Response  string
+	Err       error
+}
+
+// Tool is a tools.Tool that records its calls. It is safe for concurrent use.
+type Tool struct {
+	name        string
+	description string
+	inputSchema *jsonschema.Schema
+	handler     tools.HandleFunc
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// NewTool returns a tool that answers every call with response.
+func NewTool(name, response string) *Tool {
+	return NewToolFunc(name, func(context.Context, string) (string, error) {
+		return response, nil
+	})
+}
+
+// NewToolFunc returns a tool that answers calls with handler.
+func NewToolFunc(name string, handler tools.HandleFunc) *Tool {
+	return &Tool{name: name, description: "test tool " + name, handler: handler}
+}
+
+// WithInputSchema sets the input schema the tool reports, so the agent
+// validates the arguments of its calls.
+func (t *Tool) WithInputSchema(schema *jsonschema.Schema) *Tool {
+	t.inputSchema = schema
+	return t
+}
+
+// Name implements tools.Tool.
+func (t *Tool) Name() string { return t.name }
+
+// Description implements tools.Tool.
+func (t *Tool) Description() string { return t.description }
+
+// InputSchema implements tools.Tool.
+func (t *Tool) InputSchema() *jsonschema.Schema { return t.inputSchema }
+
+// OutputSchema implements tools.Tool.
+func (t *Tool) OutputSchema() *jsonschema.Schema { return nil }
+
+// Handle implements tools.Tool and records the call.
+func (t *Tool) Handle(ctx context.Context, arguments string) (string, error) {
+	response, err := t.handler(ctx, arguments)
+	t.mu.Lock()
+	t.calls = append(t.calls, Call{Arguments: arguments, Response: response, Err: err})
+	t.mu.Unlock()
+	return response, err
+}
+
+// Calls returns the calls recorded so far.
+func (t *Tool) Calls() []Call {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Call(nil), t.calls...)
+}
+
+// Called returns the number of recorded calls.
+func (t *Tool) Called() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.calls)
+}
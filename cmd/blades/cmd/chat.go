@@ -38,6 +38,9 @@ func newChatCmd() *cobra.Command {
 					clichi.WithClearSession(func(sessionID string) error {
 						return rt.Sessions.Delete(sessionID)
 					}),
+					clichi.WithCost(func(sessionID string) (string, error) {
+						return appcore.SessionCostReport(rt.Sessions.GetOrNew(sessionID), rt.Pricing), nil
+					}),
 				}
 				if simpleMode {
 					opts = append(opts, clichi.WithNoAltScreen())
@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-kratos/blades"
 	recipeMiddleware "github.com/go-kratos/blades/middleware"
+	"github.com/go-kratos/blades/pricing"
 	"github.com/go-kratos/blades/recipe"
 	bladeskills "github.com/go-kratos/blades/skills"
 	bladestools "github.com/go-kratos/blades/tools"
@@ -49,7 +50,15 @@ func BuildRunner(cfg *config.Config, ws *workspace.Workspace, cronSvc *cron.Serv
 
 	toolRegistry := BuildToolRegistry(ExecConfigFromDefaults(DefaultExecWorkingDir(ws), cfg.Exec), cronSvc, extraTools...)
 	middlewareRegistry := BuildMiddlewareRegistry()
-	reg := model.NewRegistry(cfg.Providers)
+	prices, err := LoadPricing(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var modelMiddlewares []blades.ModelMiddleware
+	if prices != nil {
+		modelMiddlewares = append(modelMiddlewares, pricing.Middleware(prices))
+	}
+	reg := model.NewRegistry(cfg.Providers, modelMiddlewares...)
 	agent, err := recipe.Build(spec,
 		recipe.WithModelRegistry(reg),
 		recipe.WithToolRegistry(toolRegistry),
@@ -62,6 +71,33 @@ func BuildRunner(cfg *config.Config, ws *workspace.Workspace, cronSvc *cron.Serv
 	return blades.NewRunner(agent), nil
 }
 
+// LoadPricing loads the price table configured by cfg.Pricing, or returns nil
+// when none is configured.
+func LoadPricing(cfg *config.Config) (*pricing.Registry, error) {
+	if cfg == nil || cfg.Pricing == "" {
+		return nil, nil
+	}
+	prices, err := pricing.LoadRegistry(cfg.Pricing)
+	if err != nil {
+		return nil, fmt.Errorf("pricing: %w", err)
+	}
+	return prices, nil
+}
+
+// SessionCostReport formats the token usage and estimated cost of a session.
+func SessionCostReport(sess blades.Session, prices *pricing.Registry) string {
+	usage := blades.SessionUsage(sess)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Tokens: %d input (%d cached), %d output, %d total",
+		usage.InputTokens, usage.CachedInputTokens, usage.OutputTokens, usage.TotalTokens)
+	if prices == nil {
+		b.WriteString("\nCost: not tracked (set pricing in config.yaml)")
+	} else {
+		fmt.Fprintf(&b, "\nCost: %.4f %s", pricing.SessionCost(sess), prices.Currency)
+	}
+	return b.String()
+}
+
 func BuildSessionManager(cfg *config.Config, ws *workspace.Workspace) (*session.Manager, error) {
 	spec, err := LoadAgentSpec(ws)
 	if err != nil {
@@ -15,6 +15,7 @@ import (
 	"github.com/go-kratos/blades/cmd/blades/internal/memory"
 	"github.com/go-kratos/blades/cmd/blades/internal/session"
 	"github.com/go-kratos/blades/cmd/blades/internal/workspace"
+	"github.com/go-kratos/blades/pricing"
 )
 
 type Runtime struct {
@@ -24,6 +25,7 @@ type Runtime struct {
 	Sessions  *session.Manager
 	Cron      *cron.Service
 	Runner    *blades.Runner
+	Pricing   *pricing.Registry
 }
 
 type TurnOptions struct {
@@ -62,6 +64,10 @@ func BuildRuntime(cfg *config.Config, ws *workspace.Workspace, mem *memory.Store
 	if err != nil {
 		return nil, err
 	}
+	prices, err := LoadPricing(cfg)
+	if err != nil {
+		return nil, err
+	}
 	return &Runtime{
 		Config:    cfg,
 		Workspace: ws,
@@ -69,6 +75,7 @@ func BuildRuntime(cfg *config.Config, ws *workspace.Workspace, mem *memory.Store
 		Sessions:  sessMgr,
 		Cron:      cronSvc,
 		Runner:    runner,
+		Pricing:   prices,
 	}, nil
 }
 
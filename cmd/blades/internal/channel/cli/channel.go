@@ -43,6 +43,7 @@ type Channel struct {
 	stop          func() error
 	switchSession func(string) error
 	clearSession  func(string) error
+	cost          func(string) (string, error)
 	debug         bool
 	noAltScreen   bool
 	cmdProc       *command.Processor
@@ -69,6 +70,11 @@ func WithClearSession(fn func(string) error) Option {
 	return func(c *Channel) { c.clearSession = fn }
 }
 
+// WithCost sets the function called when the user issues /cost.
+func WithCost(fn func(string) (string, error)) Option {
+	return func(c *Channel) { c.cost = fn }
+}
+
 // WithDebug enables verbose error output.
 func WithDebug(enabled bool) Option {
 	return func(c *Channel) { c.debug = enabled }
@@ -140,6 +146,7 @@ func (c *Channel) Start(ctx context.Context, handler channel.StreamHandler) erro
 	m := newModel(ctx, handler, c.sessionID, c.stop, c.debug, glamourStyle, isDark, c.cmdProc)
 	m.switchSession = c.switchSession
 	m.clearSession = c.clearSession
+	m.cost = c.cost
 	// AltScreen is declared in View() — no program options needed.
 	// Real cursor is used (SetVirtualCursor(false) in newModel) so that
 	// ConPTY/IME can track the physical cursor and position the pre-edit window.
@@ -192,6 +199,12 @@ func (c *Channel) startSimple(ctx context.Context, handler channel.StreamHandler
 					}
 					return nil
 				},
+				CostFunc: func() (string, error) {
+					if c.cost == nil {
+						return "(cost not available)", nil
+					}
+					return c.cost(c.sessionID)
+				},
 				Processor: c.cmdProc,
 			}
 
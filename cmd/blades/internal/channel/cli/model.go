@@ -53,6 +53,7 @@ type model struct {
 	stop          func() error
 	switchSession func(string) error
 	clearSession  func(string) error
+	cost          func(string) (string, error)
 	debug         bool
 	glamourStyle  string // "dark" or "light"
 	isDark        bool
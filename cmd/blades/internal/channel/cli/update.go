@@ -242,6 +242,12 @@ func (m *model) handleSlash(line string) (tea.Model, tea.Cmd) {
 			m.resetConversationView()
 			return nil
 		},
+		CostFunc: func() (string, error) {
+			if m.cost == nil {
+				return "(cost not available)", nil
+			}
+			return m.cost(m.sessionID)
+		},
 		Processor: m.cmdProc,
 		Custom: map[string]interface{}{
 			"helpSuffix": cliHelpSuffix,
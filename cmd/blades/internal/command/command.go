@@ -30,6 +30,9 @@ type Environment struct {
 	// ClearFunc clears the conversation history
 	ClearFunc func() error
 
+	// CostFunc reports the token usage and estimated cost of the current session
+	CostFunc func() (string, error)
+
 	// Processor is the command processor (optional). When set, /help lists its registered commands.
 	Processor *Processor
 
@@ -96,6 +99,13 @@ func (p *Processor) registerDefaults() {
 		Handler:     clearHandler,
 	})
 
+	p.Register(&Command{
+		Name:        "cost",
+		Description: "Show token usage and estimated cost of the session",
+		Usage:       "/cost",
+		Handler:     costHandler,
+	})
+
 	p.Register(&Command{
 		Name:        "exit",
 		Aliases:     []string{"quit"},
@@ -177,6 +187,7 @@ func helpHandler(ctx context.Context, args []string, env *Environment) (*Result,
 			{"/stop", "Stop current response (keep chatting)"},
 			{"/session [id]", "Show or switch session"},
 			{"/clear", "Clear conversation"},
+			{"/cost", "Show session usage and cost"},
 			{"/exit", "Quit"},
 		}
 		for _, cmd := range commands {
@@ -277,6 +288,26 @@ func clearHandler(ctx context.Context, args []string, env *Environment) (*Result
 	}, nil
 }
 
+func costHandler(ctx context.Context, args []string, env *Environment) (*Result, error) {
+	if env == nil || env.CostFunc == nil {
+		return &Result{
+			Message: "(cost not available)",
+		}, nil
+	}
+
+	report, err := env.CostFunc()
+	if err != nil {
+		return &Result{
+			Message: fmt.Sprintf("Cost failed: %v", err),
+			IsError: true,
+		}, nil
+	}
+
+	return &Result{
+		Message: report,
+	}, nil
+}
+
 func exitHandler(ctx context.Context, args []string, env *Environment) (*Result, error) {
 	return &Result{
 		Message:    "Bye! 👋",
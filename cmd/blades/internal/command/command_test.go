@@ -96,6 +96,21 @@ func TestProcessor_Process(t *testing.T) {
 			expectError: false,
 			expectMsg:   "cleared",
 		},
+		{
+			name: "cost command",
+			line: "/cost",
+			env: &Environment{
+				CostFunc: func() (string, error) { return "Cost: 0.0120 USD", nil },
+			},
+			expectError: false,
+			expectMsg:   "0.0120 USD",
+		},
+		{
+			name:        "cost command unavailable",
+			line:        "/cost",
+			expectError: false,
+			expectMsg:   "not available",
+		},
 	}
 
 	for _, tt := range tests {
@@ -154,7 +169,7 @@ func TestProcessor_GetCommands(t *testing.T) {
 		cmdNames[cmd.Name] = true
 	}
 
-	expected := []string{"help", "stop", "session", "clear", "exit"}
+	expected := []string{"help", "stop", "session", "clear", "cost", "exit"}
 	for _, name := range expected {
 		if !cmdNames[name] {
 			t.Errorf("expected command %q not found", name)
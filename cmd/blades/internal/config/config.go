@@ -11,6 +11,9 @@ type Config struct {
 	Providers []Provider    `yaml:"providers"`
 	Exec      ExecConfig    `yaml:"exec"`
 	Channels  ChannelConfig `yaml:"channels"`
+	// Pricing is the path of a YAML price table used to estimate the cost of
+	// model calls. Costs are not tracked when empty.
+	Pricing string `yaml:"pricing"`
 }
 
 // Provider holds credentials and model list for a single model provider.
@@ -49,6 +52,7 @@ func (c *Config) Normalize() error {
 		}
 		seenNames[p.Name] = struct{}{}
 	}
+	c.Pricing = ExpandTilde(strings.TrimSpace(c.Pricing))
 	c.Channels.Weixin.AccountDir = ExpandTilde(strings.TrimSpace(c.Channels.Weixin.AccountDir))
 	c.Channels.Weixin.StateDir = ExpandTilde(strings.TrimSpace(c.Channels.Weixin.StateDir))
 	c.Channels.Weixin.MediaDir = ExpandTilde(strings.TrimSpace(c.Channels.Weixin.MediaDir))
@@ -10,12 +10,14 @@ import (
 
 // Registry resolves "name/model" strings using a list of Provider configs.
 type Registry struct {
-	providers []config.Provider
+	providers   []config.Provider
+	middlewares []blades.ModelMiddleware
 }
 
 // NewRegistry creates a Registry from the providers list in config.yaml.
-func NewRegistry(providers []config.Provider) *Registry {
-	return &Registry{providers: providers}
+// The middlewares wrap every resolved ModelProvider.
+func NewRegistry(providers []config.Provider, mws ...blades.ModelMiddleware) *Registry {
+	return &Registry{providers: providers, middlewares: mws}
 }
 
 // Resolve looks up a "name/model" reference and returns a ModelProvider.
@@ -35,7 +37,7 @@ func (r *Registry) Resolve(name string) (blades.ModelProvider, error) {
 		}
 		for _, m := range p.Models {
 			if m == modelName {
-				return NewProvider(p, modelName)
+				return r.newProvider(p, modelName)
 			}
 		}
 	}
@@ -46,7 +48,7 @@ func (r *Registry) Resolve(name string) (blades.ModelProvider, error) {
 	if providerRef != "" {
 		for _, p := range r.providers {
 			if matchesProviderName(p, providerRef) {
-				return NewProvider(p, modelName)
+				return r.newProvider(p, modelName)
 			}
 		}
 	}
@@ -54,6 +56,14 @@ func (r *Registry) Resolve(name string) (blades.ModelProvider, error) {
 	return nil, fmt.Errorf("model %q not found in any configured provider", name)
 }
 
+func (r *Registry) newProvider(p config.Provider, modelName string) (blades.ModelProvider, error) {
+	provider, err := NewProvider(p, modelName)
+	if err != nil {
+		return nil, err
+	}
+	return blades.WrapModel(provider, r.middlewares...), nil
+}
+
 func matchesProviderName(p config.Provider, ref string) bool {
 	return p.Name == ref
 }
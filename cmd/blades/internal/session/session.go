@@ -18,37 +18,11 @@ import (
 
 // persistedSession is the JSON envelope stored on disk.
 type persistedSession struct {
-	ID           string             `json:"id"`
-	CreatedAt    time.Time          `json:"createdAt"`
-	LastAccessAt time.Time          `json:"lastAccessAt,omitempty"`
-	State        map[string]any     `json:"state"`
-	History      []persistedMessage `json:"history,omitempty"`
-}
-
-type persistedMessage struct {
 	ID           string            `json:"id"`
-	Role         blades.Role       `json:"role"`
-	Author       string            `json:"author"`
-	InvocationID string            `json:"invocationId,omitempty"`
-	Status       blades.Status     `json:"status"`
-	FinishReason string            `json:"finishReason,omitempty"`
-	TokenUsage   blades.TokenUsage `json:"tokenUsage,omitempty"`
-	Actions      map[string]any    `json:"actions,omitempty"`
-	Metadata     map[string]any    `json:"metadata,omitempty"`
-	Parts        []persistedPart   `json:"parts,omitempty"`
-}
-
-type persistedPart struct {
-	Type      string          `json:"type"`
-	Text      string          `json:"text,omitempty"`
-	Name      string          `json:"name,omitempty"`
-	URI       string          `json:"uri,omitempty"`
-	MIMEType  blades.MIMEType `json:"mimeType,omitempty"`
-	Bytes     []byte          `json:"bytes,omitempty"`
-	ID        string          `json:"id,omitempty"`
-	Request   string          `json:"arguments,omitempty"`
-	Response  string          `json:"result,omitempty"`
-	Completed bool            `json:"completed,omitempty"`
+	CreatedAt    time.Time         `json:"createdAt"`
+	LastAccessAt time.Time         `json:"lastAccessAt,omitempty"`
+	State        map[string]any    `json:"state"`
+	History      []*blades.Message `json:"history,omitempty"`
 }
 
 type managedSession struct {
@@ -160,7 +134,7 @@ func (m *Manager) Save(sess blades.Session) error {
 		CreatedAt:    createdAt,
 		LastAccessAt: now,
 		State:        sess.State(),
-		History:      makePersistedHistory(history),
+		History:      history,
 	}
 
 	data, err := json.MarshalIndent(ps, "", "  ")
@@ -181,11 +155,7 @@ func (m *Manager) loadFromDisk(id string) (blades.Session, error) {
 	if err := json.Unmarshal(data, &ps); err != nil {
 		return nil, fmt.Errorf("session: unmarshal %s: %w", id, err)
 	}
-	history, err := restoreHistory(ps.History)
-	if err != nil {
-		return nil, fmt.Errorf("session: decode history %s: %w", id, err)
-	}
-	return newManagedSession(id, ps.State, history, m.sessionOpts...), nil
+	return newManagedSession(id, ps.State, ps.History, m.sessionOpts...), nil
 }
 
 // SessionInfo holds metadata for a persisted session (for listing and archival).
@@ -259,6 +229,9 @@ func newManagedSession(id string, state map[string]any, history []*blades.Messag
 		base.SetState(key, value)
 	}
 	for _, message := range history {
+		if message == nil {
+			continue
+		}
 		if err := base.Append(context.Background(), message); err != nil {
 			// Log the error but continue loading other messages to avoid losing the entire session
 			fmt.Fprintf(os.Stderr, "warn: session %s: failed to append message %s: %v\n", id, message.ID, err)
@@ -281,127 +254,3 @@ func historyForPersistence(sess blades.Session) ([]*blades.Message, error) {
 	}
 	return history, nil
 }
-
-func makePersistedHistory(history []*blades.Message) []persistedMessage {
-	if len(history) == 0 {
-		return nil
-	}
-	persisted := make([]persistedMessage, 0, len(history))
-	for _, message := range history {
-		if message == nil {
-			continue
-		}
-		persisted = append(persisted, persistedMessage{
-			ID:           message.ID,
-			Role:         message.Role,
-			Author:       message.Author,
-			InvocationID: message.InvocationID,
-			Status:       message.Status,
-			FinishReason: message.FinishReason,
-			TokenUsage:   message.TokenUsage,
-			Actions:      message.Actions,
-			Metadata:     message.Metadata,
-			Parts:        makePersistedParts(message.Parts),
-		})
-	}
-	return persisted
-}
-
-func makePersistedParts(parts []blades.Part) []persistedPart {
-	if len(parts) == 0 {
-		return nil
-	}
-	persisted := make([]persistedPart, 0, len(parts))
-	for _, part := range parts {
-		switch v := part.(type) {
-		case blades.TextPart:
-			persisted = append(persisted, persistedPart{Type: "text", Text: v.Text})
-		case blades.FilePart:
-			persisted = append(persisted, persistedPart{
-				Type:     "file",
-				Name:     v.Name,
-				URI:      v.URI,
-				MIMEType: v.MIMEType,
-			})
-		case blades.DataPart:
-			persisted = append(persisted, persistedPart{
-				Type:     "data",
-				Name:     v.Name,
-				Bytes:    v.Bytes,
-				MIMEType: v.MIMEType,
-			})
-		case blades.ToolPart:
-			persisted = append(persisted, persistedPart{
-				Type:      "tool",
-				ID:        v.ID,
-				Name:      v.Name,
-				Request:   v.Request,
-				Response:  v.Response,
-				Completed: v.Completed,
-			})
-		}
-	}
-	return persisted
-}
-
-func restoreHistory(history []persistedMessage) ([]*blades.Message, error) {
-	if len(history) == 0 {
-		return nil, nil
-	}
-	restored := make([]*blades.Message, 0, len(history))
-	for _, message := range history {
-		parts, err := restoreParts(message.Parts)
-		if err != nil {
-			return nil, err
-		}
-		restored = append(restored, &blades.Message{
-			ID:           message.ID,
-			Role:         message.Role,
-			Author:       message.Author,
-			InvocationID: message.InvocationID,
-			Status:       message.Status,
-			FinishReason: message.FinishReason,
-			TokenUsage:   message.TokenUsage,
-			Actions:      message.Actions,
-			Metadata:     message.Metadata,
-			Parts:        parts,
-		})
-	}
-	return restored, nil
-}
-
-func restoreParts(parts []persistedPart) ([]blades.Part, error) {
-	if len(parts) == 0 {
-		return nil, nil
-	}
-	restored := make([]blades.Part, 0, len(parts))
-	for _, part := range parts {
-		switch part.Type {
-		case "text":
-			restored = append(restored, blades.TextPart{Text: part.Text})
-		case "file":
-			restored = append(restored, blades.FilePart{
-				Name:     part.Name,
-				URI:      part.URI,
-				MIMEType: part.MIMEType,
-			})
-		case "data":
-			restored = append(restored, blades.DataPart{
-				Name:     part.Name,
-				Bytes:    part.Bytes,
-				MIMEType: part.MIMEType,
-			})
-		case "tool":
-			restored = append(restored, blades.ToolPart{
-				ID:        part.ID,
-				Name:      part.Name,
-				Request:   part.Request,
-				Response:  part.Response,
-				Completed: part.Completed,
-			})
-		default:
-			return nil, fmt.Errorf("unknown message part type %q", part.Type)
-		}
-	}
-	return restored, nil
-}
@@ -144,28 +144,40 @@ func TestSessionHelpersSortAndRestoreBranches(t *testing.T) {
 		t.Fatalf("sorted infos = %+v", infos)
 	}
 
-	parts := makePersistedParts([]blades.Part{
+}
+
+func TestManagerReloadPreservesFileAndDataParts(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	mgr := NewManager(dir)
+	sess := mgr.GetOrNew("parts")
+	if err := sess.Append(context.Background(), blades.UserMessage(
 		blades.FilePart{Name: "doc", URI: "file:///tmp/doc", MIMEType: "text/plain"},
 		blades.DataPart{Name: "blob", Bytes: []byte("abc"), MIMEType: "application/octet-stream"},
-		nil,
-	})
-	if len(parts) != 2 {
-		t.Fatalf("persisted parts = %+v", parts)
+	)); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := mgr.Save(sess); err != nil {
+		t.Fatalf("save session: %v", err)
 	}
 
-	restored, err := restoreParts([]persistedPart{
-		{Type: "file", Name: "doc", URI: "file:///tmp/doc", MIMEType: "text/plain"},
-		{Type: "data", Name: "blob", Bytes: []byte("abc"), MIMEType: "application/octet-stream"},
-	})
+	reloaded, err := NewManager(dir).Get("parts")
 	if err != nil {
-		t.Fatalf("restoreParts: %v", err)
+		t.Fatalf("reload session: %v", err)
 	}
-	if len(restored) != 2 {
-		t.Fatalf("restored parts = %+v", restored)
+	history, err := reloaded.History(context.Background())
+	if err != nil {
+		t.Fatalf("history: %v", err)
 	}
-
-	if _, err := restoreParts([]persistedPart{{Type: "unknown"}}); err == nil {
-		t.Fatal("expected unknown part type error")
+	if len(history) != 1 || len(history[0].Parts) != 2 {
+		t.Fatalf("reloaded history = %+v", history)
+	}
+	if file := history[0].File(); file == nil || file.URI != "file:///tmp/doc" {
+		t.Fatalf("reloaded file part = %+v", file)
+	}
+	if data := history[0].Data(); data == nil || string(data.Bytes) != "abc" {
+		t.Fatalf("reloaded data part = %+v", data)
 	}
 }
 
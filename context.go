@@ -32,8 +32,11 @@ func FromAgentContext(ctx context.Context) (AgentContext, bool) {
 }
 
 type toolContext struct {
-	id      string
-	name    string
+	id   string
+	name string
+	// base holds the message's actions when the call started; actions holds
+	// those set by the call.
+	base    map[string]any
 	actions *maps.Map[string, any]
 }
 
@@ -44,7 +47,7 @@ func (t *toolContext) Name() string {
 	return t.name
 }
 func (t *toolContext) Actions() map[string]any {
-	return t.actions.ToMap()
+	return MergeActions(t.base, t.actions.ToMap())
 }
 func (t *toolContext) SetAction(key string, value any) {
 	t.actions.Store(key, value)
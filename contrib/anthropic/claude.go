@@ -136,6 +136,9 @@ func (m *Claude) toClaudeParams(req *blades.ModelRequest) (*anthropic.MessageNew
 	if m.config.Thinking != nil {
 		params.Thinking = *m.config.Thinking
 	}
+	if err := applyGeneration(params, req.Generation); err != nil {
+		return nil, err
+	}
 	if req.Instruction != nil {
 		params.System = []anthropic.TextBlockParam{{Text: req.Instruction.Text()}}
 	}
@@ -144,9 +147,17 @@ func (m *Claude) toClaudeParams(req *blades.ModelRequest) (*anthropic.MessageNew
 		case blades.RoleSystem:
 			params.System = []anthropic.TextBlockParam{{Text: msg.Text()}}
 		case blades.RoleUser:
-			params.Messages = append(params.Messages, anthropic.NewUserMessage(convertPartsToContent(msg.Parts)...))
+			content, err := convertPartsToContent(msg.Parts)
+			if err != nil {
+				return nil, err
+			}
+			params.Messages = append(params.Messages, anthropic.NewUserMessage(content...))
 		case blades.RoleAssistant:
-			params.Messages = append(params.Messages, anthropic.NewAssistantMessage(convertPartsToContent(msg.Parts)...))
+			content, err := convertPartsToContent(msg.Parts)
+			if err != nil {
+				return nil, err
+			}
+			params.Messages = append(params.Messages, anthropic.NewAssistantMessage(content...))
 		case blades.RoleTool:
 			var (
 				toolResults      []anthropic.ContentBlockParamUnion
@@ -156,6 +167,12 @@ func (m *Claude) toClaudeParams(req *blades.ModelRequest) (*anthropic.MessageNew
 				switch v := any(part).(type) {
 				case blades.TextPart:
 					assistantContent = append(assistantContent, anthropic.NewTextBlock(v.Text))
+				case blades.ReasoningPart:
+					// Claude requires the thinking that preceded a tool use
+					// to be sent back with it.
+					if block, ok := convertReasoningToClaude(v); ok {
+						assistantContent = append(assistantContent, block)
+					}
 				case blades.ToolPart:
 					toolResults = append(toolResults, anthropic.NewToolResultBlock(v.ID, v.Response, false))
 					assistantContent = append(assistantContent, anthropic.NewToolUseBlock(v.ID, decodeToolRequest(v.Request), v.Name))
@@ -175,6 +192,13 @@ func (m *Claude) toClaudeParams(req *blades.ModelRequest) (*anthropic.MessageNew
 			return params, fmt.Errorf("converting tools: %w", err)
 		}
 		params.Tools = tools
+		if req.ToolChoice != nil {
+			toolChoice, err := convertToolChoiceToClaude(req.ToolChoice)
+			if err != nil {
+				return params, err
+			}
+			params.ToolChoice = toolChoice
+		}
 	}
 	if m.config.CacheControl {
 		applyEphemeralCache(params)
@@ -182,6 +206,56 @@ func (m *Claude) toClaudeParams(req *blades.ModelRequest) (*anthropic.MessageNew
 	return params, nil
 }
 
+// convertToolChoiceToClaude converts a tool choice into Claude's tool_choice;
+// Claude calls a required tool "any".
+func convertToolChoiceToClaude(choice *blades.ToolChoice) (anthropic.ToolChoiceUnionParam, error) {
+	switch choice.Mode {
+	case blades.ToolChoiceAuto:
+		return anthropic.ToolChoiceUnionParam{OfAuto: &anthropic.ToolChoiceAutoParam{}}, nil
+	case blades.ToolChoiceNone:
+		return anthropic.ToolChoiceUnionParam{OfNone: &anthropic.ToolChoiceNoneParam{}}, nil
+	case blades.ToolChoiceRequired:
+		return anthropic.ToolChoiceUnionParam{OfAny: &anthropic.ToolChoiceAnyParam{}}, nil
+	case blades.ToolChoiceTool:
+		return anthropic.ToolChoiceParamOfTool(choice.Name), nil
+	}
+	return anthropic.ToolChoiceUnionParam{}, fmt.Errorf("anthropic: unsupported tool choice mode %q", choice.Mode)
+}
+
+// applyGeneration merges the generation settings of a request over the
+// static configuration. Claude has no seed or penalties, so setting them
+// is reported as unsupported.
+func applyGeneration(params *anthropic.MessageNewParams, generation *blades.GenerationConfig) error {
+	if generation == nil {
+		return nil
+	}
+	if unsupported := generation.Unsupported(
+		blades.GenerationTemperature,
+		blades.GenerationTopP,
+		blades.GenerationTopK,
+		blades.GenerationMaxOutputTokens,
+		blades.GenerationStopSequences,
+	); len(unsupported) > 0 {
+		return &blades.UnsupportedGenerationError{Provider: providerName, Fields: unsupported}
+	}
+	if generation.Temperature != nil {
+		params.Temperature = anthropic.Float(*generation.Temperature)
+	}
+	if generation.TopP != nil {
+		params.TopP = anthropic.Float(*generation.TopP)
+	}
+	if generation.TopK != nil {
+		params.TopK = anthropic.Int(*generation.TopK)
+	}
+	if generation.MaxOutputTokens != nil {
+		params.MaxTokens = *generation.MaxOutputTokens
+	}
+	if len(generation.StopSequences) > 0 {
+		params.StopSequences = generation.StopSequences
+	}
+	return nil
+}
+
 // applyEphemeralCache stamps an ephemeral cache_control breakpoint on the last
 // block of each cacheable section: system, tools, and messages.
 func applyEphemeralCache(params *anthropic.MessageNewParams) {
@@ -1,11 +1,14 @@
 package anthropic
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"testing"
 
 	anthropic "github.com/anthropics/anthropic-sdk-go"
 	"github.com/go-kratos/blades"
+	"github.com/go-kratos/blades/tools"
 )
 
 func TestToClaudeParamsAssistantRole(t *testing.T) {
@@ -229,3 +232,186 @@ func TestToClaudeParamsToolRole(t *testing.T) {
 		t.Fatalf("tool_result text block malformed: %v", resultContent[0])
 	}
 }
+
+func TestToClaudeParamsSendsThinkingBack(t *testing.T) {
+	t.Parallel()
+
+	model := &Claude{model: "claude-test"}
+	params, err := model.toClaudeParams(&blades.ModelRequest{
+		Messages: []*blades.Message{
+			{
+				Role: blades.RoleTool,
+				Parts: []blades.Part{
+					blades.ReasoningPart{Text: "Need the weather.", Signature: "sig_1", Provider: providerName},
+					blades.ReasoningPart{Text: "From another model.", Signature: "other", Provider: "gemini"},
+					blades.ReasoningPart{Signature: "encrypted", Redacted: true, Provider: providerName},
+					blades.ToolPart{ID: "toolu_1", Name: "get_weather", Request: `{}`, Response: "sunny", Completed: true},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("toClaudeParams returned error: %v", err)
+	}
+	payload, err := json.Marshal(params.Messages[0].Content)
+	if err != nil {
+		t.Fatalf("marshal content: %v", err)
+	}
+	var blocks []map[string]any
+	if err := json.Unmarshal(payload, &blocks); err != nil {
+		t.Fatalf("unmarshal content: %v", err)
+	}
+	if got, want := len(blocks), 3; got != want {
+		t.Fatalf("blocks len = %d, want %d: %s", got, want, payload)
+	}
+	if blocks[0]["type"] != "thinking" || blocks[0]["thinking"] != "Need the weather." || blocks[0]["signature"] != "sig_1" {
+		t.Fatalf("thinking block malformed: %v", blocks[0])
+	}
+	if blocks[1]["type"] != "redacted_thinking" || blocks[1]["data"] != "encrypted" {
+		t.Fatalf("redacted thinking block malformed: %v", blocks[1])
+	}
+	if blocks[2]["type"] != "tool_use" {
+		t.Fatalf("third block type = %v, want tool_use", blocks[2]["type"])
+	}
+}
+
+func TestToClaudeParamsDocuments(t *testing.T) {
+	t.Parallel()
+
+	model := &Claude{model: "claude-test"}
+	params, err := model.toClaudeParams(&blades.ModelRequest{
+		Messages: []*blades.Message{
+			blades.UserMessage(
+				"summarize",
+				blades.DataPart{Name: "report.pdf", Bytes: []byte("%PDF-1.7"), MIMEType: blades.MIMEPDF},
+				blades.DataPart{Name: "rows.csv", Bytes: []byte("a,b\n1,2"), MIMEType: blades.MIMECSV},
+				blades.FilePart{Name: "spec.pdf", URI: "https://example.com/spec.pdf", MIMEType: blades.MIMEPDF},
+				blades.DataPart{Bytes: []byte{1}, MIMEType: blades.MIMEImagePNG},
+			),
+		},
+	})
+	if err != nil {
+		t.Fatalf("toClaudeParams returned error: %v", err)
+	}
+	content := params.Messages[0].Content
+	if got, want := len(content), 5; got != want {
+		t.Fatalf("content len = %d, want %d", got, want)
+	}
+	pdf := content[1].OfDocument
+	if pdf == nil || pdf.Source.OfBase64 == nil || pdf.Title.Value != "report.pdf" {
+		t.Fatalf("pdf block = %+v", content[1])
+	}
+	csv := content[2].OfDocument
+	if csv == nil || csv.Source.OfText == nil || csv.Source.OfText.Data != "a,b\n1,2" {
+		t.Fatalf("csv block = %+v", content[2])
+	}
+	if url := content[3].OfDocument; url == nil || url.Source.OfURL == nil || url.Source.OfURL.URL != "https://example.com/spec.pdf" {
+		t.Fatalf("url block = %+v", content[3])
+	}
+	if content[4].OfImage == nil || content[4].OfImage.Source.OfBase64 == nil {
+		t.Fatalf("image block = %+v", content[4])
+	}
+}
+
+func TestToClaudeParamsRejectsUnsupportedMedia(t *testing.T) {
+	t.Parallel()
+
+	model := &Claude{model: "claude-test"}
+	tests := []struct {
+		part blades.Part
+		want error
+	}{
+		{blades.DataPart{Name: "notes.docx", Bytes: []byte("x"), MIMEType: blades.MIMEDOCX}, blades.ErrUnsupportedMediaType},
+		{blades.FilePart{Name: "rows.csv", URI: "https://example.com/rows.csv", MIMEType: blades.MIMECSV}, blades.ErrUnsupportedMediaType},
+		{blades.DataPart{Name: "big.png", Bytes: make([]byte, maxImageBytes+1), MIMEType: blades.MIMEImagePNG}, blades.ErrMediaTooLarge},
+	}
+	for _, tt := range tests {
+		_, err := model.toClaudeParams(&blades.ModelRequest{
+			Messages: []*blades.Message{blades.UserMessage("read", tt.part)},
+		})
+		if !errors.Is(err, tt.want) {
+			t.Fatalf("%T: err = %v, want %v", tt.part, err, tt.want)
+		}
+	}
+}
+
+func TestToClaudeParamsSkipsArtifactFiles(t *testing.T) {
+	t.Parallel()
+
+	model := &Claude{model: "claude-test"}
+	params, err := model.toClaudeParams(&blades.ModelRequest{
+		Messages: []*blades.Message{blades.UserMessage(
+			"read",
+			blades.FilePart{Name: "report.pdf", URI: blades.ArtifactURI("report.pdf", 1), MIMEType: blades.MIMEPDF},
+			blades.FilePart{Name: "chart.png", URI: blades.ArtifactURI("chart.png", 2), MIMEType: blades.MIMEImagePNG},
+		)},
+	})
+	if err != nil {
+		t.Fatalf("toClaudeParams returned error: %v", err)
+	}
+	if content := params.Messages[0].Content; len(content) != 1 || content[0].OfText == nil {
+		t.Fatalf("content = %+v, want only the text", content)
+	}
+}
+
+func TestToClaudeParamsGenerationConfig(t *testing.T) {
+	t.Parallel()
+
+	model := &Claude{model: "claude-test", config: Config{MaxOutputTokens: 1024, Temperature: 1, TopK: 40}}
+	params, err := model.toClaudeParams(&blades.ModelRequest{
+		Messages:   []*blades.Message{blades.UserMessage("hello")},
+		Generation: &blades.GenerationConfig{Temperature: blades.Ptr(0.0), MaxOutputTokens: blades.Ptr(int64(256))},
+	})
+	if err != nil {
+		t.Fatalf("toClaudeParams returned error: %v", err)
+	}
+	if !params.Temperature.Valid() || params.Temperature.Value != 0 {
+		t.Fatalf("temperature = %+v, want 0", params.Temperature)
+	}
+	if params.MaxTokens != 256 || params.TopK.Value != 40 {
+		t.Fatalf("max tokens = %d, top k = %d, want 256 and 40", params.MaxTokens, params.TopK.Value)
+	}
+
+	_, err = model.toClaudeParams(&blades.ModelRequest{
+		Messages:   []*blades.Message{blades.UserMessage("hello")},
+		Generation: &blades.GenerationConfig{Seed: blades.Ptr(int64(7)), PresencePenalty: blades.Ptr(0.5)},
+	})
+	var unsupported *blades.UnsupportedGenerationError
+	if !errors.As(err, &unsupported) || len(unsupported.Fields) != 2 {
+		t.Fatalf("err = %v, want unsupported seed and presence penalty", err)
+	}
+}
+
+func TestToClaudeParamsToolChoice(t *testing.T) {
+	t.Parallel()
+
+	weather := tools.NewTool("get_weather", "gets the weather", tools.HandleFunc(func(context.Context, string) (string, error) {
+		return "sunny", nil
+	}))
+	model := &Claude{model: "claude-test"}
+	tests := []struct {
+		choice blades.ToolChoice
+		want   string
+	}{
+		{blades.ToolChoice{Mode: blades.ToolChoiceNone}, `{"type":"none"}`},
+		{blades.ToolChoice{Mode: blades.ToolChoiceRequired}, `{"type":"any"}`},
+		{blades.ToolChoiceNamed("get_weather"), `{"name":"get_weather","type":"tool"}`},
+	}
+	for _, tt := range tests {
+		params, err := model.toClaudeParams(&blades.ModelRequest{
+			Messages:   []*blades.Message{blades.UserMessage("weather?")},
+			Tools:      []tools.Tool{weather},
+			ToolChoice: &tt.choice,
+		})
+		if err != nil {
+			t.Fatalf("toClaudeParams returned error: %v", err)
+		}
+		got, err := json.Marshal(params.ToolChoice)
+		if err != nil {
+			t.Fatalf("marshal tool choice: %v", err)
+		}
+		if string(got) != tt.want {
+			t.Fatalf("tool_choice = %s, want %s", got, tt.want)
+		}
+	}
+}
@@ -1,24 +1,124 @@
 package anthropic
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/go-kratos/blades"
 	"github.com/go-kratos/blades/tools"
 )
 
+// providerName identifies reasoning produced by Claude, see blades.ReasoningPart.
+const providerName = "anthropic"
+
+const (
+	// maxImageBytes is the largest image Claude accepts.
+	maxImageBytes = 5 << 20
+	// maxDocumentBytes is the largest request Claude accepts, and so the
+	// largest document.
+	maxDocumentBytes = 32 << 20
+)
+
 // convertPartsToContent converts Blades Parts to Claude ContentBlockParamUnion.
-func convertPartsToContent(parts []blades.Part) []anthropic.ContentBlockParamUnion {
+func convertPartsToContent(parts []blades.Part) ([]anthropic.ContentBlockParamUnion, error) {
 	var content []anthropic.ContentBlockParamUnion
 	for _, part := range parts {
 		switch p := part.(type) {
 		case blades.TextPart:
 			content = append(content, anthropic.NewTextBlock(p.Text))
+		case blades.ReasoningPart:
+			if block, ok := convertReasoningToClaude(p); ok {
+				content = append(content, block)
+			}
+		case blades.DataPart:
+			block, err := convertDataToClaude(p)
+			if err != nil {
+				return nil, err
+			}
+			content = append(content, block)
+		case blades.FilePart:
+			if _, _, ok := blades.ParseArtifactURI(p.URI); ok {
+				// The agent inlines artifacts it can load; Claude cannot
+				// fetch the others.
+				continue
+			}
+			block, err := convertFileToClaude(p)
+			if err != nil {
+				return nil, err
+			}
+			content = append(content, block)
 		}
 	}
-	return content
+	return content, nil
+}
+
+// convertDataToClaude converts inline data into an image or a document
+// block. PDFs are sent base64 encoded and text documents, such as CSV, as
+// plain text.
+func convertDataToClaude(part blades.DataPart) (anthropic.ContentBlockParamUnion, error) {
+	limit := maxDocumentBytes
+	if part.MIMEType.Type() == "image" {
+		limit = maxImageBytes
+	}
+	if len(part.Bytes) > limit {
+		return anthropic.ContentBlockParamUnion{}, &blades.MediaTooLargeError{
+			Provider: providerName,
+			Name:     part.Name,
+			MIMEType: part.MIMEType,
+			Size:     len(part.Bytes),
+			Limit:    limit,
+		}
+	}
+	data := base64.StdEncoding.EncodeToString(part.Bytes)
+	switch {
+	case part.MIMEType == blades.MIMEImagePNG || part.MIMEType == blades.MIMEImageJPEG ||
+		part.MIMEType == blades.MIMEImageWEBP || part.MIMEType == blades.MIMEImageGIF:
+		return anthropic.NewImageBlockBase64(string(part.MIMEType), data), nil
+	case part.MIMEType == blades.MIMEPDF:
+		return documentBlock(anthropic.NewDocumentBlock(anthropic.Base64PDFSourceParam{Data: data}), part.Name), nil
+	case part.MIMEType.IsText():
+		return documentBlock(anthropic.NewDocumentBlock(anthropic.PlainTextSourceParam{Data: string(part.Bytes)}), part.Name), nil
+	}
+	return anthropic.ContentBlockParamUnion{}, &blades.UnsupportedMediaError{Provider: providerName, Name: part.Name, MIMEType: part.MIMEType}
+}
+
+// convertFileToClaude converts a file referenced by URL into an image or a
+// document block. Claude fetches only images and PDFs from http(s) URLs.
+func convertFileToClaude(part blades.FilePart) (anthropic.ContentBlockParamUnion, error) {
+	if strings.HasPrefix(part.URI, "https://") || strings.HasPrefix(part.URI, "http://") {
+		switch {
+		case part.MIMEType.Type() == "image":
+			return anthropic.NewImageBlock(anthropic.URLImageSourceParam{URL: part.URI}), nil
+		case part.MIMEType == blades.MIMEPDF:
+			return documentBlock(anthropic.NewDocumentBlock(anthropic.URLPDFSourceParam{URL: part.URI}), part.Name), nil
+		}
+	}
+	return anthropic.ContentBlockParamUnion{}, &blades.UnsupportedMediaError{Provider: providerName, Name: part.Name, MIMEType: part.MIMEType}
+}
+
+// documentBlock titles the document block with the name of the part.
+func documentBlock(block anthropic.ContentBlockParamUnion, name string) anthropic.ContentBlockParamUnion {
+	if name != "" {
+		block.OfDocument.Title = anthropic.String(name)
+	}
+	return block
+}
+
+// convertReasoningToClaude converts a ReasoningPart back into the thinking
+// block Claude produced. Reasoning without a signature, or produced by
+// another provider, cannot be verified by Claude and is dropped.
+func convertReasoningToClaude(part blades.ReasoningPart) (anthropic.ContentBlockParamUnion, bool) {
+	if part.Provider != providerName || part.Signature == "" {
+		return anthropic.ContentBlockParamUnion{}, false
+	}
+	if part.Redacted {
+		return anthropic.NewRedactedThinkingBlock(part.Signature), true
+	}
+	return anthropic.NewThinkingBlock(part.Signature, part.Text), true
 }
 
 // convertBladesToolsToClaude converts Blades Tools to Claude ToolParams.
@@ -54,7 +154,11 @@ func convertClaudeToBlades(message *anthropic.Message, status blades.Status) (*b
 	for _, block := range message.Content {
 		switch b := block.AsAny().(type) {
 		case anthropic.TextBlock:
-			msg.Parts = append(msg.Parts, blades.TextPart{Text: b.Text})
+			msg.Parts = append(msg.Parts, blades.TextPart{Text: b.Text, Annotations: convertCitationsToBlades(b)})
+		case anthropic.ThinkingBlock:
+			msg.Parts = append(msg.Parts, blades.ReasoningPart{Text: b.Thinking, Signature: b.Signature, Provider: providerName})
+		case anthropic.RedactedThinkingBlock:
+			msg.Parts = append(msg.Parts, blades.ReasoningPart{Signature: b.Data, Redacted: true, Provider: providerName})
 		case anthropic.ToolUseBlock:
 			hasToolUse = true
 			input, err := json.Marshal(b.Input)
@@ -67,17 +171,64 @@ func convertClaudeToBlades(message *anthropic.Message, status blades.Status) (*b
 	if hasToolUse {
 		msg.Role = blades.RoleTool
 	}
+	msg.TokenUsage = convertUsageToBlades(message.Usage)
 	return &blades.ModelResponse{
 		Message: msg,
 	}, nil
 }
 
+// convertCitationsToBlades converts the citations of a text block into
+// annotations. Claude cites whole text blocks, so every annotation spans the
+// block text.
+func convertCitationsToBlades(block anthropic.TextBlock) []blades.Annotation {
+	if len(block.Citations) == 0 {
+		return nil
+	}
+	annotations := make([]blades.Annotation, 0, len(block.Citations))
+	for _, citation := range block.Citations {
+		annotation := blades.Annotation{
+			End:       len(block.Text),
+			Title:     citation.DocumentTitle,
+			CitedText: citation.CitedText,
+		}
+		switch citation.Type {
+		case "web_search_result_location":
+			annotation.SourceID = citation.URL
+			annotation.URL = citation.URL
+			annotation.Title = citation.Title
+		case "search_result_location":
+			annotation.SourceID = citation.Source
+			annotation.Title = citation.Title
+		default:
+			annotation.SourceID = strconv.FormatInt(citation.DocumentIndex, 10)
+		}
+		annotations = append(annotations, annotation)
+	}
+	return annotations
+}
+
+// convertUsageToBlades converts Claude usage, where input tokens exclude
+// cache reads and writes, into a Blades TokenUsage that includes them.
+func convertUsageToBlades(usage anthropic.Usage) blades.TokenUsage {
+	input := usage.InputTokens + usage.CacheReadInputTokens + usage.CacheCreationInputTokens
+	return blades.TokenUsage{
+		InputTokens:       input,
+		OutputTokens:      usage.OutputTokens,
+		TotalTokens:       input + usage.OutputTokens,
+		CachedInputTokens: usage.CacheReadInputTokens,
+	}
+}
+
 // convertStreamDeltaToBlades converts a Claude ContentBlockDeltaEvent to Blades ModelResponse.
 func convertStreamDeltaToBlades(event anthropic.ContentBlockDeltaEvent) (*blades.ModelResponse, error) {
 	message := blades.NewAssistantMessage(blades.StatusIncomplete)
 	switch delta := event.Delta.AsAny().(type) {
 	case anthropic.TextDelta:
 		message.Parts = append(message.Parts, blades.TextPart{Text: delta.Text})
+	case anthropic.ThinkingDelta:
+		message.Parts = append(message.Parts, blades.ReasoningPart{Text: delta.Thinking, Provider: providerName})
+	case anthropic.SignatureDelta:
+		message.Parts = append(message.Parts, blades.ReasoningPart{Signature: delta.Signature, Provider: providerName})
 	}
 	return &blades.ModelResponse{
 		Message: message,
@@ -129,3 +129,105 @@ func decodeAnthropicMessage(t *testing.T, data string) *anthropicSDK.Message {
 	}
 	return &message
 }
+
+func TestConvertClaudeToBladesThinking(t *testing.T) {
+	t.Parallel()
+
+	message := decodeAnthropicMessage(t, `{
+		"id": "msg_3",
+		"content": [
+			{"type":"thinking","thinking":"The user wants the weather.","signature":"sig_1"},
+			{"type":"redacted_thinking","data":"encrypted"},
+			{"type":"text","text":"Sunny."}
+		],
+		"model": "claude-sonnet-4-20250514",
+		"role": "assistant",
+		"stop_reason": "end_turn",
+		"type": "message",
+		"usage": {"input_tokens": 1, "output_tokens": 1}
+	}`)
+
+	response, err := convertClaudeToBlades(message, blades.StatusCompleted)
+	if err != nil {
+		t.Fatalf("convertClaudeToBlades returned error: %v", err)
+	}
+	parts := response.Message.Parts
+	if got, want := len(parts), 3; got != want {
+		t.Fatalf("parts len = %d, want %d", got, want)
+	}
+	want := blades.ReasoningPart{Text: "The user wants the weather.", Signature: "sig_1", Provider: providerName}
+	if got, ok := parts[0].(blades.ReasoningPart); !ok || got != want {
+		t.Fatalf("first part = %#v, want %#v", parts[0], want)
+	}
+	redacted := blades.ReasoningPart{Signature: "encrypted", Redacted: true, Provider: providerName}
+	if got, ok := parts[1].(blades.ReasoningPart); !ok || got != redacted {
+		t.Fatalf("second part = %#v, want %#v", parts[1], redacted)
+	}
+	if got, want := response.Message.Text(), "Sunny."; got != want {
+		t.Fatalf("text = %q, want %q", got, want)
+	}
+}
+
+func TestConvertStreamDeltaToBladesThinking(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		delta string
+		want  blades.ReasoningPart
+	}{
+		{`{"type":"thinking_delta","thinking":"Hmm"}`, blades.ReasoningPart{Text: "Hmm", Provider: providerName}},
+		{`{"type":"signature_delta","signature":"sig"}`, blades.ReasoningPart{Signature: "sig", Provider: providerName}},
+	} {
+		var event anthropicSDK.ContentBlockDeltaEvent
+		if err := json.Unmarshal([]byte(`{"type":"content_block_delta","index":0,"delta":`+tc.delta+`}`), &event); err != nil {
+			t.Fatalf("unmarshal event: %v", err)
+		}
+		response, err := convertStreamDeltaToBlades(event)
+		if err != nil {
+			t.Fatalf("convertStreamDeltaToBlades returned error: %v", err)
+		}
+		if got, ok := response.Message.Parts[0].(blades.ReasoningPart); !ok || got != tc.want {
+			t.Fatalf("part = %#v, want %#v", response.Message.Parts[0], tc.want)
+		}
+	}
+}
+
+func TestConvertClaudeToBladesCitations(t *testing.T) {
+	t.Parallel()
+
+	message := decodeAnthropicMessage(t, `{
+		"id": "msg_4",
+		"content": [
+			{"type":"text","text":"The grass is green.","citations":[
+				{"type":"char_location","cited_text":"The grass is green.","document_index":0,"document_title":"Facts","start_char_index":0,"end_char_index":20},
+				{"type":"web_search_result_location","cited_text":"Grass is green","url":"https://example.com/grass","title":"Grass","encrypted_index":"x"}
+			]}
+		],
+		"model": "claude-sonnet-4-20250514",
+		"role": "assistant",
+		"stop_reason": "end_turn",
+		"type": "message",
+		"usage": {"input_tokens": 1, "output_tokens": 1}
+	}`)
+
+	response, err := convertClaudeToBlades(message, blades.StatusCompleted)
+	if err != nil {
+		t.Fatalf("convertClaudeToBlades returned error: %v", err)
+	}
+	text, ok := response.Message.Parts[0].(blades.TextPart)
+	if !ok {
+		t.Fatalf("part type = %T, want blades.TextPart", response.Message.Parts[0])
+	}
+	want := []blades.Annotation{
+		{Start: 0, End: 19, SourceID: "0", Title: "Facts", CitedText: "The grass is green."},
+		{Start: 0, End: 19, SourceID: "https://example.com/grass", URL: "https://example.com/grass", Title: "Grass", CitedText: "Grass is green"},
+	}
+	if len(text.Annotations) != len(want) {
+		t.Fatalf("annotations = %#v, want %#v", text.Annotations, want)
+	}
+	for i := range want {
+		if text.Annotations[i] != want[i] {
+			t.Fatalf("annotation %d = %#v, want %#v", i, text.Annotations[i], want[i])
+		}
+	}
+}
@@ -93,16 +93,76 @@ func (m *Gemini) toGenerateConfig(req *blades.ModelRequest) (*genai.GenerateCont
 	if m.config.ThinkingConfig != nil {
 		config.ThinkingConfig = m.config.ThinkingConfig
 	}
+	applyGeneration(&config, req.Generation)
 	if len(req.Tools) > 0 {
 		tools, err := convertBladesToolsToGenAI(req.Tools)
 		if err != nil {
 			return nil, fmt.Errorf("converting tools: %w", err)
 		}
 		config.Tools = tools
+		if req.ToolChoice != nil {
+			toolConfig, err := convertToolChoiceToGenAI(req.ToolChoice)
+			if err != nil {
+				return nil, err
+			}
+			config.ToolConfig = toolConfig
+		}
 	}
 	return &config, nil
 }
 
+// convertToolChoiceToGenAI converts a tool choice into a function calling
+// config. A named tool is required by allowing only that function.
+func convertToolChoiceToGenAI(choice *blades.ToolChoice) (*genai.ToolConfig, error) {
+	config := &genai.FunctionCallingConfig{}
+	switch choice.Mode {
+	case blades.ToolChoiceAuto:
+		config.Mode = genai.FunctionCallingConfigModeAuto
+	case blades.ToolChoiceNone:
+		config.Mode = genai.FunctionCallingConfigModeNone
+	case blades.ToolChoiceRequired:
+		config.Mode = genai.FunctionCallingConfigModeAny
+	case blades.ToolChoiceTool:
+		config.Mode = genai.FunctionCallingConfigModeAny
+		config.AllowedFunctionNames = []string{choice.Name}
+	default:
+		return nil, fmt.Errorf("gemini: unsupported tool choice mode %q", choice.Mode)
+	}
+	return &genai.ToolConfig{FunctionCallingConfig: config}, nil
+}
+
+// applyGeneration merges the generation settings of a request over the
+// static configuration. Gemini supports every setting.
+func applyGeneration(config *genai.GenerateContentConfig, generation *blades.GenerationConfig) {
+	if generation == nil {
+		return
+	}
+	if generation.Temperature != nil {
+		config.Temperature = genai.Ptr(float32(*generation.Temperature))
+	}
+	if generation.TopP != nil {
+		config.TopP = genai.Ptr(float32(*generation.TopP))
+	}
+	if generation.TopK != nil {
+		config.TopK = genai.Ptr(float32(*generation.TopK))
+	}
+	if generation.MaxOutputTokens != nil {
+		config.MaxOutputTokens = int32(*generation.MaxOutputTokens)
+	}
+	if len(generation.StopSequences) > 0 {
+		config.StopSequences = generation.StopSequences
+	}
+	if generation.Seed != nil {
+		config.Seed = genai.Ptr(int32(*generation.Seed))
+	}
+	if generation.FrequencyPenalty != nil {
+		config.FrequencyPenalty = genai.Ptr(float32(*generation.FrequencyPenalty))
+	}
+	if generation.PresencePenalty != nil {
+		config.PresencePenalty = genai.Ptr(float32(*generation.PresencePenalty))
+	}
+}
+
 // NewStreaming is an alias for GenerateStream to implement the ModelProvider interface.
 func (m *Gemini) NewStreaming(ctx context.Context, req *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
 	return func(yield func(*blades.ModelResponse, error) bool) {
@@ -144,13 +204,22 @@ func (m *Gemini) NewStreaming(ctx context.Context, req *blades.ModelRequest) bla
 						if candidate.Content == nil {
 							candidate.Content = &genai.Content{Parts: []*genai.Part{}}
 						}
-						candidate.Content.Parts = append(candidate.Content.Parts, chunkCandidate.Content.Parts...)
+						candidate.Content.Parts = appendStreamedParts(candidate.Content.Parts, chunkCandidate.Content.Parts)
+					}
+					// Grounding metadata comes with the last chunks and
+					// refers to the whole text.
+					if chunkCandidate.GroundingMetadata != nil {
+						candidate.GroundingMetadata = chunkCandidate.GroundingMetadata
 					}
 					// Update finish reason if present
 					if chunkCandidate.FinishReason != "" {
 						candidate.FinishReason = chunkCandidate.FinishReason
 					}
 				}
+				// Usage is cumulative; the latest chunk carries the totals.
+				if chunk.UsageMetadata != nil {
+					accumulatedResponse.UsageMetadata = chunk.UsageMetadata
+				}
 			}
 		}
 		// After streaming is complete, check for tool calls in accumulated response
@@ -164,3 +233,28 @@ func (m *Gemini) NewStreaming(ctx context.Context, req *blades.ModelRequest) bla
 		}
 	}
 }
+
+// appendStreamedParts appends streamed parts to parts, joining consecutive
+// text so the accumulated response holds the text as a single part, which
+// grounding offsets refer to.
+func appendStreamedParts(parts, streamed []*genai.Part) []*genai.Part {
+	for _, part := range streamed {
+		if n := len(parts); n > 0 && isTextPart(parts[n-1]) && isTextPart(part) && parts[n-1].Thought == part.Thought {
+			merged := *parts[n-1]
+			merged.Text += part.Text
+			if len(part.ThoughtSignature) > 0 {
+				merged.ThoughtSignature = part.ThoughtSignature
+			}
+			parts[n-1] = &merged
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// isTextPart reports whether the part holds only text.
+func isTextPart(part *genai.Part) bool {
+	return part.Text != "" && part.FunctionCall == nil && part.FunctionResponse == nil &&
+		part.InlineData == nil && part.FileData == nil && part.ExecutableCode == nil && part.CodeExecutionResult == nil
+}
@@ -1,31 +1,57 @@
 package gemini
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"github.com/go-kratos/blades"
 	"github.com/go-kratos/blades/tools"
 	"google.golang.org/genai"
 )
 
+// providerName identifies reasoning produced by Gemini, see blades.ReasoningPart.
+const providerName = "gemini"
+
+// maxInlineBytes is the largest request, and so inline data, Gemini accepts.
+const maxInlineBytes = 20 << 20
+
 func convertMessageToGenAI(req *blades.ModelRequest) (*genai.Content, []*genai.Content, error) {
 	var (
 		system   *genai.Content
 		contents []*genai.Content
 	)
 	if req.Instruction != nil {
-		system = &genai.Content{Parts: convertMessagePartsToGenAI(req.Instruction.Parts)}
+		parts, err := convertMessagePartsToGenAI(req.Instruction.Parts)
+		if err != nil {
+			return nil, nil, err
+		}
+		system = &genai.Content{Parts: parts}
 	}
 	for _, msg := range req.Messages {
 		switch msg.Role {
 		case blades.RoleSystem:
-			system = &genai.Content{Parts: convertMessagePartsToGenAI(msg.Parts)}
+			parts, err := convertMessagePartsToGenAI(msg.Parts)
+			if err != nil {
+				return nil, nil, err
+			}
+			system = &genai.Content{Parts: parts}
 		case blades.RoleUser:
-			contents = append(contents, &genai.Content{Role: genai.RoleUser, Parts: convertMessagePartsToGenAI(msg.Parts)})
+			parts, err := convertMessagePartsToGenAI(msg.Parts)
+			if err != nil {
+				return nil, nil, err
+			}
+			contents = append(contents, &genai.Content{Role: genai.RoleUser, Parts: parts})
 		case blades.RoleAssistant:
-			contents = append(contents, &genai.Content{Role: genai.RoleModel, Parts: convertMessagePartsToGenAI(msg.Parts)})
+			parts, err := convertMessagePartsToGenAI(msg.Parts)
+			if err != nil {
+				return nil, nil, err
+			}
+			contents = append(contents, &genai.Content{Role: genai.RoleModel, Parts: parts})
 		case blades.RoleTool:
+			// The model turn with the function calls is replayed before the
+			// responses, so that its thought signatures are sent back.
 			var parts []*genai.Part
 			for _, part := range msg.Parts {
 				switch v := any(part).(type) {
@@ -37,37 +63,107 @@ func convertMessageToGenAI(req *blades.ModelRequest) (*genai.Content, []*genai.C
 					parts = append(parts, genai.NewPartFromFunctionResponse(v.Name, response))
 				}
 			}
+			calls, err := convertMessagePartsToGenAI(msg.Parts)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(calls) > 0 {
+				contents = append(contents, &genai.Content{Role: genai.RoleModel, Parts: calls})
+			}
 			contents = append(contents, &genai.Content{Role: genai.RoleUser, Parts: parts})
 		}
 	}
 	return system, contents, nil
 }
 
-func convertMessagePartsToGenAI(parts []blades.Part) []*genai.Part {
-	res := make([]*genai.Part, 0, len(parts))
+// convertMessagePartsToGenAI converts Blades Parts to GenAI Parts. Gemini
+// reasoning is sent back only for its thought signatures: a ReasoningPart
+// with text becomes a signed thought part, and one without text carries the
+// signature of the part that follows it.
+func convertMessagePartsToGenAI(parts []blades.Part) ([]*genai.Part, error) {
+	var (
+		res       = make([]*genai.Part, 0, len(parts))
+		signature []byte
+	)
 	for _, part := range parts {
+		n := len(res)
 		switch v := part.(type) {
+		case blades.ReasoningPart:
+			if v.Provider != providerName || v.Signature == "" {
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(v.Signature)
+			if err != nil {
+				continue
+			}
+			if v.Text == "" {
+				signature = decoded
+				continue
+			}
+			res = append(res, &genai.Part{Text: v.Text, Thought: true, ThoughtSignature: decoded})
+		case blades.ToolPart:
+			args := map[string]any{}
+			if err := json.Unmarshal([]byte(v.Request), &args); err != nil {
+				args = nil
+			}
+			call := genai.NewPartFromFunctionCall(v.Name, args)
+			call.FunctionCall.ID = v.ID
+			res = append(res, call)
 		case blades.TextPart:
 			res = append(res, &genai.Part{Text: v.Text})
 		case blades.DataPart:
+			if !supportedMIMEType(v.MIMEType) {
+				return nil, &blades.UnsupportedMediaError{Provider: providerName, Name: v.Name, MIMEType: v.MIMEType}
+			}
+			if len(v.Bytes) > maxInlineBytes {
+				return nil, &blades.MediaTooLargeError{
+					Provider: providerName,
+					Name:     v.Name,
+					MIMEType: v.MIMEType,
+					Size:     len(v.Bytes),
+					Limit:    maxInlineBytes,
+				}
+			}
+			// Display names are rejected by the Gemini API, so the name of
+			// the part is not sent.
 			res = append(res, &genai.Part{
 				InlineData: &genai.Blob{
-					Data:        v.Bytes,
-					DisplayName: v.Name,
-					MIMEType:    string(v.MIMEType),
+					Data:     v.Bytes,
+					MIMEType: string(v.MIMEType),
 				},
 			})
 		case blades.FilePart:
+			if _, _, ok := blades.ParseArtifactURI(v.URI); ok {
+				// The agent inlines artifacts it can load; Gemini cannot
+				// fetch the others.
+				continue
+			}
+			if !supportedMIMEType(v.MIMEType) {
+				return nil, &blades.UnsupportedMediaError{Provider: providerName, Name: v.Name, MIMEType: v.MIMEType}
+			}
 			res = append(res, &genai.Part{
 				FileData: &genai.FileData{
-					FileURI:     v.URI,
-					DisplayName: v.Name,
-					MIMEType:    string(v.MIMEType),
+					FileURI:  v.URI,
+					MIMEType: string(v.MIMEType),
 				},
 			})
 		}
+		if len(res) > n && signature != nil {
+			res[n].ThoughtSignature = signature
+			signature = nil
+		}
+	}
+	return res, nil
+}
+
+// supportedMIMEType reports whether Gemini accepts content of the media type:
+// images, audio, video, PDFs and text.
+func supportedMIMEType(m blades.MIMEType) bool {
+	switch m.Type() {
+	case "image", "audio", "video":
+		return true
 	}
-	return res
+	return m == blades.MIMEPDF || m.IsText()
 }
 
 func convertBladesToolsToGenAI(tools []tools.Tool) ([]*genai.Tool, error) {
@@ -104,25 +200,103 @@ func convertGenAIToBlades(resp *genai.GenerateContentResponse, status blades.Sta
 		if candidate.Content == nil {
 			continue
 		}
+		// indexes maps the index of each GenAI part to its index in the
+		// message, which differs once signatures get parts of their own.
+		indexes := make([]int, 0, len(candidate.Content.Parts))
 		for _, part := range candidate.Content.Parts {
 			bladesPart, err := convertGenAIPartToBlades(part)
 			if err != nil {
 				return nil, err
 			}
+			// A signature on a part other than a thought is kept in a
+			// ReasoningPart without text placed before it.
+			if len(part.ThoughtSignature) > 0 && !part.Thought {
+				message.Parts = append(message.Parts, blades.ReasoningPart{
+					Signature: base64.StdEncoding.EncodeToString(part.ThoughtSignature),
+					Provider:  providerName,
+				})
+			}
+			indexes = append(indexes, len(message.Parts))
 			message.Parts = append(message.Parts, bladesPart)
 			if _, ok := bladesPart.(blades.ToolPart); ok {
 				hasToolCall = true
 			}
 		}
+		annotateGrounding(message.Parts, indexes, candidate.GroundingMetadata)
 	}
 	if hasToolCall {
 		message.Role = blades.RoleTool
 	}
+	if usage := resp.UsageMetadata; usage != nil {
+		message.TokenUsage = blades.TokenUsage{
+			InputTokens:       int64(usage.PromptTokenCount),
+			OutputTokens:      int64(usage.CandidatesTokenCount + usage.ThoughtsTokenCount),
+			TotalTokens:       int64(usage.TotalTokenCount),
+			CachedInputTokens: int64(usage.CachedContentTokenCount),
+		}
+	}
 	return &blades.ModelResponse{Message: message}, nil
 }
 
+// annotateGrounding adds the sources supporting segments of the response to
+// the text parts the segments belong to. Segment offsets are in bytes, as are
+// annotation offsets.
+func annotateGrounding(parts []blades.Part, indexes []int, metadata *genai.GroundingMetadata) {
+	if metadata == nil {
+		return
+	}
+	for _, support := range metadata.GroundingSupports {
+		segment := support.Segment
+		if segment == nil || int(segment.PartIndex) >= len(indexes) {
+			continue
+		}
+		i := indexes[segment.PartIndex]
+		text, ok := parts[i].(blades.TextPart)
+		if !ok {
+			continue
+		}
+		start := min(int(segment.StartIndex), len(text.Text))
+		end := min(max(int(segment.EndIndex), start), len(text.Text))
+		for _, chunkIndex := range support.GroundingChunkIndices {
+			annotation := blades.Annotation{
+				Start:     start,
+				End:       end,
+				SourceID:  strconv.Itoa(int(chunkIndex)),
+				CitedText: segment.Text,
+			}
+			if int(chunkIndex) < len(metadata.GroundingChunks) {
+				annotation.URL, annotation.Title = groundingSource(metadata.GroundingChunks[chunkIndex])
+			}
+			text.Annotations = append(text.Annotations, annotation)
+		}
+		parts[i] = text
+	}
+}
+
+// groundingSource returns the URI and title of the source of a grounding chunk.
+func groundingSource(chunk *genai.GroundingChunk) (string, string) {
+	switch {
+	case chunk == nil:
+		return "", ""
+	case chunk.Web != nil:
+		return chunk.Web.URI, chunk.Web.Title
+	case chunk.RetrievedContext != nil:
+		return chunk.RetrievedContext.URI, chunk.RetrievedContext.Title
+	case chunk.Maps != nil:
+		return chunk.Maps.URI, chunk.Maps.Title
+	}
+	return "", ""
+}
+
 // convertGenAIPartToBlades converts a GenAI Part to Blades Part
 func convertGenAIPartToBlades(part *genai.Part) (blades.Part, error) {
+	if part.Thought {
+		reasoning := blades.ReasoningPart{Text: part.Text, Provider: providerName}
+		if len(part.ThoughtSignature) > 0 {
+			reasoning.Signature = base64.StdEncoding.EncodeToString(part.ThoughtSignature)
+		}
+		return reasoning, nil
+	}
 	if part.FunctionCall != nil {
 		request := "{}"
 		if len(part.FunctionCall.Args) > 0 {
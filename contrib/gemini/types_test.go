@@ -1,10 +1,14 @@
 package gemini
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"slices"
 	"testing"
 
 	"github.com/go-kratos/blades"
+	"github.com/go-kratos/blades/tools"
 	"google.golang.org/genai"
 )
 
@@ -137,3 +141,279 @@ func TestConvertGenAIToBlades_MixedTextAndFunctionCallUsesToolRole(t *testing.T)
 		t.Fatalf("tool completed = %t, want %t", got, want)
 	}
 }
+
+func TestConvertGenAIToBlades_ThoughtsMappedToReasoningParts(t *testing.T) {
+	t.Parallel()
+
+	resp := &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{
+			{
+				Content: &genai.Content{
+					Parts: []*genai.Part{
+						{Text: "Planning the lookup.", Thought: true, ThoughtSignature: []byte("sig-1")},
+						{
+							FunctionCall:     &genai.FunctionCall{ID: "call_1", Name: "get_weather", Args: map[string]any{"city": "Paris"}},
+							ThoughtSignature: []byte("sig-2"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	converted, err := convertGenAIToBlades(resp, blades.StatusCompleted)
+	if err != nil {
+		t.Fatalf("convertGenAIToBlades returned error: %v", err)
+	}
+	parts := converted.Message.Parts
+	if got, want := len(parts), 3; got != want {
+		t.Fatalf("parts len = %d, want %d", got, want)
+	}
+	thought := blades.ReasoningPart{Text: "Planning the lookup.", Signature: "c2lnLTE=", Provider: providerName}
+	if got, ok := parts[0].(blades.ReasoningPart); !ok || got != thought {
+		t.Fatalf("first part = %#v, want %#v", parts[0], thought)
+	}
+	signature := blades.ReasoningPart{Signature: "c2lnLTI=", Provider: providerName}
+	if got, ok := parts[1].(blades.ReasoningPart); !ok || got != signature {
+		t.Fatalf("second part = %#v, want %#v", parts[1], signature)
+	}
+	if _, ok := parts[2].(blades.ToolPart); !ok {
+		t.Fatalf("third part type = %T, want blades.ToolPart", parts[2])
+	}
+	if got := converted.Message.Text(); got != "" {
+		t.Fatalf("text = %q, want reasoning excluded", got)
+	}
+}
+
+func TestConvertMessageToGenAI_ToolRoleSendsThoughtSignaturesBack(t *testing.T) {
+	t.Parallel()
+
+	_, contents, err := convertMessageToGenAI(&blades.ModelRequest{
+		Messages: []*blades.Message{
+			blades.UserMessage("weather?"),
+			{
+				Role: blades.RoleTool,
+				Parts: []blades.Part{
+					blades.ReasoningPart{Text: "Planning the lookup.", Signature: "c2lnLTE=", Provider: providerName},
+					blades.ReasoningPart{Text: "Not mine.", Signature: "other", Provider: "anthropic"},
+					blades.ReasoningPart{Signature: "c2lnLTI=", Provider: providerName},
+					blades.ToolPart{ID: "call_1", Name: "get_weather", Request: `{"city":"Paris"}`, Response: `{"temp":21}`, Completed: true},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("convertMessageToGenAI returned error: %v", err)
+	}
+	if got, want := len(contents), 3; got != want {
+		t.Fatalf("contents len = %d, want %d", got, want)
+	}
+	model := contents[1]
+	if got, want := model.Role, genai.RoleModel; got != want {
+		t.Fatalf("second role = %q, want %q", got, want)
+	}
+	if got, want := len(model.Parts), 2; got != want {
+		t.Fatalf("model parts len = %d, want %d", got, want)
+	}
+	if thought := model.Parts[0]; !thought.Thought || string(thought.ThoughtSignature) != "sig-1" {
+		t.Fatalf("thought part = %+v", thought)
+	}
+	call := model.Parts[1]
+	if call.FunctionCall == nil || call.FunctionCall.ID != "call_1" || call.FunctionCall.Args["city"] != "Paris" {
+		t.Fatalf("function call part = %+v", call.FunctionCall)
+	}
+	if got, want := string(call.ThoughtSignature), "sig-2"; got != want {
+		t.Fatalf("function call signature = %q, want %q", got, want)
+	}
+	response := contents[2]
+	if response.Role != genai.RoleUser || len(response.Parts) != 1 || response.Parts[0].FunctionResponse == nil {
+		t.Fatalf("response content = %+v", response)
+	}
+}
+
+func TestConvertGenAIToBlades_GroundingMappedToAnnotations(t *testing.T) {
+	t.Parallel()
+
+	resp := &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			Content: &genai.Content{
+				Role: genai.RoleModel,
+				Parts: []*genai.Part{
+					{Text: "Thinking.", Thought: true},
+					{Text: "Paris is sunny. It is warm.", ThoughtSignature: []byte("sig")},
+				},
+			},
+			GroundingMetadata: &genai.GroundingMetadata{
+				GroundingChunks: []*genai.GroundingChunk{
+					{Web: &genai.GroundingChunkWeb{URI: "https://weather.example", Title: "Weather"}},
+					{RetrievedContext: &genai.GroundingChunkRetrievedContext{URI: "gs://docs/paris.txt", Title: "Paris"}},
+				},
+				GroundingSupports: []*genai.GroundingSupport{{
+					GroundingChunkIndices: []int32{0, 1},
+					Segment:               &genai.Segment{PartIndex: 1, StartIndex: 0, EndIndex: 15, Text: "Paris is sunny."},
+				}},
+			},
+		}},
+	}
+	res, err := convertGenAIToBlades(resp, blades.StatusCompleted)
+	if err != nil {
+		t.Fatalf("convertGenAIToBlades returned error: %v", err)
+	}
+	// The signature gets a part of its own, before the text.
+	if got, want := len(res.Message.Parts), 3; got != want {
+		t.Fatalf("parts len = %d, want %d", got, want)
+	}
+	text, ok := res.Message.Parts[2].(blades.TextPart)
+	if !ok || len(text.Annotations) != 2 {
+		t.Fatalf("text part = %#v, want two annotations", res.Message.Parts[2])
+	}
+	want := []blades.Annotation{
+		{Start: 0, End: 15, SourceID: "0", URL: "https://weather.example", Title: "Weather", CitedText: "Paris is sunny."},
+		{Start: 0, End: 15, SourceID: "1", URL: "gs://docs/paris.txt", Title: "Paris", CitedText: "Paris is sunny."},
+	}
+	for i := range want {
+		if text.Annotations[i] != want[i] {
+			t.Fatalf("annotation %d = %#v, want %#v", i, text.Annotations[i], want[i])
+		}
+	}
+}
+
+func TestAppendStreamedPartsJoinsText(t *testing.T) {
+	t.Parallel()
+
+	first := &genai.Part{Text: "Hel"}
+	parts := appendStreamedParts(nil, []*genai.Part{{Text: "Hmm.", Thought: true}, first})
+	parts = appendStreamedParts(parts, []*genai.Part{{Text: "lo", ThoughtSignature: []byte("sig")}})
+	parts = appendStreamedParts(parts, []*genai.Part{{FunctionCall: &genai.FunctionCall{Name: "f"}}})
+	if got, want := len(parts), 3; got != want {
+		t.Fatalf("parts len = %d, want %d", got, want)
+	}
+	if parts[1].Text != "Hello" || string(parts[1].ThoughtSignature) != "sig" {
+		t.Fatalf("joined part = %+v", parts[1])
+	}
+	if first.Text != "Hel" {
+		t.Fatalf("streamed part was modified: %q", first.Text)
+	}
+}
+
+func TestConvertMessageToGenAI_Documents(t *testing.T) {
+	t.Parallel()
+
+	_, contents, err := convertMessageToGenAI(&blades.ModelRequest{
+		Messages: []*blades.Message{
+			blades.UserMessage(
+				"summarize",
+				blades.DataPart{Name: "report.pdf", Bytes: []byte("%PDF-1.7"), MIMEType: blades.MIMEPDF},
+				blades.DataPart{Name: "rows.csv", Bytes: []byte("a,b"), MIMEType: blades.MIMECSV},
+			),
+		},
+	})
+	if err != nil {
+		t.Fatalf("convertMessageToGenAI returned error: %v", err)
+	}
+	parts := contents[0].Parts
+	if got, want := len(parts), 3; got != want {
+		t.Fatalf("parts len = %d, want %d", got, want)
+	}
+	for i, want := range []string{"application/pdf", "text/csv"} {
+		blob := parts[i+1].InlineData
+		if blob == nil || blob.MIMEType != want || blob.DisplayName != "" {
+			t.Fatalf("part %d inline data = %+v, want %s", i+1, blob, want)
+		}
+	}
+}
+
+func TestConvertMessageToGenAI_RejectsUnsupportedMedia(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		part blades.Part
+		want error
+	}{
+		{blades.DataPart{Name: "sheet.xlsx", Bytes: []byte("x"), MIMEType: blades.MIMEXLSX}, blades.ErrUnsupportedMediaType},
+		{blades.FilePart{Name: "notes.docx", URI: "gs://bucket/notes.docx", MIMEType: blades.MIMEDOCX}, blades.ErrUnsupportedMediaType},
+		{blades.DataPart{Name: "big.pdf", Bytes: make([]byte, maxInlineBytes+1), MIMEType: blades.MIMEPDF}, blades.ErrMediaTooLarge},
+	}
+	for _, tt := range tests {
+		_, _, err := convertMessageToGenAI(&blades.ModelRequest{
+			Messages: []*blades.Message{blades.UserMessage("read", tt.part)},
+		})
+		if !errors.Is(err, tt.want) {
+			t.Fatalf("%T: err = %v, want %v", tt.part, err, tt.want)
+		}
+	}
+}
+
+func TestConvertMessageToGenAI_SkipsArtifactFiles(t *testing.T) {
+	t.Parallel()
+
+	_, contents, err := convertMessageToGenAI(&blades.ModelRequest{
+		Messages: []*blades.Message{blades.UserMessage(
+			"read",
+			blades.FilePart{Name: "report.pdf", URI: blades.ArtifactURI("report.pdf", 1), MIMEType: blades.MIMEPDF},
+			blades.FilePart{Name: "chart.png", URI: blades.ArtifactURI("chart.png", 2), MIMEType: blades.MIMEImagePNG},
+		)},
+	})
+	if err != nil {
+		t.Fatalf("convertMessageToGenAI returned error: %v", err)
+	}
+	if parts := contents[0].Parts; len(parts) != 1 || parts[0].Text != "read" {
+		t.Fatalf("parts = %+v, want only the text", parts)
+	}
+}
+
+func TestToGenerateConfig_GenerationConfig(t *testing.T) {
+	t.Parallel()
+
+	model := &Gemini{model: "gemini-test", config: Config{Temperature: 1, TopK: 20}}
+	config, err := model.toGenerateConfig(&blades.ModelRequest{
+		Generation: &blades.GenerationConfig{
+			Temperature:     blades.Ptr(0.0),
+			MaxOutputTokens: blades.Ptr(int64(64)),
+			Seed:            blades.Ptr(int64(7)),
+		},
+	})
+	if err != nil {
+		t.Fatalf("toGenerateConfig returned error: %v", err)
+	}
+	if config.Temperature == nil || *config.Temperature != 0 {
+		t.Fatalf("temperature = %v, want 0", config.Temperature)
+	}
+	if config.MaxOutputTokens != 64 || config.Seed == nil || *config.Seed != 7 {
+		t.Fatalf("max tokens = %d, seed = %v, want 64 and 7", config.MaxOutputTokens, config.Seed)
+	}
+	if config.TopK == nil || *config.TopK != 20 {
+		t.Fatalf("top k = %v, want the configured 20", config.TopK)
+	}
+}
+
+func TestToGenerateConfig_ToolChoice(t *testing.T) {
+	t.Parallel()
+
+	weather := tools.NewTool("get_weather", "gets the weather", tools.HandleFunc(func(context.Context, string) (string, error) {
+		return "sunny", nil
+	}))
+	model := &Gemini{model: "gemini-test"}
+	tests := []struct {
+		choice  blades.ToolChoice
+		mode    genai.FunctionCallingConfigMode
+		allowed []string
+	}{
+		{blades.ToolChoice{Mode: blades.ToolChoiceNone}, genai.FunctionCallingConfigModeNone, nil},
+		{blades.ToolChoice{Mode: blades.ToolChoiceRequired}, genai.FunctionCallingConfigModeAny, nil},
+		{blades.ToolChoiceNamed("get_weather"), genai.FunctionCallingConfigModeAny, []string{"get_weather"}},
+	}
+	for _, tt := range tests {
+		config, err := model.toGenerateConfig(&blades.ModelRequest{
+			Tools:      []tools.Tool{weather},
+			ToolChoice: &tt.choice,
+		})
+		if err != nil {
+			t.Fatalf("toGenerateConfig returned error: %v", err)
+		}
+		calling := config.ToolConfig.FunctionCallingConfig
+		if calling.Mode != tt.mode || !slices.Equal(calling.AllowedFunctionNames, tt.allowed) {
+			t.Fatalf("function calling config = %+v, want %s %v", calling, tt.mode, tt.allowed)
+		}
+	}
+}
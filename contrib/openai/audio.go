@@ -99,6 +99,10 @@ func (m *audioModel) Generate(ctx context.Context, req *blades.ModelRequest) (*b
 	if m.config.Voice == "" {
 		return nil, ErrAudioVoiceRequired
 	}
+	// Speech generation takes none of the generation settings.
+	if fields := req.Generation.Unsupported(); len(fields) > 0 {
+		return nil, &blades.UnsupportedGenerationError{Provider: providerName, Fields: fields}
+	}
 	params := m.buildAudioParams(req)
 	resp, err := m.client.Audio.Speech.New(ctx, params)
 	if err != nil {
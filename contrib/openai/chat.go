@@ -4,16 +4,37 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
 
 	"github.com/go-kratos/blades"
 	"github.com/go-kratos/blades/tools"
+	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/packages/respjson"
 	"github.com/openai/openai-go/v3/shared"
 )
 
+// providerName identifies reasoning produced through this provider, see
+// blades.ReasoningPart.
+const providerName = "openai"
+
+const (
+	// maxImageBytes is the largest image accepted as input.
+	maxImageBytes = 20 << 20
+	// maxFileBytes is the largest file accepted as input.
+	maxFileBytes = 32 << 20
+)
+
+// reasoningFields are the non-standard fields in which OpenAI-compatible
+// servers return the reasoning of a chat completion, in order of preference.
+var reasoningFields = []string{"reasoning_content", "reasoning"}
+
 type Config struct {
 	BaseURL          string
 	APIKey           string
@@ -34,6 +55,9 @@ type chatModel struct {
 	model  string
 	config Config
 	client openai.Client
+	// jsonObject is set once the server rejects json_schema response
+	// formats; output schemas are then requested in JSON mode.
+	jsonObject atomic.Bool
 }
 
 // NewModel constructs an OpenAI provider. The API key is read from
@@ -67,6 +91,12 @@ func (m *chatModel) Generate(ctx context.Context, req *blades.ModelRequest) (*bl
 		return nil, err
 	}
 	chatResponse, err := m.client.Chat.Completions.New(ctx, params)
+	if err != nil && m.fallBackToJSONObject(params, err) {
+		if params, err = m.toChatCompletionParams(false, req); err != nil {
+			return nil, err
+		}
+		chatResponse, err = m.client.Chat.Completions.New(ctx, params)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -87,11 +117,28 @@ func (m *chatModel) NewStreaming(ctx context.Context, req *blades.ModelRequest)
 			return
 		}
 		streaming := m.client.Chat.Completions.NewStreaming(ctx, params)
+		if err := streaming.Err(); err != nil && m.fallBackToJSONObject(params, err) {
+			if params, err = m.toChatCompletionParams(true, req); err != nil {
+				yield(nil, err)
+				return
+			}
+			streaming = m.client.Chat.Completions.NewStreaming(ctx, params)
+		}
 		defer streaming.Close()
-		acc := openai.ChatCompletionAccumulator{}
+		var (
+			acc         = openai.ChatCompletionAccumulator{}
+			reasoning   strings.Builder
+			annotations = make(map[int64][]openai.ChatCompletionMessageAnnotation)
+		)
 		for streaming.Next() {
 			chunk := streaming.Current()
 			acc.AddChunk(chunk)
+			// The accumulator ignores fields missing from the delta type, so
+			// reasoning and annotations are collected separately.
+			for _, choice := range chunk.Choices {
+				reasoning.WriteString(reasoningContent(choice.Delta.JSON.ExtraFields))
+				annotations[choice.Index] = append(annotations[choice.Index], deltaAnnotations(choice.Delta.JSON.ExtraFields)...)
+			}
 			message, err := chunkChoiceToResponse(ctx, chunk.Choices)
 			if err != nil {
 				yield(nil, err)
@@ -105,11 +152,20 @@ func (m *chatModel) NewStreaming(ctx context.Context, req *blades.ModelRequest)
 			yield(nil, err)
 			return
 		}
+		for i := range acc.Choices {
+			if len(acc.Choices[i].Message.Annotations) == 0 {
+				acc.Choices[i].Message.Annotations = annotations[acc.Choices[i].Index]
+			}
+		}
 		finalResponse, err := choiceToResponse(ctx, params, &acc.ChatCompletion)
 		if err != nil {
 			yield(nil, err)
 			return
 		}
+		if reasoning.Len() > 0 {
+			part := blades.ReasoningPart{Text: reasoning.String(), Provider: providerName}
+			finalResponse.Message.Parts = append([]blades.Part{part}, finalResponse.Message.Parts...)
+		}
 		yield(finalResponse, nil)
 	}
 }
@@ -150,21 +206,15 @@ func (m *chatModel) toChatCompletionParams(isStreaming bool, req *blades.ModelRe
 	if len(m.config.ExtraFields) > 0 {
 		params.SetExtraFields(m.config.ExtraFields)
 	}
-	if req.OutputSchema != nil {
-		schemaParam := openai.ResponseFormatJSONSchemaJSONSchemaParam{
-			Name:   "structured_outputs",
-			Schema: req.OutputSchema,
-			Strict: openai.Bool(true),
-		}
-		if req.OutputSchema.Title != "" {
-			schemaParam.Name = req.OutputSchema.Title
-		}
-		if req.OutputSchema.Description != "" {
-			schemaParam.Description = openai.String(req.OutputSchema.Description)
-		}
-		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
-			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{JSONSchema: schemaParam},
+	if err := applyGeneration(&params, req.Generation); err != nil {
+		return openai.ChatCompletionNewParams{}, err
+	}
+	if req.ToolChoice != nil && len(tools) > 0 {
+		toolChoice, err := toToolChoice(req.ToolChoice)
+		if err != nil {
+			return openai.ChatCompletionNewParams{}, err
 		}
+		params.ToolChoice = toolChoice
 	}
 	if isStreaming {
 		params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
@@ -174,10 +224,19 @@ func (m *chatModel) toChatCompletionParams(isStreaming bool, req *blades.ModelRe
 	if req.Instruction != nil {
 		params.Messages = append(params.Messages, openai.SystemMessage(toTextParts(req.Instruction)))
 	}
+	if req.OutputSchema != nil {
+		if err := m.setResponseFormat(&params, req.OutputSchema); err != nil {
+			return openai.ChatCompletionNewParams{}, err
+		}
+	}
 	for _, msg := range req.Messages {
 		switch msg.Role {
 		case blades.RoleUser:
-			params.Messages = append(params.Messages, openai.UserMessage(toContentParts(msg)))
+			content, err := toContentParts(msg)
+			if err != nil {
+				return openai.ChatCompletionNewParams{}, err
+			}
+			params.Messages = append(params.Messages, openai.UserMessage(content))
 		case blades.RoleAssistant:
 			params.Messages = append(params.Messages, openai.AssistantMessage(msg.Text()))
 		case blades.RoleSystem:
@@ -196,6 +255,68 @@ func (m *chatModel) toChatCompletionParams(isStreaming bool, req *blades.ModelRe
 	return params, nil
 }
 
+// setResponseFormat requests output that conforms to schema. Strict mode is
+// used whenever the schema satisfies its rules. Once the server has rejected
+// json_schema, JSON mode is requested instead and the schema is described in
+// a system message following the instruction.
+func (m *chatModel) setResponseFormat(params *openai.ChatCompletionNewParams, schema *jsonschema.Schema) error {
+	if m.jsonObject.Load() {
+		data, err := json.Marshal(schema)
+		if err != nil {
+			return err
+		}
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONObject: &shared.ResponseFormatJSONObjectParam{},
+		}
+		params.Messages = append(params.Messages, openai.SystemMessage(
+			"Respond with a JSON value that conforms to this JSON schema:\n"+string(data)))
+		return nil
+	}
+	schemaParam := openai.ResponseFormatJSONSchemaJSONSchemaParam{
+		Name:   schemaName(schema),
+		Schema: schema,
+	}
+	if strict, ok := strictSchema(schema); ok {
+		schemaParam.Schema = strict
+		schemaParam.Strict = openai.Bool(true)
+	}
+	if schema.Description != "" {
+		schemaParam.Description = openai.String(schema.Description)
+	}
+	params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{JSONSchema: schemaParam},
+	}
+	return nil
+}
+
+// fallBackToJSONObject reports whether err is the server rejecting the
+// json_schema response format of params, in which case later requests use
+// JSON mode and the caller should retry.
+func (m *chatModel) fallBackToJSONObject(params openai.ChatCompletionNewParams, err error) bool {
+	if params.ResponseFormat.OfJSONSchema == nil || !rejectsJSONSchema(err) {
+		return false
+	}
+	m.jsonObject.Store(true)
+	return true
+}
+
+// rejectsJSONSchema reports whether err is a client error about the
+// response format, as returned by servers without json_schema support.
+func rejectsJSONSchema(err error) bool {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode != http.StatusBadRequest && apiErr.StatusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	if apiErr.Param == "response_format" {
+		return true
+	}
+	detail := strings.ToLower(apiErr.Message + " " + apiErr.RawJSON())
+	return strings.Contains(detail, "json_schema") || strings.Contains(detail, "response_format")
+}
+
 func toToolCallMessage(msg *blades.Message) openai.ChatCompletionMessageParamUnion {
 	toolCalls := make([]openai.ChatCompletionMessageToolCallUnionParam, 0, len(msg.Parts))
 	for _, part := range msg.Parts {
@@ -212,11 +333,86 @@ func toToolCallMessage(msg *blades.Message) openai.ChatCompletionMessageParamUni
 			})
 		}
 	}
-	return openai.ChatCompletionMessageParamUnion{
-		OfAssistant: &openai.ChatCompletionAssistantMessageParam{
-			ToolCalls: toolCalls,
-		},
+	assistant := &openai.ChatCompletionAssistantMessageParam{
+		ToolCalls: toolCalls,
+	}
+	setReasoningContent(assistant, msg)
+	return openai.ChatCompletionMessageParamUnion{OfAssistant: assistant}
+}
+
+// setReasoningContent sends the reasoning behind a tool call turn back in the
+// reasoning_content field, which servers that reason before calling tools
+// expect on later turns. It is never set on final answers, whose reasoning
+// servers such as DeepSeek reject. Reasoning produced by other providers is
+// dropped.
+func setReasoningContent(assistant *openai.ChatCompletionAssistantMessageParam, msg *blades.Message) {
+	var reasoning strings.Builder
+	for _, part := range msg.Parts {
+		if v, ok := part.(blades.ReasoningPart); ok && v.Provider == providerName && !v.Redacted {
+			reasoning.WriteString(v.Text)
+		}
+	}
+	if reasoning.Len() > 0 {
+		assistant.SetExtraFields(map[string]any{reasoningFields[0]: reasoning.String()})
+	}
+}
+
+// deltaAnnotations returns the annotations sent in a streamed delta, if any.
+func deltaAnnotations(fields map[string]respjson.Field) []openai.ChatCompletionMessageAnnotation {
+	field, ok := fields["annotations"]
+	if !ok || field.Raw() == "" {
+		return nil
+	}
+	var annotations []openai.ChatCompletionMessageAnnotation
+	if err := json.Unmarshal([]byte(field.Raw()), &annotations); err != nil {
+		return nil
 	}
+	return annotations
+}
+
+// convertAnnotations converts URL citations, whose spans are character
+// indexes, into annotations spanning byte offsets of text.
+func convertAnnotations(text string, annotations []openai.ChatCompletionMessageAnnotation) []blades.Annotation {
+	if len(annotations) == 0 {
+		return nil
+	}
+	offsets := make([]int, 0, len(text)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(text))
+	offset := func(index int64) int {
+		return offsets[min(max(int(index), 0), len(offsets)-1)]
+	}
+	res := make([]blades.Annotation, 0, len(annotations))
+	for _, annotation := range annotations {
+		citation := annotation.URLCitation
+		res = append(res, blades.Annotation{
+			Start:    offset(citation.StartIndex),
+			End:      offset(citation.EndIndex),
+			SourceID: citation.URL,
+			URL:      citation.URL,
+			Title:    citation.Title,
+		})
+	}
+	return res
+}
+
+// reasoningContent returns the reasoning found in the non-standard fields of
+// a message or delta, if any.
+func reasoningContent(fields map[string]respjson.Field) string {
+	for _, name := range reasoningFields {
+		// Extra fields are never reported as valid; decode the raw value.
+		field, ok := fields[name]
+		if !ok || field.Raw() == "" {
+			continue
+		}
+		var text string
+		if err := json.Unmarshal([]byte(field.Raw()), &text); err == nil && text != "" {
+			return text
+		}
+	}
+	return ""
 }
 
 func toTools(tools []tools.Tool) ([]openai.ChatCompletionToolUnionParam, error) {
@@ -262,14 +458,72 @@ func toTextParts(message *blades.Message) []openai.ChatCompletionContentPartText
 	return parts
 }
 
+// applyGeneration merges the generation settings of a request over the
+// static configuration. Chat completions have no top-k sampling, so setting
+// it is reported as unsupported.
+func applyGeneration(params *openai.ChatCompletionNewParams, generation *blades.GenerationConfig) error {
+	if generation == nil {
+		return nil
+	}
+	if unsupported := generation.Unsupported(
+		blades.GenerationTemperature,
+		blades.GenerationTopP,
+		blades.GenerationMaxOutputTokens,
+		blades.GenerationStopSequences,
+		blades.GenerationSeed,
+		blades.GenerationFrequencyPenalty,
+		blades.GenerationPresencePenalty,
+	); len(unsupported) > 0 {
+		return &blades.UnsupportedGenerationError{Provider: providerName, Fields: unsupported}
+	}
+	if generation.Temperature != nil {
+		params.Temperature = param.NewOpt(*generation.Temperature)
+	}
+	if generation.TopP != nil {
+		params.TopP = param.NewOpt(*generation.TopP)
+	}
+	if generation.MaxOutputTokens != nil {
+		params.MaxCompletionTokens = param.NewOpt(*generation.MaxOutputTokens)
+	}
+	if len(generation.StopSequences) > 0 {
+		params.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: generation.StopSequences}
+	}
+	if generation.Seed != nil {
+		params.Seed = param.NewOpt(*generation.Seed)
+	}
+	if generation.FrequencyPenalty != nil {
+		params.FrequencyPenalty = param.NewOpt(*generation.FrequencyPenalty)
+	}
+	if generation.PresencePenalty != nil {
+		params.PresencePenalty = param.NewOpt(*generation.PresencePenalty)
+	}
+	return nil
+}
+
+// toToolChoice converts a tool choice into the tool_choice parameter.
+func toToolChoice(choice *blades.ToolChoice) (openai.ChatCompletionToolChoiceOptionUnionParam, error) {
+	switch choice.Mode {
+	case blades.ToolChoiceAuto, blades.ToolChoiceNone, blades.ToolChoiceRequired:
+		return openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: param.NewOpt(string(choice.Mode))}, nil
+	case blades.ToolChoiceTool:
+		return openai.ToolChoiceOptionFunctionToolChoice(openai.ChatCompletionNamedToolChoiceFunctionParam{Name: choice.Name}), nil
+	}
+	return openai.ChatCompletionToolChoiceOptionUnionParam{}, fmt.Errorf("openai: unsupported tool choice mode %q", choice.Mode)
+}
+
 // toContentParts converts message parts to OpenAI content parts (multi-modal user input).
-func toContentParts(message *blades.Message) []openai.ChatCompletionContentPartUnionParam {
+func toContentParts(message *blades.Message) ([]openai.ChatCompletionContentPartUnionParam, error) {
 	parts := make([]openai.ChatCompletionContentPartUnionParam, 0, len(message.Parts))
 	for _, part := range message.Parts {
 		switch v := part.(type) {
 		case blades.TextPart:
 			parts = append(parts, openai.TextContentPart(v.Text))
 		case blades.FilePart:
+			if _, _, ok := blades.ParseArtifactURI(v.URI); ok {
+				// The agent inlines artifacts it can load; OpenAI cannot
+				// fetch the others.
+				continue
+			}
 			// Handle different content types based on MIME type
 			switch v.MIMEType.Type() {
 			case "image":
@@ -282,32 +536,54 @@ func toContentParts(message *blades.Message) []openai.ChatCompletionContentPartU
 					Format: v.MIMEType.Format(),
 				}))
 			default:
-				log.Println("failed to process file part with MIME type:", v.MIMEType)
+				// Files can only be sent inline or by file ID, not by URL.
+				return nil, &blades.UnsupportedMediaError{Provider: providerName, Name: v.Name, MIMEType: v.MIMEType}
 			}
 		case blades.DataPart:
+			limit := maxFileBytes
+			if v.MIMEType.Type() == "image" {
+				limit = maxImageBytes
+			}
+			if len(v.Bytes) > limit {
+				return nil, &blades.MediaTooLargeError{
+					Provider: providerName,
+					Name:     v.Name,
+					MIMEType: v.MIMEType,
+					Size:     len(v.Bytes),
+					Limit:    limit,
+				}
+			}
 			// Handle different content types based on MIME type
-			switch v.MIMEType.Type() {
-			case "image":
+			switch {
+			case v.MIMEType.Type() == "image":
 				mimeType := string(v.MIMEType)
 				base64Data := "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(v.Bytes)
 				parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
 					URL: base64Data,
 				}))
-			case "audio":
+			case v.MIMEType.Type() == "audio":
 				parts = append(parts, openai.InputAudioContentPart(openai.ChatCompletionContentPartInputAudioInputAudioParam{
 					Data:   "data:;base64," + base64.StdEncoding.EncodeToString(v.Bytes),
 					Format: v.MIMEType.Format(),
 				}))
-			default:
+			case v.MIMEType == blades.MIMEPDF:
 				fileParam := openai.ChatCompletionContentPartFileFileParam{
-					FileData: param.NewOpt(base64.StdEncoding.EncodeToString(v.Bytes)),
-					Filename: param.NewOpt(v.Name),
+					FileData: param.NewOpt("data:" + string(v.MIMEType) + ";base64," + base64.StdEncoding.EncodeToString(v.Bytes)),
+				}
+				if v.Name != "" {
+					fileParam.Filename = param.NewOpt(v.Name)
 				}
 				parts = append(parts, openai.FileContentPart(fileParam))
+			case v.MIMEType.IsText():
+				// File inputs accept only PDFs, so text documents are sent as
+				// text, headed like in Message.String.
+				parts = append(parts, openai.TextContentPart("[File: "+v.Name+" ("+string(v.MIMEType)+")]\n"+string(v.Bytes)))
+			default:
+				return nil, &blades.UnsupportedMediaError{Provider: providerName, Name: v.Name, MIMEType: v.MIMEType}
 			}
 		}
 	}
-	return parts
+	return parts, nil
 }
 
 func choiceToToolCalls(ctx context.Context, tools []*tools.Tool, choices []openai.ChatCompletionChoice) (*blades.ModelResponse, error) {
@@ -335,13 +611,20 @@ func choiceToToolCalls(ctx context.Context, tools []*tools.Tool, choices []opena
 func choiceToResponse(ctx context.Context, params openai.ChatCompletionNewParams, cc *openai.ChatCompletion) (*blades.ModelResponse, error) {
 	message := blades.NewAssistantMessage(blades.StatusCompleted)
 	message.TokenUsage = blades.TokenUsage{
-		InputTokens:  cc.Usage.PromptTokens,
-		OutputTokens: cc.Usage.CompletionTokens,
-		TotalTokens:  cc.Usage.TotalTokens,
+		InputTokens:       cc.Usage.PromptTokens,
+		OutputTokens:      cc.Usage.CompletionTokens,
+		TotalTokens:       cc.Usage.TotalTokens,
+		CachedInputTokens: cc.Usage.PromptTokensDetails.CachedTokens,
 	}
 	for _, choice := range cc.Choices {
+		if reasoning := reasoningContent(choice.Message.JSON.ExtraFields); reasoning != "" {
+			message.Parts = append(message.Parts, blades.ReasoningPart{Text: reasoning, Provider: providerName})
+		}
 		if choice.Message.Content != "" {
-			message.Parts = append(message.Parts, blades.TextPart{Text: choice.Message.Content})
+			message.Parts = append(message.Parts, blades.TextPart{
+				Text:        choice.Message.Content,
+				Annotations: convertAnnotations(choice.Message.Content, choice.Message.Annotations),
+			})
 		}
 		if choice.Message.Audio.Data != "" {
 			bytes, err := base64.StdEncoding.DecodeString(choice.Message.Audio.Data)
@@ -350,12 +633,13 @@ func choiceToResponse(ctx context.Context, params openai.ChatCompletionNewParams
 			}
 			message.Parts = append(message.Parts, blades.DataPart{Bytes: bytes})
 		}
-		if choice.Message.Refusal != "" {
-			// TODO: map refusal codes to specific error types
-		}
 		if choice.FinishReason != "" {
 			message.FinishReason = choice.FinishReason
 		}
+		if choice.Message.Refusal != "" {
+			message.Parts = append(message.Parts, blades.RefusalPart{Text: choice.Message.Refusal})
+			message.FinishReason = blades.FinishReasonRefusal
+		}
 		for _, call := range choice.Message.ToolCalls {
 			message.Role = blades.RoleTool
 			message.Parts = append(message.Parts, blades.NewToolPart(call.ID, call.Function.Name, call.Function.Arguments))
@@ -368,15 +652,19 @@ func choiceToResponse(ctx context.Context, params openai.ChatCompletionNewParams
 func chunkChoiceToResponse(ctx context.Context, choices []openai.ChatCompletionChunkChoice) (*blades.ModelResponse, error) {
 	message := blades.NewAssistantMessage(blades.StatusIncomplete)
 	for _, choice := range choices {
+		if reasoning := reasoningContent(choice.Delta.JSON.ExtraFields); reasoning != "" {
+			message.Parts = append(message.Parts, blades.ReasoningPart{Text: reasoning, Provider: providerName})
+		}
 		if choice.Delta.Content != "" {
 			message.Parts = append(message.Parts, blades.TextPart{Text: choice.Delta.Content})
 		}
-		if choice.Delta.Refusal != "" {
-			// TODO: map refusal codes to specific error types
-		}
 		if choice.FinishReason != "" {
 			message.FinishReason = choice.FinishReason
 		}
+		if choice.Delta.Refusal != "" {
+			message.Parts = append(message.Parts, blades.RefusalPart{Text: choice.Delta.Refusal})
+			message.FinishReason = blades.FinishReasonRefusal
+		}
 		for _, call := range choice.Delta.ToolCalls {
 			message.Role = blades.RoleTool
 			message.Parts = append(message.Parts, blades.NewToolPart(call.ID, call.Function.Name, call.Function.Arguments))
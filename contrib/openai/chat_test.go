@@ -4,10 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/go-kratos/blades"
+	"github.com/go-kratos/blades/tools"
+	"github.com/google/jsonschema-go/jsonschema"
 	openaisdk "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
 )
 
 func TestToChatCompletionParamsAssistantRole(t *testing.T) {
@@ -102,3 +110,559 @@ func TestChunkChoiceToResponseMarksToolPartsIncomplete(t *testing.T) {
 		t.Fatalf("tool completed = %t, want %t", got, want)
 	}
 }
+
+type cityReport struct {
+	City    string   `json:"city"`
+	Summary string   `json:"summary"`
+	Tags    []string `json:"tags"`
+}
+
+type cityNote struct {
+	City string `json:"city"`
+	Note string `json:"note,omitempty"`
+}
+
+func TestToChatCompletionParamsOutputSchema(t *testing.T) {
+	t.Parallel()
+
+	strict, err := jsonschema.For[cityReport](nil)
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	strict.Title = "city report"
+	optional, err := jsonschema.For[cityNote](nil)
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	tests := []struct {
+		name       string
+		jsonObject bool
+		schema     *jsonschema.Schema
+		wantType   string
+		wantStrict bool
+	}{
+		{name: "strict json schema", schema: strict, wantType: "json_schema", wantStrict: true},
+		{name: "optional properties", schema: optional, wantType: "json_schema"},
+		{name: "json object", jsonObject: true, schema: strict, wantType: "json_object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			model := &chatModel{model: "gpt-test"}
+			model.jsonObject.Store(tt.jsonObject)
+			params, err := model.toChatCompletionParams(false, &blades.ModelRequest{
+				Instruction:  blades.SystemMessage("You are a travel writer."),
+				Messages:     []*blades.Message{blades.UserMessage("report on Paris")},
+				OutputSchema: tt.schema,
+			})
+			if err != nil {
+				t.Fatalf("toChatCompletionParams returned error: %v", err)
+			}
+			payload, err := json.Marshal(params)
+			if err != nil {
+				t.Fatalf("marshal params: %v", err)
+			}
+			var got struct {
+				Messages []struct {
+					Content json.RawMessage `json:"content"`
+				} `json:"messages"`
+				ResponseFormat struct {
+					Type       string `json:"type"`
+					JSONSchema struct {
+						Name   string `json:"name"`
+						Strict bool   `json:"strict"`
+					} `json:"json_schema"`
+				} `json:"response_format"`
+			}
+			if err := json.Unmarshal(payload, &got); err != nil {
+				t.Fatalf("unmarshal params: %v", err)
+			}
+			if got.ResponseFormat.Type != tt.wantType {
+				t.Fatalf("response_format.type = %q, want %q; payload=%s", got.ResponseFormat.Type, tt.wantType, payload)
+			}
+			if got.ResponseFormat.JSONSchema.Strict != tt.wantStrict {
+				t.Fatalf("strict = %t, want %t; payload=%s", got.ResponseFormat.JSONSchema.Strict, tt.wantStrict, payload)
+			}
+			switch tt.wantType {
+			case "json_schema":
+				if name := got.ResponseFormat.JSONSchema.Name; name == "" || strings.Contains(name, " ") {
+					t.Fatalf("schema name = %q", name)
+				}
+			case "json_object":
+				if len(got.Messages) != 3 || !bytes.Contains(got.Messages[0].Content, []byte("travel writer")) || !bytes.Contains(got.Messages[1].Content, []byte("JSON schema")) {
+					t.Fatalf("json_object request does not describe the schema after the instruction; payload=%s", payload)
+				}
+			}
+		})
+	}
+}
+
+func TestOutputSchemaFallsBackToJSONObject(t *testing.T) {
+	t.Parallel()
+
+	var formats []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Stream         bool `json:"stream"`
+			ResponseFormat struct {
+				Type string `json:"type"`
+			} `json:"response_format"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		formats = append(formats, body.ResponseFormat.Type)
+		if body.ResponseFormat.Type == "json_schema" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"response_format.type json_schema is not supported","type":"invalid_request_error"}}`)
+			return
+		}
+		if body.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, `data: {"id":"c2","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","content":"{}"},"finish_reason":"stop"}]}`+"\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"c1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"{}"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	schema, err := jsonschema.For[cityReport](nil)
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	req := &blades.ModelRequest{Messages: []*blades.Message{blades.UserMessage("report on Paris")}, OutputSchema: schema}
+	model := NewModel("gpt-test", Config{BaseURL: server.URL, APIKey: "test", RequestOptions: []option.RequestOption{option.WithMaxRetries(0)}})
+	res, err := model.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if got, want := res.Message.Text(), "{}"; got != want {
+		t.Fatalf("text = %q, want %q", got, want)
+	}
+	for _, err := range model.NewStreaming(context.Background(), req) {
+		if err != nil {
+			t.Fatalf("stream: %v", err)
+		}
+	}
+	if got, want := strings.Join(formats, ","), "json_schema,json_object,json_object"; got != want {
+		t.Fatalf("response formats = %s, want %s", got, want)
+	}
+}
+
+func TestOutputSchemaFallbackStreaming(t *testing.T) {
+	t.Parallel()
+
+	var formats []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ResponseFormat struct {
+				Type string `json:"type"`
+			} `json:"response_format"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		formats = append(formats, body.ResponseFormat.Type)
+		if body.ResponseFormat.Type == "json_schema" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"unsupported value","param":"response_format","type":"invalid_request_error"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","content":"{}"},"finish_reason":"stop"}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	schema, err := jsonschema.For[cityReport](nil)
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	model := NewModel("gpt-test", Config{BaseURL: server.URL, APIKey: "test", RequestOptions: []option.RequestOption{option.WithMaxRetries(0)}})
+	var final *blades.Message
+	for res, err := range model.NewStreaming(context.Background(), &blades.ModelRequest{
+		Messages:     []*blades.Message{blades.UserMessage("report on Paris")},
+		OutputSchema: schema,
+	}) {
+		if err != nil {
+			t.Fatalf("stream: %v", err)
+		}
+		final = res.Message
+	}
+	if final == nil || final.Text() != "{}" {
+		t.Fatalf("final message = %v", final)
+	}
+	if got, want := strings.Join(formats, ","), "json_schema,json_object"; got != want {
+		t.Fatalf("response formats = %s, want %s", got, want)
+	}
+}
+
+func TestOutputSchemaKeepsOtherErrors(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"message":"context length exceeded","type":"invalid_request_error"}}`)
+	}))
+	defer server.Close()
+
+	schema, err := jsonschema.For[cityReport](nil)
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	model := NewModel("gpt-test", Config{BaseURL: server.URL, APIKey: "test", RequestOptions: []option.RequestOption{option.WithMaxRetries(0)}})
+	if _, err := model.Generate(context.Background(), &blades.ModelRequest{
+		Messages:     []*blades.Message{blades.UserMessage("report on Paris")},
+		OutputSchema: schema,
+	}); err == nil {
+		t.Fatal("generate succeeded, want error")
+	}
+	if requests != 1 {
+		t.Fatalf("requests = %d, want 1", requests)
+	}
+}
+
+func TestChoiceToResponseRefusal(t *testing.T) {
+	t.Parallel()
+
+	response, err := choiceToResponse(context.Background(), openaisdk.ChatCompletionNewParams{}, &openaisdk.ChatCompletion{
+		Choices: []openaisdk.ChatCompletionChoice{
+			{
+				FinishReason: "stop",
+				Message:      openaisdk.ChatCompletionMessage{Refusal: "I can't help with that."},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("choiceToResponse returned error: %v", err)
+	}
+	if got, want := response.Message.FinishReason, blades.FinishReasonRefusal; got != want {
+		t.Fatalf("finish reason = %q, want %q", got, want)
+	}
+	refusal, ok := response.Message.Parts[0].(blades.RefusalPart)
+	if !ok || refusal.Text != "I can't help with that." {
+		t.Fatalf("parts = %#v, want refusal part", response.Message.Parts)
+	}
+}
+
+func TestChoiceToResponseReasoningContent(t *testing.T) {
+	t.Parallel()
+
+	var cc openaisdk.ChatCompletion
+	if err := json.Unmarshal([]byte(`{"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"42","reasoning_content":"Think step by step."}}]}`), &cc); err != nil {
+		t.Fatalf("unmarshal completion: %v", err)
+	}
+	response, err := choiceToResponse(context.Background(), openaisdk.ChatCompletionNewParams{}, &cc)
+	if err != nil {
+		t.Fatalf("choiceToResponse returned error: %v", err)
+	}
+	want := blades.ReasoningPart{Text: "Think step by step.", Provider: providerName}
+	if got, ok := response.Message.Parts[0].(blades.ReasoningPart); !ok || got != want {
+		t.Fatalf("first part = %#v, want %#v", response.Message.Parts[0], want)
+	}
+	if got, want := response.Message.Text(), "42"; got != want {
+		t.Fatalf("text = %q, want %q", got, want)
+	}
+}
+
+func TestToChatCompletionParamsSendsReasoningBack(t *testing.T) {
+	t.Parallel()
+
+	model := &chatModel{model: "gpt-test"}
+	toolMessage := &blades.Message{
+		Role: blades.RoleTool,
+		Parts: []blades.Part{
+			blades.ReasoningPart{Text: "Need the weather.", Provider: providerName},
+			blades.ReasoningPart{Text: "Not mine.", Signature: "sig", Provider: "anthropic"},
+			blades.ToolPart{ID: "call_1", Name: "get_weather", Request: `{}`, Response: "sunny", Completed: true},
+		},
+	}
+	params, err := model.toChatCompletionParams(false, &blades.ModelRequest{
+		Messages: []*blades.Message{
+			blades.UserMessage("weather?"),
+			toolMessage,
+			blades.AssistantMessage(blades.ReasoningPart{Text: "Sunny it is.", Provider: providerName}, "Sunny."),
+		},
+	})
+	if err != nil {
+		t.Fatalf("toChatCompletionParams returned error: %v", err)
+	}
+	payload, err := json.Marshal(params.Messages)
+	if err != nil {
+		t.Fatalf("marshal messages: %v", err)
+	}
+	var messages []map[string]any
+	if err := json.Unmarshal(payload, &messages); err != nil {
+		t.Fatalf("unmarshal messages: %v", err)
+	}
+	if got, want := len(messages), 4; got != want {
+		t.Fatalf("messages len = %d, want %d: %s", got, want, payload)
+	}
+	if got, want := messages[1]["reasoning_content"], "Need the weather."; got != want {
+		t.Fatalf("tool call reasoning = %v, want %v", got, want)
+	}
+	if got, ok := messages[3]["reasoning_content"]; ok {
+		t.Fatalf("assistant answer sends reasoning %v", got)
+	}
+	if got, want := messages[3]["content"], "Sunny."; got != want {
+		t.Fatalf("assistant content = %v, want %v", got, want)
+	}
+}
+
+func TestNewStreamingReasoningContent(t *testing.T) {
+	t.Parallel()
+
+	chunks := []string{
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"Think "}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"reasoning_content":"hard."}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"42"},"finish_reason":"stop"}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	model := NewModel("gpt-test", Config{BaseURL: server.URL, APIKey: "test"})
+	var (
+		deltas []string
+		final  *blades.Message
+	)
+	for response, err := range model.NewStreaming(context.Background(), &blades.ModelRequest{Messages: []*blades.Message{blades.UserMessage("q")}}) {
+		if err != nil {
+			t.Fatalf("stream error: %v", err)
+		}
+		if response.Message.Status == blades.StatusCompleted {
+			final = response.Message
+			continue
+		}
+		for _, part := range response.Message.Parts {
+			if reasoning, ok := part.(blades.ReasoningPart); ok {
+				deltas = append(deltas, reasoning.Text)
+			}
+		}
+	}
+	if got, want := strings.Join(deltas, "|"), "Think |hard."; got != want {
+		t.Fatalf("reasoning deltas = %q, want %q", got, want)
+	}
+	if final == nil {
+		t.Fatal("no final response")
+	}
+	want := blades.ReasoningPart{Text: "Think hard.", Provider: providerName}
+	if got, ok := final.Parts[0].(blades.ReasoningPart); !ok || got != want {
+		t.Fatalf("final first part = %#v, want %#v", final.Parts[0], want)
+	}
+	if got, want := final.Text(), "42"; got != want {
+		t.Fatalf("final text = %q, want %q", got, want)
+	}
+}
+
+func TestChoiceToResponseAnnotations(t *testing.T) {
+	t.Parallel()
+
+	var cc openaisdk.ChatCompletion
+	// Indexes count characters: "é" is one character but two bytes.
+	if err := json.Unmarshal([]byte(`{"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Café news today.","annotations":[{"type":"url_citation","url_citation":{"start_index":0,"end_index":9,"url":"https://example.com/news","title":"News"}}]}}]}`), &cc); err != nil {
+		t.Fatalf("unmarshal completion: %v", err)
+	}
+	response, err := choiceToResponse(context.Background(), openaisdk.ChatCompletionNewParams{}, &cc)
+	if err != nil {
+		t.Fatalf("choiceToResponse returned error: %v", err)
+	}
+	text, ok := response.Message.Parts[0].(blades.TextPart)
+	if !ok || len(text.Annotations) != 1 {
+		t.Fatalf("parts = %#v, want annotated text", response.Message.Parts)
+	}
+	want := blades.Annotation{Start: 0, End: 10, SourceID: "https://example.com/news", URL: "https://example.com/news", Title: "News"}
+	if got := text.Annotations[0]; got != want {
+		t.Fatalf("annotation = %#v, want %#v", got, want)
+	}
+	if got := text.Text[want.Start:want.End]; got != "Café news" {
+		t.Fatalf("annotated span = %q", got)
+	}
+}
+
+func TestNewStreamingAnnotations(t *testing.T) {
+	t.Parallel()
+
+	chunks := []string{
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","content":"See "}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"docs.","annotations":[{"type":"url_citation","url_citation":{"start_index":4,"end_index":8,"url":"https://example.com/docs","title":"Docs"}}]},"finish_reason":"stop"}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	model := NewModel("gpt-test", Config{BaseURL: server.URL, APIKey: "test"})
+	var final *blades.Message
+	for response, err := range model.NewStreaming(context.Background(), &blades.ModelRequest{Messages: []*blades.Message{blades.UserMessage("q")}}) {
+		if err != nil {
+			t.Fatalf("stream error: %v", err)
+		}
+		if response.Message.Status == blades.StatusCompleted {
+			final = response.Message
+		}
+	}
+	if final == nil {
+		t.Fatal("no final response")
+	}
+	text, ok := final.Parts[0].(blades.TextPart)
+	if !ok || text.Text != "See docs." || len(text.Annotations) != 1 {
+		t.Fatalf("final parts = %#v", final.Parts)
+	}
+	if got := text.Annotations[0]; got.URL != "https://example.com/docs" || got.Start != 4 || got.End != 8 {
+		t.Fatalf("annotation = %#v", got)
+	}
+}
+
+func TestToContentPartsDocuments(t *testing.T) {
+	t.Parallel()
+
+	parts, err := toContentParts(blades.UserMessage(
+		"summarize",
+		blades.DataPart{Name: "report.pdf", Bytes: []byte("%PDF-1.7"), MIMEType: blades.MIMEPDF},
+		blades.DataPart{Name: "rows.csv", Bytes: []byte("a,b\n1,2"), MIMEType: blades.MIMECSV},
+	))
+	if err != nil {
+		t.Fatalf("toContentParts returned error: %v", err)
+	}
+	if got, want := len(parts), 3; got != want {
+		t.Fatalf("parts len = %d, want %d", got, want)
+	}
+	file := parts[1].OfFile
+	if file == nil {
+		t.Fatalf("pdf part = %+v, want file", parts[1])
+	}
+	if got, want := file.File.FileData.Value, "data:application/pdf;base64,JVBERi0xLjc="; got != want {
+		t.Fatalf("file data = %q, want %q", got, want)
+	}
+	if got, want := file.File.Filename.Value, "report.pdf"; got != want {
+		t.Fatalf("filename = %q, want %q", got, want)
+	}
+	if text := parts[2].OfText; text == nil || text.Text != "[File: rows.csv (text/csv)]\na,b\n1,2" {
+		t.Fatalf("csv part = %+v, want text", parts[2])
+	}
+}
+
+func TestToContentPartsRejectsUnsupportedMedia(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		part blades.Part
+		want error
+	}{
+		{blades.DataPart{Name: "deck.pptx", Bytes: []byte("x"), MIMEType: blades.MIMEPPTX}, blades.ErrUnsupportedMediaType},
+		{blades.FilePart{Name: "spec.pdf", URI: "https://example.com/spec.pdf", MIMEType: blades.MIMEPDF}, blades.ErrUnsupportedMediaType},
+		{blades.DataPart{Name: "big.pdf", Bytes: make([]byte, maxFileBytes+1), MIMEType: blades.MIMEPDF}, blades.ErrMediaTooLarge},
+	}
+	for _, tt := range tests {
+		model := &chatModel{model: "gpt-test"}
+		_, err := model.toChatCompletionParams(false, &blades.ModelRequest{
+			Messages: []*blades.Message{blades.UserMessage("read", tt.part)},
+		})
+		if !errors.Is(err, tt.want) {
+			t.Fatalf("%T: err = %v, want %v", tt.part, err, tt.want)
+		}
+	}
+}
+
+func TestToContentPartsSkipsArtifactFiles(t *testing.T) {
+	t.Parallel()
+
+	parts, err := toContentParts(blades.UserMessage(
+		"read",
+		blades.FilePart{Name: "report.pdf", URI: blades.ArtifactURI("report.pdf", 1), MIMEType: blades.MIMEPDF},
+		blades.FilePart{Name: "chart.png", URI: blades.ArtifactURI("chart.png", 2), MIMEType: blades.MIMEImagePNG},
+	))
+	if err != nil {
+		t.Fatalf("toContentParts returned error: %v", err)
+	}
+	if len(parts) != 1 || parts[0].OfText == nil {
+		t.Fatalf("parts = %+v, want only the text", parts)
+	}
+}
+
+func TestToChatCompletionParamsGenerationConfig(t *testing.T) {
+	t.Parallel()
+
+	model := &chatModel{model: "gpt-test", config: Config{Temperature: 1, Seed: 3, StopSequences: []string{"STOP"}}}
+	params, err := model.toChatCompletionParams(false, &blades.ModelRequest{
+		Messages: []*blades.Message{blades.UserMessage("hello")},
+		Generation: &blades.GenerationConfig{
+			Temperature:     blades.Ptr(0.0),
+			MaxOutputTokens: blades.Ptr(int64(128)),
+			StopSequences:   []string{"END"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("toChatCompletionParams returned error: %v", err)
+	}
+	if !params.Temperature.Valid() || params.Temperature.Value != 0 {
+		t.Fatalf("temperature = %+v, want 0", params.Temperature)
+	}
+	if params.MaxCompletionTokens.Value != 128 || params.Seed.Value != 3 {
+		t.Fatalf("max tokens = %d, seed = %d, want 128 and 3", params.MaxCompletionTokens.Value, params.Seed.Value)
+	}
+	if got := params.Stop.OfStringArray; len(got) != 1 || got[0] != "END" {
+		t.Fatalf("stop = %v, want [END]", got)
+	}
+
+	_, err = model.toChatCompletionParams(false, &blades.ModelRequest{
+		Messages:   []*blades.Message{blades.UserMessage("hello")},
+		Generation: &blades.GenerationConfig{TopK: blades.Ptr(int64(40))},
+	})
+	var unsupported *blades.UnsupportedGenerationError
+	if !errors.As(err, &unsupported) || len(unsupported.Fields) != 1 || unsupported.Fields[0] != blades.GenerationTopK {
+		t.Fatalf("err = %v, want unsupported top k", err)
+	}
+}
+
+func TestToChatCompletionParamsToolChoice(t *testing.T) {
+	t.Parallel()
+
+	weather := tools.NewTool("get_weather", "gets the weather", tools.HandleFunc(func(context.Context, string) (string, error) {
+		return "sunny", nil
+	}))
+	model := &chatModel{model: "gpt-test"}
+	tests := []struct {
+		choice blades.ToolChoice
+		want   string
+	}{
+		{blades.ToolChoice{Mode: blades.ToolChoiceNone}, `"none"`},
+		{blades.ToolChoice{Mode: blades.ToolChoiceRequired}, `"required"`},
+		{blades.ToolChoiceNamed("get_weather"), `{"function":{"name":"get_weather"},"type":"function"}`},
+	}
+	for _, tt := range tests {
+		params, err := model.toChatCompletionParams(false, &blades.ModelRequest{
+			Messages:   []*blades.Message{blades.UserMessage("weather?")},
+			Tools:      []tools.Tool{weather},
+			ToolChoice: &tt.choice,
+		})
+		if err != nil {
+			t.Fatalf("toChatCompletionParams returned error: %v", err)
+		}
+		got, err := json.Marshal(params.ToolChoice)
+		if err != nil {
+			t.Fatalf("marshal tool choice: %v", err)
+		}
+		if string(got) != tt.want {
+			t.Fatalf("tool_choice = %s, want %s", got, tt.want)
+		}
+	}
+}
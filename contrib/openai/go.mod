@@ -4,12 +4,12 @@ go 1.25.0
 
 require (
 	github.com/go-kratos/blades v0.0.0-20251104140906-5d72b556bf96
+	github.com/google/jsonschema-go v0.3.0
 	github.com/openai/openai-go/v3 v3.8.1
 )
 
 require (
 	github.com/go-kratos/kit v0.0.0-20251121083925-65298ad2aa44 // indirect
-	github.com/google/jsonschema-go v0.3.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/tidwall/gjson v1.18.0 // indirect
 	github.com/tidwall/match v1.2.0 // indirect
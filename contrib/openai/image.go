@@ -85,6 +85,10 @@ func (m *imageModel) NewStreaming(ctx context.Context, req *blades.ModelRequest)
 }
 
 func (m *imageModel) buildGenerateParams(req *blades.ModelRequest) (openai.ImageGenerateParams, error) {
+	// Image generation takes none of the generation settings.
+	if fields := req.Generation.Unsupported(); len(fields) > 0 {
+		return openai.ImageGenerateParams{}, &blades.UnsupportedGenerationError{Provider: providerName, Fields: fields}
+	}
 	params := openai.ImageGenerateParams{
 		Prompt: promptFromMessages(req.Messages),
 		Model:  openai.ImageModel(m.model),
@@ -0,0 +1,82 @@
+package openai
+
+import (
+	"regexp"
+	"slices"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+const defaultSchemaName = "structured_outputs"
+
+var invalidSchemaName = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// schemaName derives a response format name from the schema title. OpenAI
+// accepts names of at most 64 letters, digits, underscores and dashes.
+func schemaName(schema *jsonschema.Schema) string {
+	name := invalidSchemaName.ReplaceAllString(schema.Title, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	if name == "" || name == "_" {
+		return defaultSchemaName
+	}
+	return name
+}
+
+// strictSchema returns a copy of schema that satisfies the rules of strict
+// structured outputs, where every object forbids additional properties. It
+// reports false when the schema cannot be sent in strict mode, for example
+// when an object has optional properties or open additional properties.
+// Optional properties are not rewritten as required and nullable because the
+// answer must still validate against the original schema.
+func strictSchema(schema *jsonschema.Schema) (*jsonschema.Schema, bool) {
+	strict := schema.CloneSchemas()
+	if !makeStrict(strict) {
+		return nil, false
+	}
+	return strict, true
+}
+
+func makeStrict(s *jsonschema.Schema) bool {
+	if s == nil {
+		return true
+	}
+	if len(s.AllOf) > 0 || len(s.OneOf) > 0 || s.PatternProperties != nil || s.Not != nil {
+		return false
+	}
+	if s.Type == "object" || slices.Contains(s.Types, "object") || s.Properties != nil {
+		if s.AdditionalProperties != nil && !isFalseSchema(s.AdditionalProperties) {
+			return false
+		}
+		s.AdditionalProperties = &jsonschema.Schema{Not: &jsonschema.Schema{}}
+		for name := range s.Properties {
+			if !slices.Contains(s.Required, name) {
+				return false
+			}
+		}
+	}
+	children := []*jsonschema.Schema{s.Items}
+	children = append(children, s.PrefixItems...)
+	children = append(children, s.AnyOf...)
+	for _, m := range []map[string]*jsonschema.Schema{s.Properties, s.Defs, s.Definitions} {
+		for _, child := range m {
+			children = append(children, child)
+		}
+	}
+	for _, child := range children {
+		if !makeStrict(child) {
+			return false
+		}
+	}
+	return true
+}
+
+func isFalseSchema(s *jsonschema.Schema) bool {
+	return s.Not != nil && isEmptySchema(s.Not)
+}
+
+func isEmptySchema(s *jsonschema.Schema) bool {
+	data, err := s.MarshalJSON()
+	return err == nil && string(data) == "true"
+}
@@ -0,0 +1,292 @@
+// Package sqlite provides a blades.SessionStore backed by an embedded SQLite
+// database. The pure-Go modernc.org/sqlite driver is registered as "sqlite".
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/blades"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS blades_sessions (
+	id         TEXT PRIMARY KEY,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS blades_messages (
+	session_id TEXT NOT NULL,
+	seq        INTEGER NOT NULL,
+	data       TEXT NOT NULL,
+	PRIMARY KEY (session_id, seq)
+);
+CREATE TABLE IF NOT EXISTS blades_session_state (
+	session_id TEXT NOT NULL,
+	key        TEXT NOT NULL,
+	value      TEXT NOT NULL,
+	PRIMARY KEY (session_id, key)
+);`
+
+// SessionStore is a blades.SessionStore that keeps sessions in SQLite tables.
+type SessionStore struct {
+	db       *sql.DB
+	opts     []blades.SessionOption
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+var _ blades.SessionStore = (*SessionStore)(nil)
+
+// NewSessionStore creates the session tables in db if they do not exist and
+// returns a store using it. The SessionOptions (e.g. blades.WithContextCompressor)
+// are applied to every session returned by Get.
+//
+//	db, err := sql.Open("sqlite", "sessions.db")
+//	store, err := sqlite.NewSessionStore(db)
+func NewSessionStore(db *sql.DB, opts ...blades.SessionOption) (*SessionStore, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("sqlite: create schema: %w", err)
+	}
+	return &SessionStore{
+		db:       db,
+		opts:     opts,
+		sessions: make(map[string]*session),
+	}, nil
+}
+
+// Get returns the session with the given ID, loading its history and state
+// from the database when it is not already open. A new session row is
+// created when none exists.
+func (s *SessionStore) Get(ctx context.Context, id string) (blades.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess, ok := s.sessions[id]; ok {
+		return sess, nil
+	}
+	now := time.Now().UnixMilli()
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO blades_sessions (id, created_at, updated_at) VALUES (?, ?, ?) ON CONFLICT(id) DO NOTHING`,
+		id, now, now,
+	); err != nil {
+		return nil, fmt.Errorf("sqlite: create session %s: %w", id, err)
+	}
+	sess := &session{
+		id:   id,
+		db:   s.db,
+		base: blades.NewSession(s.opts...),
+	}
+	if err := sess.loadMessages(ctx); err != nil {
+		return nil, err
+	}
+	if err := sess.loadState(ctx); err != nil {
+		return nil, err
+	}
+	s.sessions[id] = sess
+	return sess, nil
+}
+
+// List returns the stored sessions, most recently updated first.
+func (s *SessionStore) List(ctx context.Context) ([]blades.SessionInfo, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, created_at, updated_at FROM blades_sessions ORDER BY updated_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list sessions: %w", err)
+	}
+	defer rows.Close()
+	var infos []blades.SessionInfo
+	for rows.Next() {
+		var (
+			info               blades.SessionInfo
+			createdAt, updated int64
+		)
+		if err := rows.Scan(&info.ID, &createdAt, &updated); err != nil {
+			return nil, fmt.Errorf("sqlite: scan session: %w", err)
+		}
+		info.CreatedAt = time.UnixMilli(createdAt)
+		info.UpdatedAt = time.UnixMilli(updated)
+		infos = append(infos, info)
+	}
+	return infos, rows.Err()
+}
+
+// Delete removes the session, its messages and its state.
+func (s *SessionStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite: delete %s: %w", id, err)
+	}
+	defer tx.Rollback()
+	for _, query := range []string{
+		`DELETE FROM blades_messages WHERE session_id = ?`,
+		`DELETE FROM blades_session_state WHERE session_id = ?`,
+		`DELETE FROM blades_sessions WHERE id = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("sqlite: delete %s: %w", id, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// session is a blades.Session that writes every change through to SQLite.
+// An in-memory session holds the loaded history and applies compression.
+type session struct {
+	mu   sync.Mutex
+	id   string
+	db   *sql.DB
+	base blades.Session
+	seq  int64
+	// err records a failed state write; SetState cannot return errors,
+	// so the failure is reported by the next Append.
+	err error
+}
+
+func (s *session) ID() string {
+	return s.id
+}
+
+func (s *session) State() blades.State {
+	return s.base.State()
+}
+
+func (s *session) SetState(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.base.SetState(key, value)
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = s.writeState(key, string(data))
+	}
+	if err != nil && s.err == nil {
+		s.err = fmt.Errorf("sqlite: set state %q: %w", key, err)
+	}
+}
+
+// writeState stores a state value and marks the session as updated.
+func (s *session) writeState(key, data string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(
+		`INSERT INTO blades_session_state (session_id, key, value) VALUES (?, ?, ?)
+		ON CONFLICT(session_id, key) DO UPDATE SET value = excluded.value`,
+		s.id, key, data,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`UPDATE blades_sessions SET updated_at = ? WHERE id = ?`,
+		time.Now().UnixMilli(), s.id,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *session) Append(ctx context.Context, message *blades.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		err := s.err
+		s.err = nil
+		return err
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("sqlite: marshal message: %w", err)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite: append %s: %w", s.id, err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO blades_messages (session_id, seq, data) VALUES (?, ?, ?)`,
+		s.id, s.seq, string(data),
+	); err != nil {
+		return fmt.Errorf("sqlite: append %s: %w", s.id, err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE blades_sessions SET updated_at = ? WHERE id = ?`,
+		time.Now().UnixMilli(), s.id,
+	); err != nil {
+		return fmt.Errorf("sqlite: append %s: %w", s.id, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite: append %s: %w", s.id, err)
+	}
+	s.seq++
+	return s.base.Append(ctx, message)
+}
+
+func (s *session) History(ctx context.Context) ([]*blades.Message, error) {
+	return s.base.History(ctx)
+}
+
+// loadMessages reads the stored history into the in-memory session.
+func (s *session) loadMessages(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT seq, data FROM blades_messages WHERE session_id = ? ORDER BY seq`, s.id)
+	if err != nil {
+		return fmt.Errorf("sqlite: load %s: %w", s.id, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			seq     int64
+			data    string
+			message blades.Message
+		)
+		if err := rows.Scan(&seq, &data); err != nil {
+			return fmt.Errorf("sqlite: load %s: %w", s.id, err)
+		}
+		if err := json.Unmarshal([]byte(data), &message); err != nil {
+			return fmt.Errorf("sqlite: decode message %s/%d: %w", s.id, seq, err)
+		}
+		if err := s.base.Append(ctx, &message); err != nil {
+			return err
+		}
+		s.seq = seq + 1
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("sqlite: load %s: %w", s.id, err)
+	}
+	return nil
+}
+
+// loadState reads the stored state values into the in-memory session.
+func (s *session) loadState(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT key, value FROM blades_session_state WHERE session_id = ?`, s.id)
+	if err != nil {
+		return fmt.Errorf("sqlite: load state %s: %w", s.id, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			key, data string
+			value     any
+		)
+		if err := rows.Scan(&key, &data); err != nil {
+			return fmt.Errorf("sqlite: load state %s: %w", s.id, err)
+		}
+		if err := json.Unmarshal([]byte(data), &value); err != nil {
+			return fmt.Errorf("sqlite: decode state %q: %w", key, err)
+		}
+		s.base.SetState(key, value)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("sqlite: load state %s: %w", s.id, err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/blades"
+)
+
+type lastCompressor struct{}
+
+func (lastCompressor) Compress(_ context.Context, messages []*blades.Message) ([]*blades.Message, error) {
+	if len(messages) <= 1 {
+		return messages, nil
+	}
+	return messages[len(messages)-1:], nil
+}
+
+func openStore(t *testing.T, path string, opts ...blades.SessionOption) *SessionStore {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := NewSessionStore(db, opts...)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	return store
+}
+
+func TestSessionStoreSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "sessions.db")
+
+	store := openStore(t, path)
+	sess, err := store.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	sess.SetState("topic", "weather")
+	sess.SetState("topic", "news")
+	if err := sess.Append(ctx, blades.UserMessage("hi")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	tool := blades.NewAssistantMessage(blades.StatusCompleted)
+	tool.Role = blades.RoleTool
+	tool.Parts = append(tool.Parts, blades.ToolPart{ID: "call_1", Name: "lookup", Request: `{"q":1}`})
+	if err := sess.Append(ctx, tool); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	restored, err := openStore(t, path).Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("get restored: %v", err)
+	}
+	if got := restored.State()["topic"]; got != "news" {
+		t.Fatalf("state topic = %v, want news", got)
+	}
+	history, err := restored.History(ctx)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history len = %d, want 2", len(history))
+	}
+	if got := history[0].Text(); got != "hi" {
+		t.Fatalf("first message = %q, want hi", got)
+	}
+	part, ok := history[1].Parts[0].(blades.ToolPart)
+	if !ok {
+		t.Fatalf("part type = %T, want ToolPart", history[1].Parts[0])
+	}
+	if part.ID != "call_1" || part.Completed {
+		t.Fatalf("tool part = %+v, want pending call_1", part)
+	}
+	// Appending after a restart continues the stored sequence.
+	if err := restored.Append(ctx, blades.UserMessage("again")); err != nil {
+		t.Fatalf("append restored: %v", err)
+	}
+}
+
+func TestSessionStoreAppliesContextCompressor(t *testing.T) {
+	ctx := context.Background()
+	store := openStore(t, filepath.Join(t.TempDir(), "sessions.db"), blades.WithContextCompressor(lastCompressor{}))
+	sess, err := store.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	for _, text := range []string{"a", "b", "c"} {
+		if err := sess.Append(ctx, blades.UserMessage(text)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	history, err := sess.History(ctx)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 1 || history[0].Text() != "c" {
+		t.Fatalf("history = %v, want only c", history)
+	}
+}
+
+func TestSessionStoreListAndDelete(t *testing.T) {
+	ctx := context.Background()
+	store := openStore(t, filepath.Join(t.TempDir(), "sessions.db"))
+	for _, id := range []string{"a", "b"} {
+		sess, err := store.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("get %s: %v", id, err)
+		}
+		if err := sess.Append(ctx, blades.UserMessage(id)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	infos, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("list len = %d, want 2", len(infos))
+	}
+	if err := store.Delete(ctx, "a"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	infos, err = store.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(infos) != 1 || infos[0].ID != "b" {
+		t.Fatalf("list = %v, want [b]", infos)
+	}
+	sess, err := store.Get(ctx, "a")
+	if err != nil {
+		t.Fatalf("get deleted: %v", err)
+	}
+	history, err := sess.History(ctx)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 0 {
+		t.Fatalf("deleted session history len = %d, want 0", len(history))
+	}
+}
+
+func TestSessionStoreSetStateUpdatesSession(t *testing.T) {
+	ctx := context.Background()
+	store := openStore(t, filepath.Join(t.TempDir(), "sessions.db"))
+	a, err := store.Get(ctx, "a")
+	if err != nil {
+		t.Fatalf("get a: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := store.Get(ctx, "b"); err != nil {
+		t.Fatalf("get b: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	a.SetState("topic", "weather")
+	infos, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(infos) != 2 || infos[0].ID != "a" {
+		t.Fatalf("list = %v, want a first", infos)
+	}
+}
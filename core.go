@@ -3,6 +3,7 @@ package blades
 import (
 	"context"
 	"iter"
+	"maps"
 	"slices"
 	"sync/atomic"
 
@@ -23,6 +24,9 @@ type Invocation struct {
 	// They are not persisted into the session history.
 	EphemeralMessages []*Message
 	Tools             []tools.Tool
+	// ToolDecisions holds human decisions for tool calls awaiting approval,
+	// keyed by ToolPart.ID. They are applied when the invocation is resumed.
+	ToolDecisions map[string]ToolDecision
 	// committed tracks whether the initial user message has been (or will be)
 	// appended to the session. All clones share the same *atomic.Bool pointer,
 	// so CompareAndSwap guarantees exactly-once append even under concurrent
@@ -71,5 +75,6 @@ func (inv *Invocation) Clone() *Invocation {
 		EphemeralMessages: ephemeral,
 		committed:         inv.committed,
 		Tools:             slices.Clone(inv.Tools),
+		ToolDecisions:     maps.Clone(inv.ToolDecisions),
 	}
 }
@@ -2,6 +2,9 @@ package blades
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 )
 
 var (
@@ -19,6 +22,163 @@ var (
 	ErrNoFinalResponse = errors.New("stream ended without a final response")
 	// ErrInterrupted is returned when execution is interrupted.
 	ErrInterrupted = errors.New("execution was interrupted")
+	// ErrInvalidOutput is returned when the final answer does not conform to the output schema.
+	ErrInvalidOutput = errors.New("output does not match the output schema")
+	// ErrTokenBudgetExceeded is returned when a run consumes more tokens than its budget allows.
+	ErrTokenBudgetExceeded = errors.New("token budget exceeded")
+	// ErrToolTimeout is returned when a tool call times out and the timeout policy fails the run.
+	ErrToolTimeout = errors.New("tool call timed out")
+	// ErrInvalidSessionID is returned when a store cannot use a session ID, such as one that is not a valid file name.
+	ErrInvalidSessionID = errors.New("invalid session id")
+	// ErrSessionDeleted is returned when writing to a session after it was deleted from its store.
+	ErrSessionDeleted = errors.New("session deleted")
+	// ErrArtifactNotFound is returned when an artifact or one of its versions does not exist.
+	ErrArtifactNotFound = errors.New("artifact not found")
 	// ErrLoopEscalated is returned when a loop condition signals escalation to an outer handler.
 	ErrLoopEscalated = errors.New("loop escalated to outer handler")
+	// ErrUnsupportedMediaType is returned when a model provider cannot accept the media type of a part.
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	// ErrMediaTooLarge is returned when a part exceeds the size a model provider accepts.
+	ErrMediaTooLarge = errors.New("media too large")
+	// ErrUnsupportedGeneration is returned when a model provider cannot honor a GenerationConfig field.
+	ErrUnsupportedGeneration = errors.New("unsupported generation config")
 )
+
+// InterruptError is returned when a run pauses because tool calls are awaiting
+// approval. It wraps ErrInterrupted. Resume the run with the same session and
+// invocation ID, WithResume(true) and WithToolDecisions for the pending calls.
+type InterruptError struct {
+	// InvocationID identifies the paused invocation.
+	InvocationID string
+	// Message is the tool message persisted with the pending calls.
+	Message *Message
+	// Pending lists the tool calls awaiting a decision.
+	Pending []ToolPart
+}
+
+func (e *InterruptError) Error() string {
+	return fmt.Sprintf("%s: %d tool call(s) awaiting approval", ErrInterrupted, len(e.Pending))
+}
+
+// Unwrap returns ErrInterrupted.
+func (e *InterruptError) Unwrap() error {
+	return ErrInterrupted
+}
+
+// BudgetScope identifies which TokenBudget limit was exceeded.
+type BudgetScope string
+
+const (
+	// BudgetScopeInvocation is the per-invocation limit.
+	BudgetScopeInvocation BudgetScope = "invocation"
+	// BudgetScopeSession is the per-session limit.
+	BudgetScopeSession BudgetScope = "session"
+)
+
+// TokenBudgetError is returned when a run exceeds its TokenBudget. It wraps
+// ErrTokenBudgetExceeded.
+type TokenBudgetError struct {
+	// Scope is the budget that was exceeded.
+	Scope BudgetScope
+	// Limit is the exceeded limit.
+	Limit TokenUsage
+	// Usage is the usage counted against the limit.
+	Usage TokenUsage
+}
+
+func (e *TokenBudgetError) Error() string {
+	return fmt.Sprintf("%s: %s used %d input, %d output, %d total tokens",
+		ErrTokenBudgetExceeded, e.Scope, e.Usage.InputTokens, e.Usage.OutputTokens, e.Usage.TotalTokens)
+}
+
+// Unwrap returns ErrTokenBudgetExceeded.
+func (e *TokenBudgetError) Unwrap() error {
+	return ErrTokenBudgetExceeded
+}
+
+// ToolTimeoutError is returned by FailOnToolTimeout when a tool call times
+// out. It wraps ErrToolTimeout.
+type ToolTimeoutError struct {
+	// ID is the ToolPart.ID of the call.
+	ID string
+	// Name is the name of the tool.
+	Name string
+	// Timeout is the exceeded timeout.
+	Timeout time.Duration
+}
+
+func (e *ToolTimeoutError) Error() string {
+	return fmt.Sprintf("%s: %s after %s", ErrToolTimeout, e.Name, e.Timeout)
+}
+
+// Unwrap returns ErrToolTimeout.
+func (e *ToolTimeoutError) Unwrap() error {
+	return ErrToolTimeout
+}
+
+// UnsupportedMediaError is returned by a model provider when a message part
+// has a media type, or a source, the provider cannot accept. It wraps
+// ErrUnsupportedMediaType.
+type UnsupportedMediaError struct {
+	// Provider is the name of the model provider.
+	Provider string
+	// Name is the name of the part, if any.
+	Name string
+	// MIMEType is the rejected media type.
+	MIMEType MIMEType
+}
+
+func (e *UnsupportedMediaError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("%s: %s does not accept %s (%s)", ErrUnsupportedMediaType, e.Provider, e.MIMEType, e.Name)
+	}
+	return fmt.Sprintf("%s: %s does not accept %s", ErrUnsupportedMediaType, e.Provider, e.MIMEType)
+}
+
+// Unwrap returns ErrUnsupportedMediaType.
+func (e *UnsupportedMediaError) Unwrap() error {
+	return ErrUnsupportedMediaType
+}
+
+// MediaTooLargeError is returned by a model provider when a message part is
+// larger than the provider accepts. It wraps ErrMediaTooLarge.
+type MediaTooLargeError struct {
+	// Provider is the name of the model provider.
+	Provider string
+	// Name is the name of the part, if any.
+	Name string
+	// MIMEType is the media type of the part.
+	MIMEType MIMEType
+	// Size is the size of the part in bytes.
+	Size int
+	// Limit is the largest size the provider accepts.
+	Limit int
+}
+
+func (e *MediaTooLargeError) Error() string {
+	return fmt.Sprintf("%s: %s %q is %d bytes, %s accepts at most %d", ErrMediaTooLarge, e.MIMEType, e.Name, e.Size, e.Provider, e.Limit)
+}
+
+// Unwrap returns ErrMediaTooLarge.
+func (e *MediaTooLargeError) Unwrap() error {
+	return ErrMediaTooLarge
+}
+
+// UnsupportedGenerationError is returned by a model provider when a request
+// sets GenerationConfig fields the provider cannot honor. It wraps
+// ErrUnsupportedGeneration.
+type UnsupportedGenerationError struct {
+	// Provider is the name of the model provider.
+	Provider string
+	// Fields are the names of the unsupported fields, see GenerationConfig.Fields.
+	Fields []string
+}
+
+func (e *UnsupportedGenerationError) Error() string {
+	return fmt.Sprintf("%s: %s does not support %s", ErrUnsupportedGeneration, e.Provider, strings.Join(e.Fields, ", "))
+}
+
+// Unwrap returns ErrUnsupportedGeneration.
+func (e *UnsupportedGenerationError) Unwrap() error {
+	return ErrUnsupportedGeneration
+}
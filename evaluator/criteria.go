@@ -2,7 +2,6 @@ package evaluator
 
 import (
 	"context"
-	"encoding/json"
 
 	"github.com/go-kratos/blades"
 	"github.com/google/jsonschema-go/jsonschema"
@@ -19,6 +18,8 @@ func New(name string, opts ...blades.AgentOption) (Evaluator, error) {
 	if err != nil {
 		return nil, err
 	}
+	// A single repair attempt is allowed by default; opts may override it.
+	opts = append([]blades.AgentOption{blades.WithOutputRepair(1)}, opts...)
 	agent, err := blades.NewAgent(
 		name,
 		append(opts, blades.WithOutputSchema(schema))...,
@@ -36,8 +37,8 @@ func (r *Criteria) Run(ctx context.Context, message *blades.Message) (*Evaluatio
 		if err != nil {
 			return nil, err
 		}
-		var evaluation Evaluation
-		if err := json.Unmarshal([]byte(msg.Text()), &evaluation); err != nil {
+		evaluation, err := blades.DecodeOutput[Evaluation](msg)
+		if err != nil {
 			return nil, err
 		}
 		return &evaluation, nil
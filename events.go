@@ -0,0 +1,186 @@
+package blades
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+const (
+	// EventInvocationStart is emitted once before the root agent runs.
+	EventInvocationStart EventType = "invocation_start"
+	// EventInvocationEnd is emitted once after the root agent finished; it
+	// carries the final message, or the error that ended the run.
+	EventInvocationEnd EventType = "invocation_end"
+	// EventAgentEnter is emitted when an agent, including a sub-agent, starts running.
+	EventAgentEnter EventType = "agent_enter"
+	// EventAgentExit is emitted when an agent stops running.
+	EventAgentExit EventType = "agent_exit"
+	// EventModelRequest is emitted before a request is sent to the model.
+	EventModelRequest EventType = "model_request"
+	// EventTextDelta is emitted for each incremental assistant chunk while streaming.
+	EventTextDelta EventType = "text_delta"
+	// EventMessage is emitted for each completed message produced by an agent.
+	EventMessage EventType = "message"
+	// EventToolCallStart is emitted before a tool is executed.
+	EventToolCallStart EventType = "tool_call_start"
+	// EventToolCallEnd is emitted after a tool was executed.
+	EventToolCallEnd EventType = "tool_call_end"
+	// EventStateChange is emitted when a session state value is set.
+	EventStateChange EventType = "state_change"
+	// EventError is emitted when the run fails.
+	EventError EventType = "error"
+)
+
+// Event describes a step in the lifecycle of a run. Only the fields relevant
+// to the event type are set.
+type Event struct {
+	// ID uniquely identifies the event.
+	ID   string
+	Type EventType
+	Time time.Time
+	// InvocationID is the invocation of the agent that emitted the event.
+	InvocationID string
+	// Agent is the name of the agent that emitted the event.
+	Agent string
+	// Message is the produced message for EventMessage, EventTextDelta and
+	// EventInvocationEnd.
+	Message *Message
+	// Text is the incremental text of an EventTextDelta.
+	Text string
+	// Request is the request sent to the model for EventModelRequest.
+	Request *ModelRequest
+	// Tool is the tool call for EventToolCallStart and EventToolCallEnd. Its
+	// ID correlates the start and end events of the same call.
+	Tool *ToolPart
+	// Key and Value are the state entry of an EventStateChange.
+	Key   string
+	Value any
+	// Duration is the elapsed time for EventToolCallEnd, EventAgentExit and
+	// EventInvocationEnd.
+	Duration time.Duration
+	// Usage is the usage report of the run for EventInvocationEnd.
+	Usage *UsageReport
+	// Err is the failure for EventError, and for end and exit events of a
+	// failed run.
+	Err error
+}
+
+type ctxEventsKey struct{}
+
+// eventSink receives the events emitted during a run.
+type eventSink func(*Event)
+
+// emitEvent sends the event to the sink carried by ctx, if any, filling in its
+// ID, time and agent name.
+func emitEvent(ctx context.Context, event *Event) {
+	sink, ok := ctx.Value(ctxEventsKey{}).(eventSink)
+	if !ok {
+		return
+	}
+	event.ID = uuid.NewString()
+	event.Time = time.Now()
+	if event.Agent == "" {
+		if agent, ok := FromAgentContext(ctx); ok {
+			event.Agent = agent.Name()
+		}
+	}
+	sink(event)
+}
+
+// eventSession reports state changes of the wrapped session as events.
+type eventSession struct {
+	Session
+	ctx context.Context
+}
+
+func (s *eventSession) SetState(key string, value any) {
+	s.Session.SetState(key, value)
+	emitEvent(s.ctx, &Event{Type: EventStateChange, Key: key, Value: value})
+}
+
+// RunEvents runs the agent like Run and yields the lifecycle events of the run
+// as they happen, including those of sub-agents and agent tools. Failures are
+// reported as an EventError followed by EventInvocationEnd. Stopping the
+// iteration cancels the run.
+func (r *Runner) RunEvents(ctx context.Context, message *Message, opts ...RunOption) iter.Seq[*Event] {
+	return func(yield func(*Event) bool) {
+		o := &RunOptions{
+			Session:      NewSession(),
+			InvocationID: NewInvocationID(),
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+		r.prepareSession(o)
+		ctx, cancel := context.WithCancel(ctx)
+		var (
+			wg     sync.WaitGroup
+			events = make(chan *Event)
+		)
+		defer func() {
+			cancel()
+			// Unblock the run until it observes the cancellation.
+			for range events {
+			}
+			wg.Wait()
+		}()
+		ctx = context.WithValue(ctx, ctxEventsKey{}, eventSink(func(event *Event) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		}))
+		o.Session = &eventSession{Session: o.Session, ctx: ctx}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(events)
+			var (
+				err    error
+				output *Message
+				start  = time.Now()
+				agent  = r.rootAgent.Name()
+			)
+			emitEvent(ctx, &Event{Type: EventInvocationStart, InvocationID: o.InvocationID, Agent: agent, Message: message})
+			invocation := r.buildInvocation(message, true, o)
+			tracker := newUsageTracker(invocation.ID, o.Session, r.budget)
+			runCtx := r.runContext(ctx, o.Session, tracker)
+			for m, runErr := range r.rootAgent.Run(runCtx, invocation) {
+				if runErr != nil {
+					err = runErr
+					break
+				}
+				output = m
+			}
+			if err == nil && output == nil {
+				err = ErrNoFinalResponse
+			}
+			report := tracker.save(o.Session)
+			if err != nil {
+				emitEvent(ctx, &Event{Type: EventError, InvocationID: o.InvocationID, Agent: agent, Err: err})
+				output = nil
+			}
+			emitEvent(ctx, &Event{
+				Type:         EventInvocationEnd,
+				InvocationID: o.InvocationID,
+				Agent:        agent,
+				Message:      output,
+				Duration:     time.Since(start),
+				Usage:        &report,
+				Err:          err,
+			})
+		}()
+		for event := range events {
+			if !yield(event) {
+				return
+			}
+		}
+	}
+}
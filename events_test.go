@@ -0,0 +1,142 @@
+package blades
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	bladestools "github.com/go-kratos/blades/tools"
+)
+
+// eventsModel streams a tool call until it sees the tool result, then streams
+// the answer in two chunks.
+type eventsModel struct{}
+
+func (eventsModel) Name() string { return "events" }
+
+func (eventsModel) Generate(context.Context, *ModelRequest) (*ModelResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (eventsModel) NewStreaming(_ context.Context, req *ModelRequest) Generator[*ModelResponse, error] {
+	return func(yield func(*ModelResponse, error) bool) {
+		for _, message := range req.Messages {
+			if message.Role == RoleTool {
+				if yield(streamingResponse(StatusIncomplete, "it is "), nil) {
+					yield(streamingResponse(StatusCompleted, "it is sunny"), nil)
+				}
+				return
+			}
+		}
+		msg := NewAssistantMessage(StatusCompleted)
+		msg.Role = RoleTool
+		msg.Parts = append(msg.Parts, NewToolPart("call_1", "weather", `{}`))
+		yield(&ModelResponse{Message: msg}, nil)
+	}
+}
+
+func newEventsRunner(t *testing.T) *Runner {
+	t.Helper()
+	tool := bladestools.NewTool("weather", "current weather", bladestools.HandleFunc(func(context.Context, string) (string, error) {
+		return "sunny", nil
+	}))
+	agent, err := NewAgent("events-agent",
+		WithModel(eventsModel{}),
+		WithTools(tool),
+		WithOutputKey("answer"),
+	)
+	if err != nil {
+		t.Fatalf("new agent: %v", err)
+	}
+	return NewRunner(agent)
+}
+
+func TestRunnerRunEvents(t *testing.T) {
+	t.Parallel()
+
+	var (
+		types  []EventType
+		events []*Event
+		ids    = map[string]bool{}
+	)
+	for event := range newEventsRunner(t).RunEvents(context.Background(), UserMessage("weather?"), WithInvocationID("inv-events")) {
+		if event.ID == "" || ids[event.ID] {
+			t.Fatalf("event %s has a missing or duplicate ID %q", event.Type, event.ID)
+		}
+		ids[event.ID] = true
+		types = append(types, event.Type)
+		events = append(events, event)
+	}
+	if types[0] != EventInvocationStart || types[len(types)-1] != EventInvocationEnd {
+		t.Fatalf("events = %v, want invocation start ... end", types)
+	}
+	for _, want := range []EventType{
+		EventAgentEnter, EventModelRequest, EventToolCallStart, EventToolCallEnd,
+		EventTextDelta, EventStateChange, EventMessage, EventAgentExit,
+	} {
+		if !slices.Contains(types, want) {
+			t.Fatalf("events = %v, missing %s", types, want)
+		}
+	}
+	if slices.Contains(types, EventError) {
+		t.Fatalf("unexpected error event: %v", types)
+	}
+	if slices.Index(types, EventToolCallStart) > slices.Index(types, EventToolCallEnd) {
+		t.Fatalf("tool call end before start: %v", types)
+	}
+	for _, event := range events {
+		switch event.Type {
+		case EventToolCallEnd:
+			if event.Tool == nil || event.Tool.ID != "call_1" || event.Tool.Response != "sunny" {
+				t.Fatalf("tool call end = %+v", event.Tool)
+			}
+		case EventAgentEnter, EventModelRequest:
+			if event.Agent != "events-agent" || event.InvocationID != "inv-events" {
+				t.Fatalf("%s event = %+v", event.Type, event)
+			}
+		case EventStateChange:
+			if event.Key == "answer" && event.Value != "it is sunny" {
+				t.Fatalf("answer state = %v", event.Value)
+			}
+		}
+	}
+	end := events[len(events)-1]
+	if end.Err != nil || end.Message == nil || end.Message.Text() != "it is sunny" {
+		t.Fatalf("invocation end = %+v", end)
+	}
+	if end.Usage == nil || end.Usage.ModelCalls != 2 {
+		t.Fatalf("invocation end usage = %+v, want 2 model calls", end.Usage)
+	}
+}
+
+func TestRunnerRunEventsError(t *testing.T) {
+	t.Parallel()
+
+	model := &scriptedStreamingModel{streamErr: errors.New("boom")}
+	agent, err := NewAgent("failing", WithModel(model))
+	if err != nil {
+		t.Fatalf("new agent: %v", err)
+	}
+	var events []*Event
+	for event := range NewRunner(agent).RunEvents(context.Background(), UserMessage("hi")) {
+		events = append(events, event)
+	}
+	n := len(events)
+	if n < 2 || events[n-2].Type != EventError || events[n-1].Type != EventInvocationEnd {
+		t.Fatalf("last events = %v, want error then invocation end", events)
+	}
+	if events[n-1].Err == nil || events[n-1].Message != nil {
+		t.Fatalf("invocation end = %+v", events[n-1])
+	}
+}
+
+func TestRunnerRunEventsStopEarly(t *testing.T) {
+	t.Parallel()
+
+	for event := range newEventsRunner(t).RunEvents(context.Background(), UserMessage("weather?")) {
+		if event.Type == EventToolCallStart {
+			break
+		}
+	}
+}
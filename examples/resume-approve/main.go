@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -14,10 +15,7 @@ import (
 	"github.com/go-kratos/blades/tools"
 )
 
-const (
-	leaveApprovalKey    = "leave_approval"
-	defaultLeaveRequest = "My name is Alice. I need leave from 2026-03-12 to 2026-03-14 because I have a fever."
-)
+const defaultLeaveRequest = "My name is Alice. I need leave from 2026-03-12 to 2026-03-14 because I have a fever."
 
 type LeaveApprovalRequest struct {
 	EmployeeName string `json:"employee_name" jsonschema:"Employee name"`
@@ -30,60 +28,43 @@ type LeaveApprovalResult struct {
 	Approved bool `json:"approved" jsonschema:"Whether the leave request is approved"`
 }
 
-type LeaveApprovalState struct {
-	Request  LeaveApprovalRequest
-	Decision string
-}
-
 func requestLeaveApproval(ctx context.Context, req LeaveApprovalRequest) (LeaveApprovalResult, error) {
-	session, ok := blades.SessionFromContext(ctx)
-	if !ok {
-		return LeaveApprovalResult{}, blades.ErrNoSessionContext
-	}
-
-	state, _ := session.State()[leaveApprovalKey].(LeaveApprovalState)
-	state.Request = req
-	session.SetState(leaveApprovalKey, state)
-
-	if state.Decision == "" {
-		return LeaveApprovalResult{}, blades.ErrInterrupted
-	}
-
-	return LeaveApprovalResult{
-		Approved: state.Decision == "approve",
-	}, nil
+	// The tool only runs once a human has approved the call.
+	return LeaveApprovalResult{Approved: true}, nil
 }
 
-func promptApproval(session blades.Session) error {
-	state, ok := session.State()[leaveApprovalKey].(LeaveApprovalState)
-	if !ok {
-		return fmt.Errorf("pending leave request not found in session")
-	}
-	req := state.Request
+func promptApproval(pending []blades.ToolPart) ([]blades.ToolDecision, error) {
 	reader := bufio.NewReader(os.Stdin)
-
-	fmt.Println("Leave request is waiting for human approval:")
-	fmt.Printf("  Employee: %s\n", req.EmployeeName)
-	fmt.Printf("  Start:    %s\n", req.StartDate)
-	fmt.Printf("  End:      %s\n", req.EndDate)
-	fmt.Printf("  Reason:   %s\n", req.Reason)
-
-	for {
-		fmt.Print("Decision [approve/reject]: ")
-		decision, err := reader.ReadString('\n')
-		if err != nil {
-			return err
+	decisions := make([]blades.ToolDecision, 0, len(pending))
+	for _, part := range pending {
+		var req LeaveApprovalRequest
+		if err := json.Unmarshal([]byte(part.Request), &req); err != nil {
+			return nil, err
 		}
-		decision = strings.TrimSpace(decision)
-		if decision != "approve" && decision != "reject" {
+		fmt.Println("Leave request is waiting for human approval:")
+		fmt.Printf("  Employee: %s\n", req.EmployeeName)
+		fmt.Printf("  Start:    %s\n", req.StartDate)
+		fmt.Printf("  End:      %s\n", req.EndDate)
+		fmt.Printf("  Reason:   %s\n", req.Reason)
+		for {
+			fmt.Print("Decision [approve/reject]: ")
+			decision, err := reader.ReadString('\n')
+			if err != nil {
+				return nil, err
+			}
+			decision = strings.TrimSpace(decision)
+			if decision == "approve" {
+				decisions = append(decisions, blades.ApproveToolCall(part.ID))
+				break
+			}
+			if decision == "reject" {
+				decisions = append(decisions, blades.RejectToolCall(part.ID, "rejected by the manager"))
+				break
+			}
 			fmt.Println("Please enter either approve or reject.")
-			continue
 		}
-
-		state.Decision = decision
-		session.SetState(leaveApprovalKey, state)
-		return nil
 	}
+	return decisions, nil
 }
 
 func main() {
@@ -108,6 +89,7 @@ For every leave request:
 2. Call the request_leave_approval tool exactly once.
 3. After the tool returns, tell the user whether the leave was approved or rejected.`),
 		blades.WithTools(approvalTool),
+		blades.WithToolApproval(blades.RequireToolApproval("request_leave_approval")),
 	)
 	if err != nil {
 		log.Fatal(err)
@@ -125,11 +107,13 @@ For every leave request:
 		blades.WithSession(session),
 		blades.WithInvocationID(invocationID),
 	)
-	if !errors.Is(err, blades.ErrInterrupted) {
+	var interrupt *blades.InterruptError
+	if !errors.As(err, &interrupt) {
 		log.Fatal(err)
 	}
 	log.Println("leave request paused, waiting for human approval")
-	if err := promptApproval(session); err != nil {
+	decisions, err := promptApproval(interrupt.Pending)
+	if err != nil {
 		log.Fatal(err)
 	}
 	output, err = runner.Run(
@@ -138,6 +122,7 @@ For every leave request:
 		blades.WithResume(true),
 		blades.WithSession(session),
 		blades.WithInvocationID(invocationID),
+		blades.WithToolDecisions(decisions...),
 	)
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,172 @@
+package blades
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// ToolSettings configures the execution of a single tool.
+type ToolSettings struct {
+	// Timeout bounds each call of the tool, overriding the default set with
+	// WithToolTimeout. Zero uses the default.
+	Timeout time.Duration
+	// Exclusive runs each call of the tool alone: it waits for the calls in
+	// flight to finish, and no other call starts until it is done. Use it for
+	// tools that are not safe for concurrent use. The wait counts against the
+	// call's timeout, and a call abandoned on timeout stops holding up the
+	// others.
+	Exclusive bool
+}
+
+// ToolTimeoutPolicy decides the outcome of a tool call that timed out. The
+// returned response is sent to the model as the tool result; a non-nil error
+// fails the run instead.
+type ToolTimeoutPolicy func(ctx context.Context, part ToolPart, timeout time.Duration) (string, error)
+
+// ReportToolTimeout is the default ToolTimeoutPolicy: the model is told that
+// the tool timed out, so it can retry or carry on without the result.
+func ReportToolTimeout(_ context.Context, part ToolPart, timeout time.Duration) (string, error) {
+	return fmt.Sprintf("Tool error: tool %s timed out after %s", part.Name, timeout), nil
+}
+
+// FailOnToolTimeout is a ToolTimeoutPolicy that fails the run with a
+// ToolTimeoutError.
+func FailOnToolTimeout(_ context.Context, part ToolPart, timeout time.Duration) (string, error) {
+	return "", &ToolTimeoutError{ID: part.ID, Name: part.Name, Timeout: timeout}
+}
+
+// WithToolTimeout bounds every tool call to d, unless the tool has its own
+// timeout set with WithToolSettings. A timed out call is abandoned, even if
+// the tool ignores its context, and resolved by the timeout policy. By
+// default tool calls have no timeout.
+func WithToolTimeout(d time.Duration) AgentOption {
+	return func(a *agent) {
+		a.toolTimeout = d
+	}
+}
+
+// WithToolSettings configures the execution of the named tool.
+func WithToolSettings(name string, settings ToolSettings) AgentOption {
+	return func(a *agent) {
+		if a.toolSettings == nil {
+			a.toolSettings = make(map[string]ToolSettings)
+		}
+		a.toolSettings[name] = settings
+	}
+}
+
+// WithMaxToolConcurrency limits how many tool calls of a message run at the
+// same time. With 1, calls run sequentially in the order the model made them.
+// By default all calls run concurrently.
+func WithMaxToolConcurrency(n int) AgentOption {
+	return func(a *agent) {
+		a.toolConcurrency = n
+	}
+}
+
+// WithToolTimeoutPolicy sets the policy applied to tool calls that time out.
+// The default is ReportToolTimeout.
+func WithToolTimeoutPolicy(policy ToolTimeoutPolicy) AgentOption {
+	return func(a *agent) {
+		a.toolTimeoutPolicy = policy
+	}
+}
+
+// toolTimeoutFor returns the timeout of the named tool, or zero for none.
+func (a *agent) toolTimeoutFor(name string) time.Duration {
+	if settings, ok := a.toolSettings[name]; ok && settings.Timeout > 0 {
+		return settings.Timeout
+	}
+	return a.toolTimeout
+}
+
+// toolSlots lets the tool calls of a message run together, except exclusive
+// calls, which take every slot and so run alone.
+type toolSlots struct {
+	sem  *semaphore.Weighted
+	size int64
+}
+
+func newToolSlots(calls int) *toolSlots {
+	size := int64(max(calls, 1))
+	return &toolSlots{sem: semaphore.NewWeighted(size), size: size}
+}
+
+// acquire waits for the slots of a call until ctx is done, and returns the
+// function that releases them.
+func (s *toolSlots) acquire(ctx context.Context, exclusive bool) (func(), error) {
+	n := int64(1)
+	if exclusive {
+		n = s.size
+	}
+	if err := s.sem.Acquire(ctx, n); err != nil {
+		return nil, err
+	}
+	return func() { s.sem.Release(n) }, nil
+}
+
+// runTool executes a tool call within its timeout, which also bounds the wait
+// for its slot. A call that times out is abandoned: its slot is released even
+// if the tool ignores its context and keeps running. The returned bool
+// reports whether the call timed out; the result of such a call comes from
+// the timeout policy, and nothing else the tool did applies.
+func (a *agent) runTool(ctx context.Context, invocation *Invocation, part ToolPart, slots *toolSlots) (ToolPart, bool, error) {
+	timeout := a.toolTimeoutFor(part.Name)
+	toolCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		toolCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	release, err := slots.acquire(toolCtx, a.toolSettings[part.Name].Exclusive)
+	if err != nil {
+		if err := ctx.Err(); err != nil {
+			return part, false, err
+		}
+		return a.toolTimedOut(ctx, part, timeout)
+	}
+	defer release()
+	if timeout <= 0 {
+		part, err := a.callTool(ctx, invocation, part)
+		return part, false, err
+	}
+	type result struct {
+		part ToolPart
+		err  error
+	}
+	done := make(chan result, 1)
+	go func(part ToolPart) {
+		part, err := a.callTool(toolCtx, invocation, part)
+		done <- result{part, err}
+	}(part)
+	select {
+	case r := <-done:
+		if r.err == nil || ctx.Err() != nil || !errors.Is(toolCtx.Err(), context.DeadlineExceeded) {
+			return r.part, false, r.err
+		}
+	case <-toolCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return part, true, err
+		}
+	}
+	return a.toolTimedOut(ctx, part, timeout)
+}
+
+// toolTimedOut resolves a timed out call with the timeout policy.
+func (a *agent) toolTimedOut(ctx context.Context, part ToolPart, timeout time.Duration) (ToolPart, bool, error) {
+	policy := a.toolTimeoutPolicy
+	if policy == nil {
+		policy = ReportToolTimeout
+	}
+	response, err := policy(ctx, part, timeout)
+	if err != nil {
+		return part, true, err
+	}
+	part.Response = response
+	part.Completed = true
+	return part, true, nil
+}
@@ -0,0 +1,226 @@
+package blades
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	bladestools "github.com/go-kratos/blades/tools"
+)
+
+// multiToolModel calls every listed tool once, then answers with the
+// responses it received, in call order.
+type multiToolModel struct {
+	calls []string
+}
+
+func (m *multiToolModel) Name() string { return "multi-tool" }
+
+func (m *multiToolModel) Generate(_ context.Context, req *ModelRequest) (*ModelResponse, error) {
+	for _, message := range req.Messages {
+		if message.Role != RoleTool {
+			continue
+		}
+		var responses []string
+		for _, part := range message.Parts {
+			if tool, ok := part.(ToolPart); ok {
+				responses = append(responses, tool.Response)
+			}
+		}
+		return &ModelResponse{Message: AssistantMessage(strings.Join(responses, "|"))}, nil
+	}
+	msg := NewAssistantMessage(StatusCompleted)
+	msg.Role = RoleTool
+	for i, name := range m.calls {
+		msg.Parts = append(msg.Parts, NewToolPart("call_"+string(rune('a'+i)), name, "{}"))
+	}
+	return &ModelResponse{Message: msg}, nil
+}
+
+func (m *multiToolModel) NewStreaming(context.Context, *ModelRequest) Generator[*ModelResponse, error] {
+	return nil
+}
+
+// concurrencyProbe records the order of tool calls and the peak number of
+// calls running at once.
+type concurrencyProbe struct {
+	mu     sync.Mutex
+	active int
+	peak   int
+	order  []string
+	alone  map[string]bool // whether each call ran without any other
+}
+
+func (p *concurrencyProbe) tool(name string) bladestools.Tool {
+	return bladestools.NewTool(name, name, bladestools.HandleFunc(func(context.Context, string) (string, error) {
+		p.mu.Lock()
+		p.active++
+		p.peak = max(p.peak, p.active)
+		p.order = append(p.order, name)
+		alone := p.active == 1
+		p.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		p.mu.Lock()
+		alone = alone && p.active == 1
+		if p.alone == nil {
+			p.alone = make(map[string]bool)
+		}
+		p.alone[name] = alone
+		p.active--
+		p.mu.Unlock()
+		return name, nil
+	}))
+}
+
+func runToolAgent(t *testing.T, model ModelProvider, opts ...AgentOption) (*Message, error) {
+	t.Helper()
+	agent, err := NewAgent("tools", append([]AgentOption{WithModel(model)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRunner(agent).Run(context.Background(), UserMessage("go"))
+}
+
+func TestToolTimeoutIsReportedToModel(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	defer close(release)
+	// The tool ignores its context; the call is abandoned anyway.
+	hang := bladestools.NewTool("hang", "hangs", bladestools.HandleFunc(func(context.Context, string) (string, error) {
+		<-release
+		return "late", nil
+	}))
+	fast := bladestools.NewTool("fast", "fast", bladestools.HandleFunc(func(context.Context, string) (string, error) {
+		return "ok", nil
+	}))
+	output, err := runToolAgent(t, &multiToolModel{calls: []string{"hang", "fast"}},
+		WithTools(hang, fast),
+		WithToolTimeout(time.Hour),
+		WithToolSettings("hang", ToolSettings{Timeout: 20 * time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Tool error: tool hang timed out after 20ms|ok"; output.Text() != want {
+		t.Fatalf("output = %q, want %q", output.Text(), want)
+	}
+}
+
+func TestTimedOutToolReleasesItsSlot(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	defer close(release)
+	// The tool ignores its context and sets an action once it returns.
+	hang := bladestools.NewTool("hang", "hangs", bladestools.HandleFunc(func(ctx context.Context, _ string) (string, error) {
+		<-release
+		if tc, ok := bladestools.FromContext(ctx); ok {
+			tc.SetAction(bladestools.ActionLoopExit, true)
+		}
+		return "late", nil
+	}))
+	probe := bladestools.NewTool("probe", "probes", bladestools.HandleFunc(func(context.Context, string) (string, error) {
+		return "ok", nil
+	}))
+	type result struct {
+		output *Message
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		output, err := runToolAgent(t, &multiToolModel{calls: []string{"hang", "probe"}},
+			WithTools(hang, probe),
+			WithMaxToolConcurrency(1),
+			WithToolSettings("hang", ToolSettings{Timeout: 20 * time.Millisecond}),
+			WithToolSettings("probe", ToolSettings{Exclusive: true}),
+		)
+		done <- result{output, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		if want := "Tool error: tool hang timed out after 20ms|ok"; r.output.Text() != want {
+			t.Fatalf("output = %q, want %q", r.output.Text(), want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the exclusive tool waited for the abandoned call")
+	}
+}
+
+func TestExclusiveWaitCountsAgainstTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slots := newToolSlots(2)
+	hold, err := slots.acquire(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hold()
+	a := &agent{toolSettings: map[string]ToolSettings{"wait": {Timeout: 20 * time.Millisecond}}}
+	part, timedOut, err := a.runTool(ctx, &Invocation{}, NewToolPart("call_1", "wait", "{}"), slots)
+	if err != nil || !timedOut || part.Response != "Tool error: tool wait timed out after 20ms" {
+		t.Fatalf("runTool = %+v, %t, %v; want a timeout while waiting", part, timedOut, err)
+	}
+	cancel()
+	if _, _, err := a.runTool(ctx, &Invocation{}, NewToolPart("call_2", "other", "{}"), slots); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled while waiting", err)
+	}
+}
+
+func TestToolTimeoutPolicyFailsRun(t *testing.T) {
+	t.Parallel()
+
+	slow := bladestools.NewTool("slow", "slow", bladestools.HandleFunc(func(ctx context.Context, _ string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}))
+	_, err := runToolAgent(t, &multiToolModel{calls: []string{"slow"}},
+		WithTools(slow),
+		WithToolTimeout(10*time.Millisecond),
+		WithToolTimeoutPolicy(FailOnToolTimeout),
+	)
+	var timeout *ToolTimeoutError
+	if !errors.Is(err, ErrToolTimeout) || !errors.As(err, &timeout) || timeout.Name != "slow" {
+		t.Fatalf("err = %v, want a ToolTimeoutError for slow", err)
+	}
+}
+
+func TestMaxToolConcurrencyRunsSequentially(t *testing.T) {
+	t.Parallel()
+
+	probe := &concurrencyProbe{}
+	output, err := runToolAgent(t, &multiToolModel{calls: []string{"a", "b", "c"}},
+		WithTools(probe.tool("a"), probe.tool("b"), probe.tool("c")),
+		WithMaxToolConcurrency(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if probe.peak != 1 || strings.Join(probe.order, "") != "abc" || output.Text() != "a|b|c" {
+		t.Fatalf("peak = %d, order = %v, output = %q", probe.peak, probe.order, output.Text())
+	}
+}
+
+func TestExclusiveToolRunsAlone(t *testing.T) {
+	t.Parallel()
+
+	probe := &concurrencyProbe{}
+	_, err := runToolAgent(t, &multiToolModel{calls: []string{"a", "b", "unsafe", "c"}},
+		WithTools(probe.tool("a"), probe.tool("b"), probe.tool("c"), probe.tool("unsafe")),
+		WithToolSettings("unsafe", ToolSettings{Exclusive: true}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !probe.alone["unsafe"] {
+		t.Fatal("the exclusive tool overlapped with other calls")
+	}
+}
@@ -0,0 +1,30 @@
+package flow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/blades"
+)
+
+func TestSequentialAgentUsageIsAggregated(t *testing.T) {
+	t.Parallel()
+
+	var subAgents []blades.Agent
+	for _, name := range []string{"first", "second"} {
+		agent, err := blades.NewAgent(name, blades.WithModel(&echoModel{name: name, text: name}))
+		if err != nil {
+			t.Fatalf("new agent: %v", err)
+		}
+		subAgents = append(subAgents, agent)
+	}
+	runner := blades.NewRunner(NewSequentialAgent(SequentialConfig{Name: "seq", SubAgents: subAgents}))
+	output, err := runner.Run(context.Background(), blades.UserMessage("go"))
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	report, ok := blades.MessageUsage(output)
+	if !ok || report.ModelCalls != 2 {
+		t.Fatalf("usage report = %+v, want 2 model calls across sub-agents", report)
+	}
+}
@@ -0,0 +1,109 @@
+package blades
+
+import "slices"
+
+// Names of the GenerationConfig fields, as reported by
+// UnsupportedGenerationError.
+const (
+	GenerationTemperature      = "temperature"
+	GenerationTopP             = "topP"
+	GenerationTopK             = "topK"
+	GenerationMaxOutputTokens  = "maxOutputTokens"
+	GenerationStopSequences    = "stopSequences"
+	GenerationSeed             = "seed"
+	GenerationFrequencyPenalty = "frequencyPenalty"
+	GenerationPresencePenalty  = "presencePenalty"
+)
+
+// GenerationConfig holds provider-neutral settings for generating a single
+// response. Providers merge it over their static configuration: a set field
+// takes precedence, a nil or empty field keeps the provider's setting. A
+// provider that cannot honor a set field returns an UnsupportedGenerationError
+// instead of ignoring it.
+type GenerationConfig struct {
+	// Temperature controls randomness; lower values are more deterministic.
+	Temperature *float64 `json:"temperature,omitempty"`
+	// TopP limits sampling to the most likely tokens whose probabilities add
+	// up to TopP.
+	TopP *float64 `json:"topP,omitempty"`
+	// TopK limits sampling to the TopK most likely tokens.
+	TopK *int64 `json:"topK,omitempty"`
+	// MaxOutputTokens caps the number of tokens generated.
+	MaxOutputTokens *int64 `json:"maxOutputTokens,omitempty"`
+	// StopSequences stop generation when produced.
+	StopSequences []string `json:"stopSequences,omitempty"`
+	// Seed makes sampling repeatable where the provider supports it.
+	Seed *int64 `json:"seed,omitempty"`
+	// FrequencyPenalty penalizes tokens by how often they already appeared.
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+	// PresencePenalty penalizes tokens that already appeared.
+	PresencePenalty *float64 `json:"presencePenalty,omitempty"`
+}
+
+// Ptr returns a pointer to v, for setting the fields of a GenerationConfig.
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// Clone returns a copy of the config that shares no memory with it.
+func (c *GenerationConfig) Clone() *GenerationConfig {
+	if c == nil {
+		return nil
+	}
+	return &GenerationConfig{
+		Temperature:      clonePtr(c.Temperature),
+		TopP:             clonePtr(c.TopP),
+		TopK:             clonePtr(c.TopK),
+		MaxOutputTokens:  clonePtr(c.MaxOutputTokens),
+		StopSequences:    slices.Clone(c.StopSequences),
+		Seed:             clonePtr(c.Seed),
+		FrequencyPenalty: clonePtr(c.FrequencyPenalty),
+		PresencePenalty:  clonePtr(c.PresencePenalty),
+	}
+}
+
+// Fields returns the names of the fields set in the config.
+func (c *GenerationConfig) Fields() []string {
+	if c == nil {
+		return nil
+	}
+	var fields []string
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{GenerationTemperature, c.Temperature != nil},
+		{GenerationTopP, c.TopP != nil},
+		{GenerationTopK, c.TopK != nil},
+		{GenerationMaxOutputTokens, c.MaxOutputTokens != nil},
+		{GenerationStopSequences, len(c.StopSequences) > 0},
+		{GenerationSeed, c.Seed != nil},
+		{GenerationFrequencyPenalty, c.FrequencyPenalty != nil},
+		{GenerationPresencePenalty, c.PresencePenalty != nil},
+	} {
+		if f.set {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+// Unsupported returns the names of the fields set in the config that are not
+// among the supported ones. Providers use it to report what they cannot honor.
+func (c *GenerationConfig) Unsupported(supported ...string) []string {
+	var unsupported []string
+	for _, field := range c.Fields() {
+		if !slices.Contains(supported, field) {
+			unsupported = append(unsupported, field)
+		}
+	}
+	return unsupported
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
@@ -0,0 +1,74 @@
+package blades
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	bladestools "github.com/go-kratos/blades/tools"
+)
+
+// generationModel records the generation settings of every request.
+type generationModel struct {
+	multiToolModel
+	generations []*GenerationConfig
+}
+
+func (m *generationModel) Generate(ctx context.Context, req *ModelRequest) (*ModelResponse, error) {
+	m.generations = append(m.generations, req.Generation.Clone())
+	return m.multiToolModel.Generate(ctx, req)
+}
+
+func TestAgentSendsGenerationConfig(t *testing.T) {
+	t.Parallel()
+
+	noop := bladestools.NewTool("noop", "does nothing", bladestools.HandleFunc(func(context.Context, string) (string, error) {
+		return "ok", nil
+	}))
+	model := &generationModel{multiToolModel: multiToolModel{calls: []string{"noop"}}}
+	// Drafting after the tool call uses a higher temperature.
+	draft := ModelHooks{BeforeModel: func(_ context.Context, req *ModelRequest) (*ModelResponse, error) {
+		if len(req.Messages) > 1 {
+			req.Generation.Temperature = Ptr(0.9)
+		}
+		return nil, nil
+	}}
+	for range 2 {
+		if _, err := runToolAgent(t, model, WithTools(noop), WithModelMiddleware(draft),
+			WithGenerationConfig(GenerationConfig{Temperature: Ptr(0.2), StopSequences: []string{"END"}})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var temperatures []float64
+	for _, generation := range model.generations {
+		if generation == nil || generation.Temperature == nil || !slices.Equal(generation.StopSequences, []string{"END"}) {
+			t.Fatalf("generation = %+v", generation)
+		}
+		temperatures = append(temperatures, *generation.Temperature)
+	}
+	if want := []float64{0.2, 0.9, 0.2, 0.9}; !slices.Equal(temperatures, want) {
+		t.Fatalf("temperatures = %v, want %v", temperatures, want)
+	}
+}
+
+func TestGenerationConfigUnsupported(t *testing.T) {
+	t.Parallel()
+
+	config := &GenerationConfig{Temperature: Ptr(0.0), Seed: Ptr(int64(7)), StopSequences: []string{"END"}}
+	if got, want := config.Fields(), []string{GenerationTemperature, GenerationStopSequences, GenerationSeed}; !slices.Equal(got, want) {
+		t.Fatalf("fields = %v, want %v", got, want)
+	}
+	unsupported := config.Unsupported(GenerationTemperature, GenerationStopSequences)
+	if want := []string{GenerationSeed}; !slices.Equal(unsupported, want) {
+		t.Fatalf("unsupported = %v, want %v", unsupported, want)
+	}
+	var nilConfig *GenerationConfig
+	if fields := nilConfig.Unsupported(); len(fields) != 0 {
+		t.Fatalf("nil config unsupported = %v", fields)
+	}
+	err := error(&UnsupportedGenerationError{Provider: "test", Fields: unsupported})
+	if !errors.Is(err, ErrUnsupportedGeneration) || err.Error() != "unsupported generation config: test does not support seed" {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package blades
+
+import "context"
+
+// ModelHooks intercept every model call made by an Agent, on every iteration
+// of its loop. Either function may be nil.
+type ModelHooks struct {
+	// BeforeModel runs before a request is sent and may modify it. Returning a
+	// non-nil response skips the model call and uses that response instead;
+	// it must hold a completed message.
+	BeforeModel func(ctx context.Context, req *ModelRequest) (*ModelResponse, error)
+	// AfterModel runs on each completed response, including short-circuited
+	// ones, and returns the response the Agent continues with. While
+	// streaming, it runs on the final chunk only.
+	AfterModel func(ctx context.Context, req *ModelRequest, res *ModelResponse) (*ModelResponse, error)
+}
+
+// ToolCallHooks intercept every tool call executed by an Agent. Either
+// function may be nil.
+type ToolCallHooks struct {
+	// BeforeTool runs before a tool is executed and may rewrite the call, for
+	// example its arguments. Returning a part marked Completed skips the tool
+	// and uses the part's Response as the result, which can veto or mock a call.
+	BeforeTool func(ctx context.Context, part ToolPart) (ToolPart, error)
+	// AfterTool runs after the tool call completed and may rewrite its response.
+	AfterTool func(ctx context.Context, part ToolPart) (ToolPart, error)
+}
+
+// beforeModel runs the BeforeModel hooks in order until one short-circuits.
+func (a *agent) beforeModel(ctx context.Context, req *ModelRequest) (*ModelResponse, error) {
+	for _, hooks := range a.modelHooks {
+		if hooks.BeforeModel == nil {
+			continue
+		}
+		res, err := hooks.BeforeModel(ctx, req)
+		if err != nil || res != nil {
+			return res, err
+		}
+	}
+	return nil, nil
+}
+
+// afterModel runs the AfterModel hooks in reverse order, so the first hooks
+// see the response last, like the outermost middleware.
+func (a *agent) afterModel(ctx context.Context, req *ModelRequest, res *ModelResponse) (*ModelResponse, error) {
+	for i := len(a.modelHooks) - 1; i >= 0; i-- {
+		if after := a.modelHooks[i].AfterModel; after != nil {
+			var err error
+			if res, err = after(ctx, req, res); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return res, nil
+}
+
+// generate performs a non-streaming model call through the model hooks.
+func (a *agent) generate(ctx context.Context, invocation *Invocation, req *ModelRequest) (*ModelResponse, error) {
+	res, err := a.beforeModel(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		sent := *req
+		emitEvent(ctx, &Event{Type: EventModelRequest, InvocationID: invocation.ID, Request: &sent})
+		if res, err = a.model.Generate(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	return a.afterModel(ctx, req, res)
+}
+
+// newStreaming performs a streaming model call through the model hooks.
+func (a *agent) newStreaming(ctx context.Context, invocation *Invocation, req *ModelRequest) Generator[*ModelResponse, error] {
+	return func(yield func(*ModelResponse, error) bool) {
+		res, err := a.beforeModel(ctx, req)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if res != nil {
+			yield(a.afterModel(ctx, req, res))
+			return
+		}
+		sent := *req
+		emitEvent(ctx, &Event{Type: EventModelRequest, InvocationID: invocation.ID, Request: &sent})
+		for res, err := range a.model.NewStreaming(ctx, req) {
+			if err == nil && res != nil && res.Message != nil && res.Message.Status == StatusCompleted {
+				res, err = a.afterModel(ctx, req, res)
+			}
+			if !yield(res, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// callTool executes a tool call through the tool call hooks.
+func (a *agent) callTool(ctx context.Context, invocation *Invocation, part ToolPart) (ToolPart, error) {
+	for _, hooks := range a.toolHooks {
+		if hooks.BeforeTool == nil {
+			continue
+		}
+		var err error
+		if part, err = hooks.BeforeTool(ctx, part); err != nil {
+			return part, err
+		}
+		if part.Completed {
+			break
+		}
+	}
+	if !part.Completed {
+		var err error
+		if part, err = a.handleTools(ctx, invocation, part); err != nil {
+			return part, err
+		}
+		part.Completed = true
+	}
+	for i := len(a.toolHooks) - 1; i >= 0; i-- {
+		if after := a.toolHooks[i].AfterTool; after != nil {
+			var err error
+			if part, err = after(ctx, part); err != nil {
+				return part, err
+			}
+		}
+	}
+	part.Completed = true
+	return part, nil
+}
@@ -0,0 +1,130 @@
+package blades
+
+import (
+	"context"
+	"testing"
+
+	bladestools "github.com/go-kratos/blades/tools"
+)
+
+func newHookedAgent(t *testing.T, model ModelProvider, calls *int, opts ...AgentOption) *Runner {
+	t.Helper()
+	tool := bladestools.NewTool("delete_file", "delete a file", bladestools.HandleFunc(func(context.Context, string) (string, error) {
+		*calls++
+		return "deleted", nil
+	}))
+	agent, err := NewAgent("hooked", append(opts, WithModel(model), WithTools(tool))...)
+	if err != nil {
+		t.Fatalf("new agent: %v", err)
+	}
+	return NewRunner(agent)
+}
+
+func TestModelHooksRunEveryIteration(t *testing.T) {
+	t.Parallel()
+
+	var (
+		calls  int
+		before int
+		after  int
+		model  = &approvalModel{}
+	)
+	runner := newHookedAgent(t, model, &calls, WithModelMiddleware(ModelHooks{
+		BeforeModel: func(_ context.Context, req *ModelRequest) (*ModelResponse, error) {
+			before++
+			req.Messages = append(req.Messages, SystemMessage("be brief"))
+			return nil, nil
+		},
+		AfterModel: func(_ context.Context, _ *ModelRequest, res *ModelResponse) (*ModelResponse, error) {
+			after++
+			if res.Message.Role == RoleAssistant {
+				res.Message.Parts = []Part{TextPart{Text: "rewritten: " + res.Message.Text()}}
+			}
+			return res, nil
+		},
+	}))
+	output, err := runner.Run(context.Background(), UserMessage("delete a.txt"))
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if before != 2 || after != 2 {
+		t.Fatalf("hooks ran before=%d after=%d times, want 2 each", before, after)
+	}
+	if got, want := output.Text(), "rewritten: result: deleted"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	for _, req := range model.requests {
+		if last := req.Messages[len(req.Messages)-1]; last.Text() != "be brief" {
+			t.Fatalf("request was not rewritten: %v", req.Messages)
+		}
+	}
+}
+
+func TestModelHooksShortCircuit(t *testing.T) {
+	t.Parallel()
+
+	var (
+		calls int
+		model = &approvalModel{}
+	)
+	runner := newHookedAgent(t, model, &calls, WithModelMiddleware(ModelHooks{
+		BeforeModel: func(context.Context, *ModelRequest) (*ModelResponse, error) {
+			return &ModelResponse{Message: AssistantMessage("cached")}, nil
+		},
+	}))
+	output, err := runner.Run(context.Background(), UserMessage("delete a.txt"))
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if output.Text() != "cached" || len(model.requests) != 0 {
+		t.Fatalf("output = %q, model requests = %d", output.Text(), len(model.requests))
+	}
+}
+
+func TestToolCallHooks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		hooks     ToolCallHooks
+		wantCalls int
+		want      string
+	}{
+		{
+			name: "veto",
+			hooks: ToolCallHooks{BeforeTool: func(_ context.Context, part ToolPart) (ToolPart, error) {
+				part.Response = "vetoed"
+				part.Completed = true
+				return part, nil
+			}},
+			want: "result: vetoed",
+		},
+		{
+			name: "rewrite result",
+			hooks: ToolCallHooks{AfterTool: func(_ context.Context, part ToolPart) (ToolPart, error) {
+				part.Response += " (audited)"
+				return part, nil
+			}},
+			wantCalls: 1,
+			want:      "result: deleted (audited)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls int
+			runner := newHookedAgent(t, &approvalModel{}, &calls, WithToolCallMiddleware(tt.hooks))
+			output, err := runner.Run(context.Background(), UserMessage("delete a.txt"))
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if calls != tt.wantCalls {
+				t.Fatalf("tool calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if got := output.Text(); got != tt.want {
+				t.Fatalf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
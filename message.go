@@ -1,6 +1,8 @@
 package blades
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
 	"slices"
@@ -38,6 +40,23 @@ const (
 // TextPart is plain text content.
 type TextPart struct {
 	Text string `json:"text"`
+	// Annotations attribute spans of Text to sources, such as the citations
+	// a model returns for a grounded answer.
+	Annotations []Annotation `json:"annotations,omitempty"`
+}
+
+// Annotation attributes a span of a TextPart to a source.
+type Annotation struct {
+	// Start and End are the byte offsets of the annotated span in the text.
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// SourceID identifies the source within the response or request, such as
+	// the index of a cited document or of a grounding chunk.
+	SourceID string `json:"sourceId,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Title    string `json:"title,omitempty"`
+	// CitedText is the quoted source text, when the provider returns it.
+	CitedText string `json:"citedText,omitempty"`
 }
 
 // FilePart is a reference to a file by its URI.
@@ -72,21 +91,66 @@ func NewToolPart(id, name, request string) ToolPart {
 	}
 }
 
+// RefusalPart is an explanation from the model for declining to answer.
+// It is not included in Message.Text.
+type RefusalPart struct {
+	Text string `json:"text"`
+}
+
+// ReasoningPart is the reasoning (thinking) the model produced before its
+// answer. It is not included in Message.Text. Providers that verify reasoning
+// sent back on later turns return an opaque Signature with it; redacted
+// reasoning has no Text and is carried encrypted in Signature.
+type ReasoningPart struct {
+	Text      string `json:"text,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Redacted  bool   `json:"redacted,omitempty"`
+	// Provider names the API that produced the reasoning. Signatures are only
+	// valid for that API, so providers drop reasoning produced by others.
+	Provider string `json:"provider,omitempty"`
+}
+
+// FinishReasonRefusal is the Message.FinishReason of a response in which the
+// model refused to answer; the message holds a RefusalPart.
+const FinishReasonRefusal = "refusal"
+
+// UnknownPart holds a part whose type is not recognized by this version of
+// blades. It keeps the raw JSON so the part survives a decode/encode round trip.
+type UnknownPart struct {
+	Type string          `json:"type"`
+	Raw  json.RawMessage `json:"-"`
+}
+
 // Part is a part of a message, which can be text or a file.
 type Part interface {
 	isPart()
 }
 
-func (TextPart) isPart() {}
-func (FilePart) isPart() {}
-func (DataPart) isPart() {}
-func (ToolPart) isPart() {}
+func (TextPart) isPart()      {}
+func (FilePart) isPart()      {}
+func (DataPart) isPart()      {}
+func (ToolPart) isPart()      {}
+func (RefusalPart) isPart()   {}
+func (ReasoningPart) isPart() {}
+func (UnknownPart) isPart()   {}
+
+// Part type discriminators used by the JSON encoding of Message.
+const (
+	partTypeText      = "text"
+	partTypeFile      = "file"
+	partTypeData      = "data"
+	partTypeTool      = "tool"
+	partTypeRefusal   = "refusal"
+	partTypeReasoning = "reasoning"
+)
 
 // TokenUsage tracks token consumption for a message.
 type TokenUsage struct {
 	InputTokens  int64 `json:"inputTokens"`
 	OutputTokens int64 `json:"outputTokens"`
 	TotalTokens  int64 `json:"totalTokens"`
+	// CachedInputTokens is the part of InputTokens served from the provider's prompt cache.
+	CachedInputTokens int64 `json:"cachedInputTokens,omitempty"`
 }
 
 // Message represents a single message in a conversation.
@@ -143,6 +207,12 @@ func (m *Message) Clone() *Message {
 	}
 	c := *m
 	c.Parts = slices.Clone(m.Parts)
+	for i, part := range c.Parts {
+		if text, ok := part.(TextPart); ok && text.Annotations != nil {
+			text.Annotations = slices.Clone(text.Annotations)
+			c.Parts[i] = text
+		}
+	}
 	if m.Actions != nil {
 		c.Actions = maps.Clone(m.Actions)
 	}
@@ -152,6 +222,135 @@ func (m *Message) Clone() *Message {
 	return &c
 }
 
+// MarshalJSON encodes the message, tagging every part with a "type" field.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message Message
+	parts := make([]json.RawMessage, 0, len(m.Parts))
+	for _, part := range m.Parts {
+		if part == nil {
+			continue
+		}
+		data, err := marshalPart(part)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, data)
+	}
+	return json.Marshal(struct {
+		message
+		Parts []json.RawMessage `json:"parts"`
+	}{message(m), parts})
+}
+
+// UnmarshalJSON decodes a message encoded by MarshalJSON. Parts with an
+// unrecognized type are kept as UnknownPart.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type message Message
+	var v struct {
+		message
+		Parts []json.RawMessage `json:"parts"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*m = Message(v.message)
+	m.Parts = nil
+	if len(v.Parts) > 0 {
+		m.Parts = make([]Part, 0, len(v.Parts))
+	}
+	for _, raw := range v.Parts {
+		part, err := unmarshalPart(raw)
+		if err != nil {
+			return err
+		}
+		m.Parts = append(m.Parts, part)
+	}
+	return nil
+}
+
+func marshalPart(part Part) ([]byte, error) {
+	switch v := part.(type) {
+	case TextPart:
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			TextPart
+		}{partTypeText, v})
+	case FilePart:
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			FilePart
+		}{partTypeFile, v})
+	case DataPart:
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			DataPart
+		}{partTypeData, v})
+	case ToolPart:
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			ToolPart
+		}{partTypeTool, v})
+	case RefusalPart:
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			RefusalPart
+		}{partTypeRefusal, v})
+	case ReasoningPart:
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			ReasoningPart
+		}{partTypeReasoning, v})
+	case UnknownPart:
+		if len(v.Raw) == 0 {
+			return json.Marshal(struct {
+				Type string `json:"type"`
+			}{v.Type})
+		}
+		return v.Raw, nil
+	default:
+		return nil, fmt.Errorf("blades: cannot marshal part of type %T", part)
+	}
+}
+
+func unmarshalPart(data json.RawMessage) (Part, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	switch header.Type {
+	case partTypeText:
+		var v TextPart
+		err := json.Unmarshal(data, &v)
+		return v, err
+	case partTypeFile:
+		var v FilePart
+		err := json.Unmarshal(data, &v)
+		return v, err
+	case partTypeData:
+		var v DataPart
+		err := json.Unmarshal(data, &v)
+		return v, err
+	case partTypeTool:
+		var v ToolPart
+		err := json.Unmarshal(data, &v)
+		return v, err
+	case partTypeRefusal:
+		var v RefusalPart
+		err := json.Unmarshal(data, &v)
+		return v, err
+	case partTypeReasoning:
+		var v ReasoningPart
+		err := json.Unmarshal(data, &v)
+		return v, err
+	case "":
+		return nil, errors.New("blades: message part is missing its type")
+	default:
+		return UnknownPart{Type: header.Type, Raw: slices.Clone(data)}, nil
+	}
+}
+
 func (m *Message) String() string {
 	var buf strings.Builder
 	for _, part := range m.Parts {
@@ -164,6 +363,12 @@ func (m *Message) String() string {
 			buf.WriteString("[Data: " + v.Name + " (" + string(v.MIMEType) + "), " + fmt.Sprintf("%d bytes", len(v.Bytes)) + "]")
 		case ToolPart:
 			buf.WriteString("[Tool: " + v.Name + " (Request: " + v.Request + ", Response: " + v.Response + ")]")
+		case RefusalPart:
+			buf.WriteString("[Refusal: " + v.Text + "]")
+		case ReasoningPart:
+			buf.WriteString("[Reasoning: " + v.Text + "]")
+		case UnknownPart:
+			buf.WriteString("[Unknown: " + v.Type + "]")
 		}
 	}
 	return buf.String()
@@ -207,7 +412,7 @@ func NewMessageParts(inputs ...any) []Part {
 	for _, input := range inputs {
 		switch v := any(input).(type) {
 		case string:
-			parts = append(parts, TextPart{v})
+			parts = append(parts, TextPart{Text: v})
 		case TextPart:
 			parts = append(parts, v)
 		case FilePart:
@@ -216,6 +421,10 @@ func NewMessageParts(inputs ...any) []Part {
 			parts = append(parts, v)
 		case ToolPart:
 			parts = append(parts, v)
+		case RefusalPart:
+			parts = append(parts, v)
+		case ReasoningPart:
+			parts = append(parts, v)
 		}
 	}
 	return parts
@@ -1,6 +1,11 @@
 package blades
 
-import "testing"
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestMergeParts(t *testing.T) {
 	t.Parallel()
@@ -25,3 +30,131 @@ func TestMergeParts(t *testing.T) {
 		}
 	})
 }
+
+func TestMessageJSONRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	msg := NewAssistantMessage(StatusCompleted)
+	msg.Author = "agent"
+	msg.InvocationID = "inv-1"
+	msg.FinishReason = "stop"
+	msg.TokenUsage = TokenUsage{InputTokens: 3, OutputTokens: 5, TotalTokens: 8}
+	msg.Actions["done"] = true
+	msg.Metadata["k"] = "v"
+	msg.Parts = []Part{
+		TextPart{Text: "hello", Annotations: []Annotation{{Start: 0, End: 5, SourceID: "0", URL: "https://example.com", Title: "Example", CitedText: "hi"}}},
+		FilePart{Name: "doc", URI: "file:///tmp/doc.txt", MIMEType: MIMEText},
+		DataPart{Name: "img", Bytes: []byte{0x89, 0x50}, MIMEType: MIMEImagePNG},
+		ToolPart{ID: "call_1", Name: "lookup", Request: `{"q":"x"}`, Response: `{"ok":true}`, Completed: true},
+		RefusalPart{Text: "cannot help"},
+		ReasoningPart{Text: "think", Signature: "sig", Provider: "anthropic"},
+		ReasoningPart{Signature: "encrypted", Redacted: true},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var got Message
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(&got, msg) {
+		t.Fatalf("round trip mismatch:\n got: %#v\nwant: %#v", &got, msg)
+	}
+}
+
+func TestMessageJSONPartDiscriminator(t *testing.T) {
+	t.Parallel()
+
+	data, err := json.Marshal(UserMessage("hi", ToolPart{ID: "1", Name: "t", Request: "{}"}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var raw struct {
+		Parts []map[string]any `json:"parts"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("unmarshal raw: %v", err)
+	}
+	if got, want := len(raw.Parts), 2; got != want {
+		t.Fatalf("parts len = %d, want %d", got, want)
+	}
+	if got := raw.Parts[0]["type"]; got != "text" {
+		t.Fatalf("part[0].type = %v, want text", got)
+	}
+	if got := raw.Parts[1]["type"]; got != "tool" {
+		t.Fatalf("part[1].type = %v, want tool", got)
+	}
+	if got := raw.Parts[1]["arguments"]; got != "{}" {
+		t.Fatalf("part[1].arguments = %v, want {}", got)
+	}
+}
+
+func TestMessageJSONUnknownPart(t *testing.T) {
+	t.Parallel()
+
+	input := `{"id":"m1","role":"assistant","author":"a","status":"completed","parts":[` +
+		`{"type":"text","text":"before"},` +
+		`{"type":"hologram","frames":[1,2,3]}]}`
+	var msg Message
+	if err := json.Unmarshal([]byte(input), &msg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	unknown, ok := msg.Parts[1].(UnknownPart)
+	if !ok {
+		t.Fatalf("part type = %T, want UnknownPart", msg.Parts[1])
+	}
+	if unknown.Type != "hologram" {
+		t.Fatalf("unknown type = %q, want hologram", unknown.Type)
+	}
+	if got, want := msg.Text(), "before"; got != want {
+		t.Fatalf("text = %q, want %q", got, want)
+	}
+
+	data, err := json.Marshal(&msg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `{"type":"hologram","frames":[1,2,3]}`) {
+		t.Fatalf("unknown part not preserved: %s", data)
+	}
+}
+
+func TestMessageJSONMissingPartType(t *testing.T) {
+	t.Parallel()
+
+	var msg Message
+	if err := json.Unmarshal([]byte(`{"id":"m1","parts":[{"text":"x"}]}`), &msg); err == nil {
+		t.Fatal("expected error for part without type")
+	}
+}
+
+func TestMessageCloneCopiesAnnotations(t *testing.T) {
+	t.Parallel()
+
+	msg := AssistantMessage(TextPart{Text: "grounded", Annotations: []Annotation{{End: 8, URL: "https://example.com"}}})
+	clone := msg.Clone()
+	clone.Parts[0].(TextPart).Annotations[0].URL = "changed"
+	if got := msg.Parts[0].(TextPart).Annotations[0].URL; got != "https://example.com" {
+		t.Fatalf("original annotation URL = %q, clone shares annotations", got)
+	}
+}
+
+func TestMessageTextExcludesReasoning(t *testing.T) {
+	t.Parallel()
+
+	msg := AssistantMessage(ReasoningPart{Text: "let me think"}, "answer")
+	if got, want := msg.Text(), "answer"; got != want {
+		t.Fatalf("text = %q, want %q", got, want)
+	}
+}
+
+func TestMessageTextExcludesRefusal(t *testing.T) {
+	t.Parallel()
+
+	msg := AssistantMessage("partial", RefusalPart{Text: "cannot help"})
+	if got, want := msg.Text(), "partial"; got != want {
+		t.Fatalf("text = %q, want %q", got, want)
+	}
+}
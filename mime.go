@@ -13,6 +13,7 @@ const (
 	MIMEImagePNG  MIMEType = "image/png"
 	MIMEImageJPEG MIMEType = "image/jpeg"
 	MIMEImageWEBP MIMEType = "image/webp"
+	MIMEImageGIF  MIMEType = "image/gif"
 	// Common audio mime types (non-exhaustive).
 	MIMEAudioWAV  MIMEType = "audio/wav"
 	MIMEAudioMP3  MIMEType = "audio/mpeg"
@@ -24,6 +25,12 @@ const (
 	// Common video mime types (non-exhaustive).
 	MIMEVideoMP4 MIMEType = "video/mp4"
 	MIMEVideoOGG MIMEType = "video/ogg"
+	// Document mime types.
+	MIMEPDF  MIMEType = "application/pdf"
+	MIMECSV  MIMEType = "text/csv"
+	MIMEDOCX MIMEType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	MIMEXLSX MIMEType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	MIMEPPTX MIMEType = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
 )
 
 // Type returns the general type of the MIMEType (e.g., "image", "audio", "video", or "file").
@@ -41,6 +48,12 @@ func (m MIMEType) Type() string {
 	}
 }
 
+// IsText reports whether content of the MIMEType is text, such as plain
+// text, markdown or CSV, which providers can read without decoding a file.
+func (m MIMEType) IsText() bool {
+	return strings.HasPrefix(string(m), "text/")
+}
+
 // Format returns the file format associated with the MIMEType.
 func (m MIMEType) Format() string {
 	parts := strings.SplitN(string(m), "/", 2)
@@ -14,6 +14,11 @@ type ModelRequest struct {
 	Instruction  *Message           `json:"instruction,omitempty"`
 	InputSchema  *jsonschema.Schema `json:"inputSchema,omitempty"`
 	OutputSchema *jsonschema.Schema `json:"outputSchema,omitempty"`
+	// Generation overrides the provider's generation settings for this request.
+	Generation *GenerationConfig `json:"generation,omitempty"`
+	// ToolChoice controls which tools the model may call; nil leaves the
+	// choice to the provider's default. Providers ignore it without Tools.
+	ToolChoice *ToolChoice `json:"toolChoice,omitempty"`
 }
 
 // ModelResponse is a single assistant message as a result of generation.
@@ -30,3 +35,61 @@ type ModelProvider interface {
 	// NewStreaming executes the request and returns a stream of assistant responses.
 	NewStreaming(context.Context, *ModelRequest) Generator[*ModelResponse, error]
 }
+
+// ModelMiddleware wraps a ModelProvider and returns a ModelProvider with
+// additional behavior, such as retries or logging, around each model call.
+type ModelMiddleware func(ModelProvider) ModelProvider
+
+// ChainModelMiddlewares composes model middlewares into one, applying them in
+// order. The first middleware becomes the outermost wrapper.
+func ChainModelMiddlewares(mws ...ModelMiddleware) ModelMiddleware {
+	return func(next ModelProvider) ModelProvider {
+		m := next
+		for i := len(mws) - 1; i >= 0; i-- { // apply in reverse to make mws[0] outermost
+			m = mws[i](m)
+		}
+		return m
+	}
+}
+
+// WrapModel applies the middlewares to model, the first being the outermost.
+func WrapModel(model ModelProvider, mws ...ModelMiddleware) ModelProvider {
+	return ChainModelMiddlewares(mws...)(model)
+}
+
+// GenerateFunc is the signature of ModelProvider.Generate.
+type GenerateFunc func(context.Context, *ModelRequest) (*ModelResponse, error)
+
+// StreamingFunc is the signature of ModelProvider.NewStreaming.
+type StreamingFunc func(context.Context, *ModelRequest) Generator[*ModelResponse, error]
+
+// NewModelWrapper returns a ModelProvider with the name of next whose Generate
+// and NewStreaming are replaced by the given functions. A nil function
+// delegates to next, so a middleware can wrap only one of the two calls.
+func NewModelWrapper(next ModelProvider, generate GenerateFunc, streaming StreamingFunc) ModelProvider {
+	if generate == nil {
+		generate = next.Generate
+	}
+	if streaming == nil {
+		streaming = next.NewStreaming
+	}
+	return &modelWrapper{next: next, generate: generate, streaming: streaming}
+}
+
+type modelWrapper struct {
+	next      ModelProvider
+	generate  GenerateFunc
+	streaming StreamingFunc
+}
+
+func (m *modelWrapper) Name() string {
+	return m.next.Name()
+}
+
+func (m *modelWrapper) Generate(ctx context.Context, req *ModelRequest) (*ModelResponse, error) {
+	return m.generate(ctx, req)
+}
+
+func (m *modelWrapper) NewStreaming(ctx context.Context, req *ModelRequest) Generator[*ModelResponse, error] {
+	return m.streaming(ctx, req)
+}
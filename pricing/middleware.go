@@ -0,0 +1,110 @@
+package pricing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-kratos/blades"
+	"github.com/go-kratos/blades/provider"
+)
+
+// Middleware returns a model middleware that annotates every completed model
+// message with its estimated cost under MetadataKeyCost and MetadataKeyCurrency,
+// and adds the cost to the session total when a session is in the context.
+// The price is looked up by the name of the model that served the response,
+// as recorded by provider.FallbackModel, or else by the wrapped model's name;
+// unpriced models are left untouched. Responses served from provider.Cache
+// are annotated with a zero cost. While streaming, only the final chunk is
+// annotated.
+func Middleware(r *Registry) blades.ModelMiddleware {
+	// mu serializes the read-modify-write of session cost totals.
+	var mu sync.Mutex
+	return func(next blades.ModelProvider) blades.ModelProvider {
+		annotate := func(ctx context.Context, res *blades.ModelResponse) {
+			if res == nil || res.Message == nil {
+				return
+			}
+			cost, ok := r.Cost(servedBy(res.Message, next), res.Message.TokenUsage)
+			if !ok {
+				return
+			}
+			// A response replayed by provider.Cache keeps the usage of the
+			// original call but costs nothing.
+			cached := res.Message.Metadata[provider.MetadataKeyCacheHit] == true
+			if cached {
+				cost = 0
+			}
+			if res.Message.Metadata == nil {
+				res.Message.Metadata = make(map[string]any)
+			}
+			res.Message.Metadata[MetadataKeyCost] = cost
+			res.Message.Metadata[MetadataKeyCurrency] = r.Currency
+			if session, ok := blades.SessionFromContext(ctx); ok && !cached {
+				mu.Lock()
+				session.SetState(StateKeyCost, SessionCost(session)+cost)
+				mu.Unlock()
+			}
+		}
+		generate := func(ctx context.Context, req *blades.ModelRequest) (*blades.ModelResponse, error) {
+			res, err := next.Generate(ctx, req)
+			if err == nil {
+				annotate(ctx, res)
+			}
+			return res, err
+		}
+		streaming := func(ctx context.Context, req *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
+			return func(yield func(*blades.ModelResponse, error) bool) {
+				for res, err := range next.NewStreaming(ctx, req) {
+					if err == nil && res != nil && res.Message != nil && res.Message.Status == blades.StatusCompleted {
+						annotate(ctx, res)
+					}
+					if !yield(res, err) || err != nil {
+						return
+					}
+				}
+			}
+		}
+		return blades.NewModelWrapper(next, generate, streaming)
+	}
+}
+
+// servedBy returns the name of the model that produced message.
+func servedBy(message *blades.Message, next blades.ModelProvider) string {
+	if name, ok := message.Metadata[provider.MetadataKeyProvider].(string); ok && name != "" {
+		return name
+	}
+	return next.Name()
+}
+
+// SessionCost returns the cost accumulated by the session.
+func SessionCost(session blades.Session) float64 {
+	if session == nil {
+		return 0
+	}
+	return toFloat(session.State()[StateKeyCost])
+}
+
+// MessageCost returns the estimated cost annotated on a message.
+func MessageCost(message *blades.Message) (float64, bool) {
+	if message == nil || message.Metadata == nil {
+		return 0, false
+	}
+	value, ok := message.Metadata[MetadataKeyCost]
+	return toFloat(value), ok
+}
+
+// toFloat converts a state value, which becomes a generic JSON number once a
+// session is persisted.
+func toFloat(value any) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
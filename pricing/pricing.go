@@ -0,0 +1,115 @@
+// Package pricing estimates the cost of model calls from per-model price tables.
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-kratos/blades"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// MetadataKeyCost is the Message.Metadata key holding the estimated cost
+	// of the model call that produced the message.
+	MetadataKeyCost = "cost"
+	// MetadataKeyCurrency is the Message.Metadata key holding the currency of
+	// the cost.
+	MetadataKeyCurrency = "cost_currency"
+	// StateKeyCost is the session state key holding the cost accumulated by
+	// all model calls of the session.
+	StateKeyCost = "blades:cost"
+)
+
+// Price is the price of a model per million tokens.
+type Price struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+	// CachedInput is the price of input tokens served from the prompt cache.
+	// When zero, cached tokens are charged at the Input price.
+	CachedInput float64 `yaml:"cached_input"`
+}
+
+// Registry holds the prices of models.
+//
+//	currency: USD
+//	models:
+//	  gpt-4o:
+//	    input: 2.5
+//	    output: 10
+//	    cached_input: 1.25
+type Registry struct {
+	Currency string           `yaml:"currency"`
+	Models   map[string]Price `yaml:"models"`
+}
+
+// ParseRegistry parses a YAML price table.
+func ParseRegistry(data []byte) (*Registry, error) {
+	var r Registry
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("pricing: %w", err)
+	}
+	if r.Currency == "" {
+		r.Currency = "USD"
+	}
+	for name, price := range r.Models {
+		if price.Input < 0 || price.Output < 0 || price.CachedInput < 0 {
+			return nil, fmt.Errorf("pricing: model %q has a negative price", name)
+		}
+	}
+	return &r, nil
+}
+
+// LoadRegistry reads a YAML price table from a file.
+func LoadRegistry(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRegistry(data)
+}
+
+// Lookup returns the price of a model. An exact match wins, otherwise the
+// longest configured name that prefixes the model, so "gpt-4o" also prices
+// "gpt-4o-2024-08-06".
+func (r *Registry) Lookup(model string) (Price, bool) {
+	if r == nil {
+		return Price{}, false
+	}
+	if price, ok := r.Models[model]; ok {
+		return price, true
+	}
+	var (
+		match string
+		price Price
+	)
+	for name, p := range r.Models {
+		if len(name) > len(match) && strings.HasPrefix(model, name) {
+			match, price = name, p
+		}
+	}
+	return price, match != ""
+}
+
+// Cost returns the estimated cost of the token usage of a model, and false
+// when the model has no price.
+func (r *Registry) Cost(model string, usage blades.TokenUsage) (float64, bool) {
+	price, ok := r.Lookup(model)
+	if !ok {
+		return 0, false
+	}
+	return price.Cost(usage), true
+}
+
+// Cost returns the estimated cost of the token usage.
+func (p Price) Cost(usage blades.TokenUsage) float64 {
+	cached := min(usage.CachedInputTokens, usage.InputTokens)
+	cachedPrice := p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	return (float64(usage.InputTokens-cached)*p.Input +
+		float64(cached)*cachedPrice +
+		float64(usage.OutputTokens)*p.Output) / 1e6
+}
@@ -0,0 +1,209 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/go-kratos/blades"
+	"github.com/go-kratos/blades/provider"
+)
+
+const table = `
+currency: USD
+models:
+  gpt-4o:
+    input: 2.5
+    output: 10
+    cached_input: 1.25
+  gpt-4o-mini:
+    input: 0.15
+    output: 0.6
+`
+
+type usageModel struct {
+	name  string
+	usage blades.TokenUsage
+}
+
+func (m *usageModel) Name() string { return m.name }
+
+func (m *usageModel) message() *blades.Message {
+	msg := blades.AssistantMessage("ok")
+	msg.Status = blades.StatusCompleted
+	msg.TokenUsage = m.usage
+	return msg
+}
+
+func (m *usageModel) Generate(context.Context, *blades.ModelRequest) (*blades.ModelResponse, error) {
+	return &blades.ModelResponse{Message: m.message()}, nil
+}
+
+func (m *usageModel) NewStreaming(context.Context, *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
+	return func(yield func(*blades.ModelResponse, error) bool) {
+		chunk := blades.AssistantMessage("o")
+		chunk.Status = blades.StatusIncomplete
+		if !yield(&blades.ModelResponse{Message: chunk}, nil) {
+			return
+		}
+		yield(&blades.ModelResponse{Message: m.message()}, nil)
+	}
+}
+
+func almostEqual(a, b float64) bool { return math.Abs(a-b) < 1e-12 }
+
+func TestRegistryCost(t *testing.T) {
+	r, err := ParseRegistry([]byte(table))
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := blades.TokenUsage{InputTokens: 1_000_000, OutputTokens: 100_000, CachedInputTokens: 400_000}
+	tests := []struct {
+		model string
+		want  float64
+		ok    bool
+	}{
+		{model: "gpt-4o", want: 0.6*2.5 + 0.4*1.25 + 0.1*10, ok: true},
+		{model: "gpt-4o-2024-08-06", want: 0.6*2.5 + 0.4*1.25 + 0.1*10, ok: true},
+		// Without a cached price, cached tokens are charged as input tokens.
+		{model: "gpt-4o-mini-2024-07-18", want: 0.15 + 0.1*0.6, ok: true},
+		{model: "claude-sonnet-4", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := r.Cost(tt.model, usage)
+		if ok != tt.ok || !almostEqual(got, tt.want) {
+			t.Errorf("Cost(%q) = %v, %v; want %v, %v", tt.model, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseRegistryRejectsNegativePrices(t *testing.T) {
+	if _, err := ParseRegistry([]byte("models:\n  m:\n    input: -1\n")); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	r, err := ParseRegistry([]byte(table))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := blades.WrapModel(&usageModel{
+		name:  "gpt-4o-mini",
+		usage: blades.TokenUsage{InputTokens: 1_000_000, OutputTokens: 1_000_000},
+	}, Middleware(r))
+	session := blades.NewSession()
+	ctx := blades.NewSessionContext(context.Background(), session)
+
+	res, err := model.Generate(ctx, &blades.ModelRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost, ok := MessageCost(res.Message); !ok || !almostEqual(cost, 0.75) {
+		t.Fatalf("message cost = %v, %v; want 0.75", cost, ok)
+	}
+	if currency := res.Message.Metadata[MetadataKeyCurrency]; currency != "USD" {
+		t.Fatalf("currency = %v", currency)
+	}
+
+	var annotated int
+	for res, err := range model.NewStreaming(ctx, &blades.ModelRequest{}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := MessageCost(res.Message); ok {
+			annotated++
+		}
+	}
+	if annotated != 1 {
+		t.Fatalf("annotated chunks = %d; want 1", annotated)
+	}
+	if got := SessionCost(session); !almostEqual(got, 1.5) {
+		t.Fatalf("session cost = %v; want 1.5", got)
+	}
+}
+
+func TestMiddlewareUnpricedModel(t *testing.T) {
+	model := blades.WrapModel(&usageModel{name: "unknown"}, Middleware(&Registry{}))
+	res, err := model.Generate(context.Background(), &blades.ModelRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := MessageCost(res.Message); ok {
+		t.Fatal("unpriced model should not be annotated")
+	}
+}
+
+type failingModel struct{ name string }
+
+func (m *failingModel) Name() string { return m.name }
+
+func (m *failingModel) Generate(context.Context, *blades.ModelRequest) (*blades.ModelResponse, error) {
+	return nil, errors.New("unavailable")
+}
+
+func (m *failingModel) NewStreaming(context.Context, *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
+	return func(yield func(*blades.ModelResponse, error) bool) {
+		yield(nil, errors.New("unavailable"))
+	}
+}
+
+func TestMiddlewarePricesFallbackModel(t *testing.T) {
+	r, err := ParseRegistry([]byte(table))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fallback, err := provider.NewFallbackModel([]blades.ModelProvider{
+		&failingModel{name: "gpt-4o-mini"},
+		&usageModel{name: "gpt-4o", usage: blades.TokenUsage{InputTokens: 1_000_000, OutputTokens: 1_000_000}},
+	}, provider.WithErrorClassifier(func(blades.ModelProvider, error) provider.ErrorClass {
+		return provider.ErrorClassServer
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := blades.WrapModel(fallback, Middleware(r))
+	res, err := model.Generate(context.Background(), &blades.ModelRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost, ok := MessageCost(res.Message); !ok || !almostEqual(cost, 12.5) {
+		t.Fatalf("message cost = %v, %v; want 12.5 at gpt-4o rates", cost, ok)
+	}
+}
+
+func TestMiddlewareCacheHitIsFree(t *testing.T) {
+	r, err := ParseRegistry([]byte(table))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := blades.WrapModel(&usageModel{
+		name:  "gpt-4o-mini",
+		usage: blades.TokenUsage{InputTokens: 1_000_000, OutputTokens: 1_000_000},
+	}, Middleware(r), provider.Cache(provider.NewDirCache(t.TempDir())))
+	session := blades.NewSession()
+	ctx := blades.NewSessionContext(context.Background(), session)
+
+	if _, err := model.Generate(ctx, &blades.ModelRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := model.Generate(ctx, &blades.ModelRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost, ok := MessageCost(res.Message); !ok || cost != 0 {
+		t.Fatalf("cached message cost = %v, %v; want 0", cost, ok)
+	}
+	for res, err := range model.NewStreaming(ctx, &blades.ModelRequest{}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cost, ok := MessageCost(res.Message); ok && cost != 0 {
+			t.Fatalf("cached stream cost = %v; want 0", cost)
+		}
+	}
+	if got := SessionCost(session); !almostEqual(got, 0.75) {
+		t.Fatalf("session cost = %v; want 0.75 for the one uncached call", got)
+	}
+}
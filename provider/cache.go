@@ -0,0 +1,276 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kratos/blades"
+)
+
+// MetadataKeyCacheHit is the Message.Metadata key set to true on responses
+// replayed from a cache.
+const MetadataKeyCacheHit = "cache_hit"
+
+// ErrCacheMiss is returned by a CacheStore when it holds no entry for a key.
+var ErrCacheMiss = errors.New("provider: cache miss")
+
+// CacheEntry is a cached model response.
+type CacheEntry struct {
+	Model     string          `json:"model"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Message   *blades.Message `json:"message"`
+}
+
+// CacheStore persists cache entries by request key.
+type CacheStore interface {
+	// Get returns the entry stored under key, or ErrCacheMiss.
+	Get(ctx context.Context, key string) (*CacheEntry, error)
+	// Put stores the entry under key, replacing any previous entry.
+	Put(ctx context.Context, key string, entry *CacheEntry) error
+}
+
+// CacheOption configures the Cache middleware.
+type CacheOption func(*cacheOptions)
+
+type cacheOptions struct {
+	ttl       time.Duration
+	chunkSize int
+	now       func() time.Time
+}
+
+// WithCacheTTL expires cached responses after d. Expired entries are
+// refreshed by the next call. By default entries never expire.
+func WithCacheTTL(d time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.ttl = d
+	}
+}
+
+// WithCacheChunkSize sets the number of runes per text chunk when a cached
+// response is replayed as a stream. The default is 16.
+func WithCacheChunkSize(n int) CacheOption {
+	return func(o *cacheOptions) {
+		if n > 0 {
+			o.chunkSize = n
+		}
+	}
+}
+
+type ctxCacheBypassKey struct{}
+
+// NewCacheBypassContext returns a context whose model calls skip the Cache
+// middleware: they neither read nor store cached responses.
+func NewCacheBypassContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxCacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(ctxCacheBypassKey{}).(bool)
+	return bypass
+}
+
+// Cache returns a model middleware that stores completed responses in store,
+// keyed by RequestKey, and replays them for identical requests instead of
+// calling the model. It is meant for development against paid providers,
+// where the same prompts are sent over and over.
+//
+// Generate and NewStreaming share the cache. A cached response is replayed to
+// a streaming call as incremental text chunks followed by the completed
+// message. Only successful calls that complete are stored. Replayed messages
+// get a fresh ID and are marked with MetadataKeyCacheHit; their TokenUsage is
+// the one recorded with the original response.
+func Cache(store CacheStore, opts ...CacheOption) blades.ModelMiddleware {
+	o := cacheOptions{chunkSize: 16, now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(next blades.ModelProvider) blades.ModelProvider {
+		lookup := func(ctx context.Context, req *blades.ModelRequest) (string, *blades.Message, error) {
+			if cacheBypassed(ctx) {
+				return "", nil, nil
+			}
+			key, err := RequestKey(next.Name(), req)
+			if err != nil {
+				return "", nil, err
+			}
+			entry, err := store.Get(ctx, key)
+			if errors.Is(err, ErrCacheMiss) {
+				return key, nil, nil
+			}
+			if err != nil {
+				return "", nil, err
+			}
+			if entry.Message == nil || (o.ttl > 0 && o.now().Sub(entry.CreatedAt) > o.ttl) {
+				return key, nil, nil
+			}
+			return key, replayedMessage(entry.Message), nil
+		}
+		save := func(ctx context.Context, key string, message *blades.Message) error {
+			if key == "" || message == nil {
+				return nil
+			}
+			return store.Put(ctx, key, &CacheEntry{
+				Model:     next.Name(),
+				CreatedAt: o.now(),
+				Message:   message,
+			})
+		}
+		generate := func(ctx context.Context, req *blades.ModelRequest) (*blades.ModelResponse, error) {
+			key, cached, err := lookup(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+			if cached != nil {
+				return &blades.ModelResponse{Message: cached}, nil
+			}
+			res, err := next.Generate(ctx, req)
+			if err != nil || res == nil {
+				return res, err
+			}
+			if err := save(ctx, key, res.Message); err != nil {
+				return nil, err
+			}
+			return res, nil
+		}
+		streaming := func(ctx context.Context, req *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
+			return func(yield func(*blades.ModelResponse, error) bool) {
+				key, cached, err := lookup(ctx, req)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				if cached != nil {
+					for _, chunk := range rechunk(cached, o.chunkSize) {
+						if !yield(&blades.ModelResponse{Message: chunk}, nil) {
+							return
+						}
+					}
+					return
+				}
+				var completed *blades.Message
+				for res, err := range next.NewStreaming(ctx, req) {
+					if err != nil {
+						yield(nil, err)
+						return
+					}
+					if res != nil && res.Message != nil && res.Message.Status == blades.StatusCompleted {
+						completed = res.Message
+					}
+					if !yield(res, nil) {
+						return
+					}
+				}
+				if err := save(ctx, key, completed); err != nil {
+					yield(nil, err)
+				}
+			}
+		}
+		return blades.NewModelWrapper(next, generate, streaming)
+	}
+}
+
+// replayedMessage returns a copy of a cached message with a fresh ID, so a
+// response replayed twice in a session is not mistaken for the same message.
+func replayedMessage(m *blades.Message) *blades.Message {
+	c := m.Clone()
+	c.ID = blades.NewMessageID()
+	if c.Metadata == nil {
+		c.Metadata = make(map[string]any)
+	}
+	c.Metadata[MetadataKeyCacheHit] = true
+	return c
+}
+
+// rechunk splits the text of a completed message into incremental chunks of
+// size runes, followed by the completed message itself.
+func rechunk(m *blades.Message, size int) []*blades.Message {
+	var chunks []*blades.Message
+	for _, part := range m.Parts {
+		text, ok := part.(blades.TextPart)
+		if !ok {
+			continue
+		}
+		runes := []rune(text.Text)
+		for start := 0; start < len(runes); start += size {
+			end := min(start+size, len(runes))
+			chunks = append(chunks, &blades.Message{
+				ID:       m.ID,
+				Role:     m.Role,
+				Author:   m.Author,
+				Status:   blades.StatusIncomplete,
+				Parts:    []blades.Part{blades.TextPart{Text: string(runes[start:end])}},
+				Metadata: map[string]any{MetadataKeyCacheHit: true},
+			})
+		}
+	}
+	return append(chunks, m)
+}
+
+// DirCache is a CacheStore that keeps one JSON file per entry in a directory.
+type DirCache struct {
+	dir string
+}
+
+// NewDirCache returns a CacheStore backed by dir, which is created on first write.
+func NewDirCache(dir string) *DirCache {
+	return &DirCache{dir: dir}
+}
+
+func (c *DirCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// Get implements CacheStore.
+func (c *DirCache) Get(ctx context.Context, key string) (*CacheEntry, error) {
+	data, err := os.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// A corrupt entry is treated like a missing one and rewritten.
+		return nil, ErrCacheMiss
+	}
+	return &entry, nil
+}
+
+// Put implements CacheStore. Entries are written to a temporary file and
+// renamed, so concurrent readers never see a partial entry.
+func (c *DirCache) Put(ctx context.Context, key string, entry *CacheEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), c.path(key))
+}
+
+// Delete removes the entry stored under key, if any.
+func (c *DirCache) Delete(key string) error {
+	if err := os.Remove(c.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/blades"
+)
+
+// echoModel answers with a completed message and counts its calls.
+type echoModel struct {
+	answer string
+	calls  int
+}
+
+func (m *echoModel) Name() string { return "echo" }
+
+func (m *echoModel) completed() *blades.Message {
+	msg := blades.AssistantMessage(m.answer)
+	msg.Status = blades.StatusCompleted
+	msg.TokenUsage = blades.TokenUsage{InputTokens: 3, OutputTokens: 5, TotalTokens: 8}
+	return msg
+}
+
+func (m *echoModel) Generate(context.Context, *blades.ModelRequest) (*blades.ModelResponse, error) {
+	m.calls++
+	return &blades.ModelResponse{Message: m.completed()}, nil
+}
+
+func (m *echoModel) NewStreaming(context.Context, *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
+	return func(yield func(*blades.ModelResponse, error) bool) {
+		m.calls++
+		chunk := blades.AssistantMessage(m.answer)
+		chunk.Status = blades.StatusIncomplete
+		if !yield(&blades.ModelResponse{Message: chunk}, nil) {
+			return
+		}
+		yield(&blades.ModelResponse{Message: m.completed()}, nil)
+	}
+}
+
+func cacheRequest() *blades.ModelRequest {
+	return &blades.ModelRequest{
+		Instruction: blades.SystemMessage("be brief"),
+		Messages:    []*blades.Message{blades.UserMessage("hello")},
+	}
+}
+
+func TestRequestKey(t *testing.T) {
+	t.Parallel()
+
+	a, err := RequestKey("m", cacheRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fresh message IDs must not change the key.
+	b, err := RequestKey("m", cacheRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatal("identical requests have different keys")
+	}
+	other := cacheRequest()
+	other.Messages[0] = blades.UserMessage("goodbye")
+	hot := cacheRequest()
+	hot.Generation = &blades.GenerationConfig{Temperature: blades.Ptr(1.5)}
+	forced := cacheRequest()
+	forced.ToolChoice = &blades.ToolChoice{Mode: blades.ToolChoiceRequired}
+	for name, key := range map[string]func() (string, error){
+		"model":      func() (string, error) { return RequestKey("n", cacheRequest()) },
+		"message":    func() (string, error) { return RequestKey("m", other) },
+		"generation": func() (string, error) { return RequestKey("m", hot) },
+		"toolChoice": func() (string, error) { return RequestKey("m", forced) },
+	} {
+		k, err := key()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k == a {
+			t.Errorf("different %s produced the same key", name)
+		}
+	}
+}
+
+func TestCacheGenerate(t *testing.T) {
+	t.Parallel()
+
+	model := &echoModel{answer: "hi"}
+	cached := blades.WrapModel(model, Cache(NewDirCache(t.TempDir())))
+	first, err := cached.Generate(context.Background(), cacheRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := cached.Generate(context.Background(), cacheRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.calls != 1 {
+		t.Fatalf("model calls = %d; want 1", model.calls)
+	}
+	if second.Message.Text() != "hi" || second.Message.TokenUsage.TotalTokens != 8 {
+		t.Fatalf("replayed message = %+v", second.Message)
+	}
+	if second.Message.ID == first.Message.ID {
+		t.Fatal("replayed message should get a fresh ID")
+	}
+	if second.Message.Metadata[MetadataKeyCacheHit] != true {
+		t.Fatal("replayed message is not marked as a cache hit")
+	}
+
+	if _, err := cached.Generate(NewCacheBypassContext(context.Background()), cacheRequest()); err != nil {
+		t.Fatal(err)
+	}
+	if model.calls != 2 {
+		t.Fatalf("bypassed call did not reach the model: calls = %d", model.calls)
+	}
+}
+
+// nilModel answers with neither a response nor an error.
+type nilModel struct{ echoModel }
+
+func (m *nilModel) Generate(context.Context, *blades.ModelRequest) (*blades.ModelResponse, error) {
+	return nil, nil
+}
+
+func TestCacheGenerateNilResponse(t *testing.T) {
+	t.Parallel()
+
+	cached := blades.WrapModel(&nilModel{}, Cache(NewDirCache(t.TempDir())))
+	res, err := cached.Generate(context.Background(), cacheRequest())
+	if res != nil || err != nil {
+		t.Fatalf("Generate = %v, %v; want nil, nil", res, err)
+	}
+}
+
+func TestCacheStreamingReplay(t *testing.T) {
+	t.Parallel()
+
+	model := &echoModel{answer: "a cached answer that spans chunks"}
+	cached := blades.WrapModel(model, Cache(NewDirCache(t.TempDir()), WithCacheChunkSize(4)))
+	drain := func() []*blades.Message {
+		var messages []*blades.Message
+		for res, err := range cached.NewStreaming(context.Background(), cacheRequest()) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages = append(messages, res.Message)
+		}
+		return messages
+	}
+	if got := drain(); len(got) != 2 {
+		t.Fatalf("live stream yielded %d responses; want 2", len(got))
+	}
+	replayed := drain()
+	if model.calls != 1 {
+		t.Fatalf("model calls = %d; want 1", model.calls)
+	}
+	var text strings.Builder
+	for _, m := range replayed[:len(replayed)-1] {
+		if m.Status != blades.StatusIncomplete {
+			t.Fatalf("chunk status = %q", m.Status)
+		}
+		text.WriteString(m.Text())
+	}
+	final := replayed[len(replayed)-1]
+	if len(replayed) != 10 || final.Status != blades.StatusCompleted || text.String() != model.answer {
+		t.Fatalf("replayed %d responses, text %q, final %+v", len(replayed), text.String(), final)
+	}
+	// A cached streaming response also serves Generate.
+	if _, err := cached.Generate(context.Background(), cacheRequest()); err != nil || model.calls != 1 {
+		t.Fatalf("Generate after stream: calls = %d, err = %v", model.calls, err)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	model := &echoModel{answer: "hi"}
+	cached := blades.WrapModel(model, Cache(NewDirCache(t.TempDir()), WithCacheTTL(time.Minute), func(o *cacheOptions) {
+		o.now = func() time.Time { return now }
+	}))
+	for _, advance := range []time.Duration{0, 30 * time.Second, 2 * time.Minute} {
+		now = now.Add(advance)
+		if _, err := cached.Generate(context.Background(), cacheRequest()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if model.calls != 2 {
+		t.Fatalf("model calls = %d; want 2", model.calls)
+	}
+}
@@ -0,0 +1,434 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-kratos/blades"
+)
+
+// ErrNoInteraction is returned while replaying a cassette when no recorded
+// interaction matches a request.
+var ErrNoInteraction = errors.New("provider: no matching interaction in cassette")
+
+// CassetteMode selects whether a Cassette records or replays interactions.
+type CassetteMode int
+
+const (
+	// ModeAuto replays an existing cassette file and records a missing one.
+	ModeAuto CassetteMode = iota
+	// ModeRecord calls the real provider and overwrites the cassette file.
+	ModeRecord
+	// ModeReplay only replays; a missing cassette file is an error.
+	ModeReplay
+)
+
+// Interaction is a recorded model call.
+type Interaction struct {
+	Request *CanonicalRequest `json:"request"`
+	// Stream reports whether the call was made with NewStreaming.
+	Stream bool `json:"stream,omitempty"`
+	// Responses are the messages returned by the model, one per streamed chunk.
+	Responses []*blades.Message `json:"responses,omitempty"`
+	// Error is the failure of the call, replayed as a plain error.
+	Error string `json:"error,omitempty"`
+}
+
+// cassetteFile is the on-disk format of a Cassette.
+type cassetteFile struct {
+	Interactions []*Interaction     `json:"interactions,omitempty"`
+	HTTP         []*HTTPInteraction `json:"http,omitempty"`
+}
+
+// Matcher reports how a recorded request differs from the actual one, or nil
+// when it matches.
+type Matcher func(recorded, actual *CanonicalRequest) error
+
+// CassetteOption configures a Cassette.
+type CassetteOption func(*Cassette)
+
+// WithCassetteMode sets the mode of the cassette. The default is ModeAuto.
+func WithCassetteMode(mode CassetteMode) CassetteOption {
+	return func(c *Cassette) {
+		c.mode = mode
+	}
+}
+
+// WithMatchers replaces the rules used to match model requests, which default
+// to DefaultMatchers.
+func WithMatchers(matchers ...Matcher) CassetteOption {
+	return func(c *Cassette) {
+		c.matchers = matchers
+	}
+}
+
+// WithHTTPMatchers replaces the rules used to match HTTP requests, which
+// default to DefaultHTTPMatchers.
+func WithHTTPMatchers(matchers ...HTTPMatcher) CassetteOption {
+	return func(c *Cassette) {
+		c.httpMatchers = matchers
+	}
+}
+
+// Cassette records model interactions to a file once and replays them
+// afterwards, so agents built on real providers can be tested offline.
+// Recorded interactions are replayed in order: each request is served by the
+// first unused interaction that satisfies every matcher.
+//
+// A cassette records at the blades level with Middleware, or at the HTTP
+// level with Transport, which suits provider SDK clients.
+type Cassette struct {
+	path         string
+	mode         CassetteMode
+	matchers     []Matcher
+	httpMatchers []HTTPMatcher
+
+	mu        sync.Mutex
+	file      cassetteFile
+	used      []bool
+	httpUsed  []bool
+	recording bool
+}
+
+// NewCassette opens the cassette stored at path.
+func NewCassette(path string, opts ...CassetteOption) (*Cassette, error) {
+	c := &Cassette{
+		path:         path,
+		matchers:     DefaultMatchers(),
+		httpMatchers: DefaultHTTPMatchers(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if c.mode == ModeReplay {
+			return nil, fmt.Errorf("provider: cassette %s does not exist", path)
+		}
+		c.recording = true
+	case err != nil:
+		return nil, err
+	case c.mode == ModeRecord:
+		c.recording = true
+	default:
+		if err := json.Unmarshal(data, &c.file); err != nil {
+			return nil, fmt.Errorf("provider: cassette %s: %w", path, err)
+		}
+	}
+	c.used = make([]bool, len(c.file.Interactions))
+	c.httpUsed = make([]bool, len(c.file.HTTP))
+	return c, nil
+}
+
+// Recording reports whether the cassette records rather than replays.
+func (c *Cassette) Recording() bool {
+	return c.recording
+}
+
+// Middleware returns a model middleware that records the calls made to the
+// wrapped provider, or replays them without calling it.
+func (c *Cassette) Middleware() blades.ModelMiddleware {
+	return func(next blades.ModelProvider) blades.ModelProvider {
+		generate := func(ctx context.Context, req *blades.ModelRequest) (*blades.ModelResponse, error) {
+			canonical, err := NewCanonicalRequest(next.Name(), req)
+			if err != nil {
+				return nil, err
+			}
+			if !c.recording {
+				interaction, err := c.match(canonical)
+				if err != nil {
+					return nil, err
+				}
+				return replayGenerate(interaction)
+			}
+			res, err := next.Generate(ctx, req)
+			interaction := &Interaction{Request: canonical}
+			if err != nil {
+				interaction.Error = err.Error()
+			} else if res != nil && res.Message != nil {
+				interaction.Responses = []*blades.Message{res.Message.Clone()}
+			}
+			if saveErr := c.record(interaction); saveErr != nil && err == nil {
+				return nil, saveErr
+			}
+			return res, err
+		}
+		streaming := func(ctx context.Context, req *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
+			return func(yield func(*blades.ModelResponse, error) bool) {
+				canonical, err := NewCanonicalRequest(next.Name(), req)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				if !c.recording {
+					interaction, err := c.match(canonical)
+					if err != nil {
+						yield(nil, err)
+						return
+					}
+					for _, message := range interaction.Responses {
+						if !yield(&blades.ModelResponse{Message: message.Clone()}, nil) {
+							return
+						}
+					}
+					if interaction.Error != "" {
+						yield(nil, errors.New(interaction.Error))
+					}
+					return
+				}
+				interaction := &Interaction{Request: canonical, Stream: true}
+				for res, err := range next.NewStreaming(ctx, req) {
+					if err != nil {
+						interaction.Error = err.Error()
+						c.record(interaction)
+						yield(nil, err)
+						return
+					}
+					if res != nil {
+						interaction.Responses = append(interaction.Responses, res.Message.Clone())
+					}
+					if !yield(res, nil) {
+						// The stream was not consumed to the end, so it is not recorded.
+						return
+					}
+				}
+				if err := c.record(interaction); err != nil {
+					yield(nil, err)
+				}
+			}
+		}
+		return blades.NewModelWrapper(next, generate, streaming)
+	}
+}
+
+// replayGenerate returns the completed response of an interaction. A
+// recorded stream is served by its last response.
+func replayGenerate(interaction *Interaction) (*blades.ModelResponse, error) {
+	if interaction.Error != "" {
+		return nil, errors.New(interaction.Error)
+	}
+	if len(interaction.Responses) == 0 {
+		return nil, blades.ErrNoFinalResponse
+	}
+	return &blades.ModelResponse{Message: interaction.Responses[len(interaction.Responses)-1].Clone()}, nil
+}
+
+// match returns the first unused interaction that satisfies every matcher.
+func (c *Cassette) match(actual *CanonicalRequest) (*Interaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var diffs []string
+	for i, interaction := range c.file.Interactions {
+		if c.used[i] {
+			continue
+		}
+		if err := matchAll(c.matchers, interaction.Request, actual); err != nil {
+			diffs = append(diffs, fmt.Sprintf("interaction %d: %v", i, err))
+			continue
+		}
+		c.used[i] = true
+		return interaction, nil
+	}
+	return nil, &MismatchError{Path: c.path, Request: summarizeRequest(actual), Diffs: diffs}
+}
+
+func matchAll(matchers []Matcher, recorded, actual *CanonicalRequest) error {
+	for _, match := range matchers {
+		if err := match(recorded, actual); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record appends an interaction and rewrites the cassette file.
+func (c *Cassette) record(interaction *Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.file.Interactions = append(c.file.Interactions, interaction)
+	return c.saveLocked()
+}
+
+func (c *Cassette) saveLocked() error {
+	data, err := json.MarshalIndent(c.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0o644)
+}
+
+// MismatchError reports a request that no recorded interaction matches,
+// together with how each unused interaction differs from it.
+type MismatchError struct {
+	Path    string
+	Request string
+	Diffs   []string
+}
+
+func (e *MismatchError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v: %s: %s", ErrNoInteraction, e.Path, e.Request)
+	if len(e.Diffs) == 0 {
+		b.WriteString(" (all recorded interactions were used; re-record the cassette)")
+	}
+	for _, diff := range e.Diffs {
+		b.WriteString("\n  ")
+		b.WriteString(diff)
+	}
+	return b.String()
+}
+
+func (e *MismatchError) Unwrap() error { return ErrNoInteraction }
+
+func summarizeRequest(r *CanonicalRequest) string {
+	s := fmt.Sprintf("request to %q with %d messages", r.Model, len(r.Messages))
+	if n := len(r.Messages); n > 0 {
+		s += ", last " + string(r.Messages[n-1].Role) + " " + showJSON(r.Messages[n-1].Parts)
+	}
+	return s
+}
+
+// DefaultMatchers match the model, instruction, messages, tools, schemas,
+// generation settings and tool choice.
+func DefaultMatchers() []Matcher {
+	return []Matcher{MatchModel, MatchInstruction, MatchMessages, MatchTools, MatchSchemas, MatchGeneration, MatchToolChoice}
+}
+
+// MatchModel requires the same model name.
+func MatchModel(recorded, actual *CanonicalRequest) error {
+	if recorded.Model != actual.Model {
+		return fmt.Errorf("model: recorded %q, got %q", recorded.Model, actual.Model)
+	}
+	return nil
+}
+
+// MatchInstruction requires the same system instruction.
+func MatchInstruction(recorded, actual *CanonicalRequest) error {
+	var want, got []byte
+	if recorded.Instruction != nil {
+		want = recorded.Instruction.Parts
+	}
+	if actual.Instruction != nil {
+		got = actual.Instruction.Parts
+	}
+	if !jsonEqual(want, got) {
+		return fmt.Errorf("instruction: recorded %s, got %s", showJSON(want), showJSON(got))
+	}
+	return nil
+}
+
+// MatchMessages requires the same roles and parts for every message.
+func MatchMessages(recorded, actual *CanonicalRequest) error {
+	if len(recorded.Messages) != len(actual.Messages) {
+		return fmt.Errorf("messages: recorded %d, got %d", len(recorded.Messages), len(actual.Messages))
+	}
+	for i := range recorded.Messages {
+		want, got := recorded.Messages[i], actual.Messages[i]
+		if want.Role != got.Role {
+			return fmt.Errorf("messages[%d].role: recorded %q, got %q", i, want.Role, got.Role)
+		}
+		if !jsonEqual(want.Parts, got.Parts) {
+			return fmt.Errorf("messages[%d].parts: recorded %s, got %s", i, showJSON(want.Parts), showJSON(got.Parts))
+		}
+	}
+	return nil
+}
+
+// MatchLastMessage requires the same last message only, which tolerates
+// changes earlier in the conversation.
+func MatchLastMessage(recorded, actual *CanonicalRequest) error {
+	if len(recorded.Messages) == 0 || len(actual.Messages) == 0 {
+		if len(recorded.Messages) != len(actual.Messages) {
+			return fmt.Errorf("messages: recorded %d, got %d", len(recorded.Messages), len(actual.Messages))
+		}
+		return nil
+	}
+	return MatchMessages(
+		&CanonicalRequest{Messages: recorded.Messages[len(recorded.Messages)-1:]},
+		&CanonicalRequest{Messages: actual.Messages[len(actual.Messages)-1:]},
+	)
+}
+
+// MatchTools requires the same tool definitions.
+func MatchTools(recorded, actual *CanonicalRequest) error {
+	return matchJSON("tools", recorded.Tools, actual.Tools)
+}
+
+// MatchSchemas requires the same input and output schemas.
+func MatchSchemas(recorded, actual *CanonicalRequest) error {
+	if err := matchJSON("inputSchema", recorded.InputSchema, actual.InputSchema); err != nil {
+		return err
+	}
+	return matchJSON("outputSchema", recorded.OutputSchema, actual.OutputSchema)
+}
+
+// MatchGeneration requires the same generation settings.
+func MatchGeneration(recorded, actual *CanonicalRequest) error {
+	return matchJSON("generation", recorded.Generation, actual.Generation)
+}
+
+// MatchToolChoice requires the same tool choice.
+func MatchToolChoice(recorded, actual *CanonicalRequest) error {
+	return matchJSON("toolChoice", recorded.ToolChoice, actual.ToolChoice)
+}
+
+func matchJSON(field string, recorded, actual any) error {
+	want, err := json.Marshal(recorded)
+	if err != nil {
+		return err
+	}
+	got, err := json.Marshal(actual)
+	if err != nil {
+		return err
+	}
+	if !jsonEqual(want, got) {
+		return fmt.Errorf("%s: recorded %s, got %s", field, showJSON(want), showJSON(got))
+	}
+	return nil
+}
+
+// jsonEqual reports whether two JSON documents are equal regardless of
+// formatting and key order. Empty documents equal null.
+func jsonEqual(a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	var va, vb any
+	if len(bytes.TrimSpace(a)) > 0 && json.Unmarshal(a, &va) != nil {
+		return false
+	}
+	if len(bytes.TrimSpace(b)) > 0 && json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	ca, _ := json.Marshal(va)
+	cb, _ := json.Marshal(vb)
+	return bytes.Equal(ca, cb)
+}
+
+// showJSON formats a JSON document compactly for a diagnostic.
+func showJSON(data []byte) string {
+	var buf bytes.Buffer
+	if json.Compact(&buf, data) == nil {
+		data = buf.Bytes()
+	}
+	return truncate(string(data))
+}
+
+func truncate(s string) string {
+	const max = 120
+	if runes := []rune(s); len(runes) > max {
+		return string(runes[:max]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,212 @@
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HTTPInteraction is a recorded HTTP exchange. Request headers and API key
+// query parameters are not recorded, so credentials never end up in a cassette.
+type HTTPInteraction struct {
+	Request  *HTTPRequest  `json:"request"`
+	Response *HTTPResponse `json:"response"`
+}
+
+// HTTPRequest is a recorded HTTP request.
+type HTTPRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// HTTPResponse is a recorded HTTP response. Streamed responses, such as
+// server-sent events, are recorded whole and replayed at once.
+type HTTPResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// HTTPMatcher reports how a recorded HTTP request differs from the actual
+// one, or nil when it matches.
+type HTTPMatcher func(recorded, actual *HTTPRequest) error
+
+// DefaultHTTPMatchers match the method, the URL and the body.
+func DefaultHTTPMatchers() []HTTPMatcher {
+	return []HTTPMatcher{MatchMethod, MatchURL, MatchBody}
+}
+
+// MatchMethod requires the same HTTP method.
+func MatchMethod(recorded, actual *HTTPRequest) error {
+	if recorded.Method != actual.Method {
+		return fmt.Errorf("method: recorded %s, got %s", recorded.Method, actual.Method)
+	}
+	return nil
+}
+
+// MatchURL requires the same URL, ignoring the order of query parameters.
+func MatchURL(recorded, actual *HTTPRequest) error {
+	want, err := url.Parse(recorded.URL)
+	if err != nil {
+		return err
+	}
+	got, err := url.Parse(actual.URL)
+	if err != nil {
+		return err
+	}
+	if want.Scheme != got.Scheme || want.Host != got.Host || want.Path != got.Path ||
+		want.Query().Encode() != got.Query().Encode() {
+		return fmt.Errorf("url: recorded %s, got %s", recorded.URL, actual.URL)
+	}
+	return nil
+}
+
+// MatchBody requires the same body. JSON bodies are compared regardless of
+// formatting and key order.
+func MatchBody(recorded, actual *HTTPRequest) error {
+	if recorded.Body == actual.Body || jsonEqual([]byte(recorded.Body), []byte(actual.Body)) {
+		return nil
+	}
+	return fmt.Errorf("body: recorded %s, got %s", showJSON([]byte(recorded.Body)), showJSON([]byte(actual.Body)))
+}
+
+// secretHeaders are response headers that are never recorded.
+var secretHeaders = []string{"Set-Cookie"}
+
+// secretParams are query parameters that are never recorded, since some APIs
+// accept the API key in the URL.
+var secretParams = []string{"key", "api_key", "api-key"}
+
+// redactURL returns the URL without its secret query parameters.
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	redacted := false
+	for _, key := range secretParams {
+		if query.Has(key) {
+			query.Del(key)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return u.String()
+	}
+	c := *u
+	c.RawQuery = query.Encode()
+	return c.String()
+}
+
+// Transport returns an http.RoundTripper for provider SDK clients that
+// records the exchanges made through next, or http.DefaultTransport when it
+// is nil, or replays them without sending any request.
+func (c *Cassette) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &cassetteTransport{cassette: c, next: next}
+}
+
+type cassetteTransport struct {
+	cassette *Cassette
+	next     http.RoundTripper
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded := &HTTPRequest{Method: req.Method, URL: redactURL(req.URL)}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		recorded.Body = string(body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if !t.cassette.recording {
+		interaction, err := t.cassette.matchHTTP(recorded)
+		if err != nil {
+			return nil, err
+		}
+		return interaction.Response.toHTTP(req), nil
+	}
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	header := res.Header.Clone()
+	for _, key := range secretHeaders {
+		header.Del(key)
+	}
+	interaction := &HTTPInteraction{
+		Request:  recorded,
+		Response: &HTTPResponse{StatusCode: res.StatusCode, Header: header, Body: string(body)},
+	}
+	if err := t.cassette.recordHTTP(interaction); err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
+
+func (r *HTTPResponse) toHTTP(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// matchHTTP returns the first unused HTTP interaction that satisfies every
+// HTTP matcher.
+func (c *Cassette) matchHTTP(actual *HTTPRequest) (*HTTPInteraction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var diffs []string
+	for i, interaction := range c.file.HTTP {
+		if c.httpUsed[i] {
+			continue
+		}
+		if err := matchAllHTTP(c.httpMatchers, interaction.Request, actual); err != nil {
+			diffs = append(diffs, fmt.Sprintf("interaction %d: %v", i, err))
+			continue
+		}
+		c.httpUsed[i] = true
+		return interaction, nil
+	}
+	return nil, &MismatchError{
+		Path:    c.path,
+		Request: fmt.Sprintf("%s %s", actual.Method, actual.URL),
+		Diffs:   diffs,
+	}
+}
+
+func matchAllHTTP(matchers []HTTPMatcher, recorded, actual *HTTPRequest) error {
+	for _, match := range matchers {
+		if err := match(recorded, actual); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordHTTP appends an HTTP interaction and rewrites the cassette file.
+func (c *Cassette) recordHTTP(interaction *HTTPInteraction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.file.HTTP = append(c.file.HTTP, interaction)
+	return c.saveLocked()
+}
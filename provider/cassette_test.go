@@ -0,0 +1,236 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/blades"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "agent.json")
+	model := &echoModel{answer: "recorded"}
+	record, err := NewCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !record.Recording() {
+		t.Fatal("a missing cassette should be recorded")
+	}
+	recorder := blades.WrapModel(model, record.Middleware())
+	if _, err := recorder.Generate(context.Background(), cacheRequest()); err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range recorder.NewStreaming(context.Background(), cacheRequest()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replay, err := NewCassette(path, WithCassetteMode(ModeReplay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	player := blades.WrapModel(model, replay.Middleware())
+	res, err := player.Generate(context.Background(), cacheRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Message.Text() != "recorded" {
+		t.Fatalf("replayed text = %q", res.Message.Text())
+	}
+	var chunks int
+	for _, err := range player.NewStreaming(context.Background(), cacheRequest()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks++
+	}
+	if chunks != 2 {
+		t.Fatalf("replayed %d chunks; want 2", chunks)
+	}
+	if model.calls != 2 {
+		t.Fatalf("model calls = %d; the replay must not call the model", model.calls)
+	}
+
+	// Both interactions were used, and a different request never matches.
+	req := cacheRequest()
+	req.Messages[0] = blades.UserMessage("something else")
+	_, err = player.Generate(context.Background(), req)
+	if !errors.Is(err, ErrNoInteraction) {
+		t.Fatalf("err = %v; want ErrNoInteraction", err)
+	}
+}
+
+func TestCassetteMismatchDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "agent.json")
+	record, err := NewCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blades.WrapModel(&echoModel{answer: "a"}, record.Middleware()).Generate(context.Background(), cacheRequest()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := cacheRequest()
+	req.Messages[0] = blades.UserMessage("goodbye")
+	for _, tt := range []struct {
+		name     string
+		matchers []Matcher
+		wantErr  string
+	}{
+		{name: "default", wantErr: `messages[0].parts: recorded [{"type":"text","text":"hello"}], got [{"type":"text","text":"goodbye"}]`},
+		{name: "model only", matchers: []Matcher{MatchModel}},
+	} {
+		opts := []CassetteOption{WithCassetteMode(ModeReplay)}
+		if tt.matchers != nil {
+			opts = append(opts, WithMatchers(tt.matchers...))
+		}
+		replay, err := NewCassette(path, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = blades.WrapModel(&echoModel{}, replay.Middleware()).Generate(context.Background(), req)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		var mismatch *MismatchError
+		if !errors.As(err, &mismatch) || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v; want a mismatch containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCassetteMatchesGenerationAndToolChoice(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "agent.json")
+	record, err := NewCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := cacheRequest()
+	req.Generation = &blades.GenerationConfig{Temperature: blades.Ptr(0.2)}
+	req.ToolChoice = &blades.ToolChoice{Mode: blades.ToolChoiceNone}
+	recorder := blades.WrapModel(&echoModel{answer: "a"}, record.Middleware())
+	res, err := recorder.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Changing the response does not change the recording, even once the
+	// cassette is rewritten for the next interaction.
+	res.Message.Parts[0] = blades.TextPart{Text: "changed"}
+	other := cacheRequest()
+	other.Messages[0] = blades.UserMessage("other")
+	if _, err := recorder.Generate(context.Background(), other); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		change  func(*blades.ModelRequest)
+		wantErr string
+	}{
+		{name: "same", change: func(*blades.ModelRequest) {}},
+		{name: "generation", change: func(r *blades.ModelRequest) { r.Generation.Temperature = blades.Ptr(0.9) }, wantErr: "generation: recorded"},
+		{name: "tool choice", change: func(r *blades.ModelRequest) { r.ToolChoice = nil }, wantErr: "toolChoice: recorded"},
+	} {
+		replay, err := NewCassette(path, WithCassetteMode(ModeReplay))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := cacheRequest()
+		req.Generation = &blades.GenerationConfig{Temperature: blades.Ptr(0.2)}
+		req.ToolChoice = &blades.ToolChoice{Mode: blades.ToolChoiceNone}
+		tt.change(req)
+		res, err := blades.WrapModel(&echoModel{}, replay.Middleware()).Generate(context.Background(), req)
+		if tt.wantErr == "" {
+			if err != nil || res.Message.Text() != "a" {
+				t.Errorf("%s: replayed %v, %v; want the recorded answer", tt.name, res, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrNoInteraction) || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v; want a mismatch containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCassetteReplayRequiresFile(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewCassette(filepath.Join(t.TempDir(), "missing.json"), WithCassetteMode(ModeReplay)); err == nil {
+		t.Fatal("expected an error for a missing cassette")
+	}
+}
+
+func TestCassetteTransport(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"echo":` + string(body) + `}`))
+	}))
+	path := filepath.Join(t.TempDir(), "http.json")
+	post := func(c *Cassette, body string) (string, error) {
+		client := &http.Client{Transport: c.Transport(nil)}
+		res, err := client.Post(server.URL+"/v1/chat?key=secret", "application/json", strings.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		return string(data), err
+	}
+
+	record, err := NewCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := post(record, `{"a":1,"b":2}`); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	replay, err := NewCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := post(replay, `{"b":2, "a":1}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != `{"echo":{"a":1,"b":2}}` {
+		t.Fatalf("replayed body = %s", got)
+	}
+	if _, err := post(replay, `{"a":3}`); !errors.Is(err, ErrNoInteraction) {
+		t.Fatalf("err = %v; want ErrNoInteraction", err)
+	}
+	if strings.Contains(mustRead(t, path), "secret") {
+		t.Fatal("the API key was recorded")
+	}
+}
+
+func mustRead(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
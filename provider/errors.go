@@ -0,0 +1,182 @@
+// Package provider offers decorators that add cross-cutting behavior, such as
+// retries, logging, timeouts, response caching and record/replay, to any
+// blades.ModelProvider.
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// statusCoder is implemented by errors that carry an HTTP status code.
+type statusCoder interface {
+	StatusCode() int
+}
+
+// StatusCode returns the HTTP status code carried by err or any error it
+// wraps. It recognizes errors with a StatusCode() int method, as well as the
+// API error types of the provider SDKs, which expose the code as a StatusCode
+// or Code field.
+func StatusCode(err error) (int, bool) {
+	for err != nil {
+		if v, ok := err.(statusCoder); ok {
+			return v.StatusCode(), true
+		}
+		if code, ok := statusCodeField(err); ok {
+			return code, true
+		}
+		switch v := err.(type) {
+		case interface{ Unwrap() error }:
+			err = v.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, err := range v.Unwrap() {
+				if code, ok := StatusCode(err); ok {
+					return code, true
+				}
+			}
+			return 0, false
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+func statusCodeField(err error) (int, bool) {
+	v := reflect.ValueOf(err)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return 0, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return 0, false
+	}
+	for _, name := range []string{"StatusCode", "Code"} {
+		f := v.FieldByName(name)
+		if !f.IsValid() || !f.CanInt() {
+			continue
+		}
+		if code := int(f.Int()); code >= 100 && code <= 599 {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// IsRetryable reports whether a failed model call may succeed when retried:
+// the request timed out, was rate limited, or hit a transient server error.
+// Context cancellation is never retryable.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	code, ok := StatusCode(err)
+	if !ok {
+		return false
+	}
+	switch code {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// ErrorClass is the kind of failure of a model call.
+type ErrorClass int
+
+const (
+	// ErrorClassUnknown is a failure that is not recognized.
+	ErrorClassUnknown ErrorClass = iota
+	// ErrorClassRateLimit is a rate limit or quota failure (HTTP 429).
+	ErrorClassRateLimit
+	// ErrorClassServer is a server failure or overload (HTTP 5xx).
+	ErrorClassServer
+	// ErrorClassContextLength is a request too large for the model's context window.
+	ErrorClassContextLength
+	// ErrorClassTimeout is a call that ran out of time.
+	ErrorClassTimeout
+	// ErrorClassCanceled is a call canceled by the caller.
+	ErrorClassCanceled
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassRateLimit:
+		return "rate_limit"
+	case ErrorClassServer:
+		return "server"
+	case ErrorClassContextLength:
+		return "context_length"
+	case ErrorClassTimeout:
+		return "timeout"
+	case ErrorClassCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// contextLengthMessages are fragments of the error messages providers return
+// when a request exceeds the context window.
+var contextLengthMessages = []string{
+	"context_length_exceeded",
+	"context length",
+	"context window",
+	"prompt is too long",
+	"maximum number of tokens",
+	"input token count",
+}
+
+// ClassifyError returns the class of a model call failure, from the HTTP
+// status code it carries and, for context length failures, its message. The
+// message is only consulted when the status is 400 or unknown, so a rate
+// limit that mentions tokens is still classified as a rate limit.
+func ClassifyError(err error) ErrorClass {
+	switch {
+	case err == nil:
+		return ErrorClassUnknown
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	}
+	code, ok := StatusCode(err)
+	if (!ok || code == http.StatusBadRequest) && isContextLength(err) {
+		return ErrorClassContextLength
+	}
+	switch {
+	case !ok:
+		return ErrorClassUnknown
+	case code == http.StatusTooManyRequests:
+		return ErrorClassRateLimit
+	case code == http.StatusRequestTimeout || code == http.StatusGatewayTimeout:
+		return ErrorClassTimeout
+	case code == http.StatusRequestEntityTooLarge:
+		return ErrorClassContextLength
+	case code >= 500:
+		return ErrorClassServer
+	}
+	return ErrorClassUnknown
+}
+
+// isContextLength reports whether the error message says the request exceeds
+// the context window.
+func isContextLength(err error) bool {
+	message := strings.ToLower(err.Error())
+	for _, fragment := range contextLengthMessages {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
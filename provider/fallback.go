@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/go-kratos/blades"
+)
+
+// MetadataKeyProvider is the Message.Metadata key holding the name of the
+// provider that served a response.
+const MetadataKeyProvider = "provider"
+
+// FallbackOption configures a FallbackModel.
+type FallbackOption func(*FallbackModel)
+
+// WithFallbackOn sets the error classes that move a call on to the next
+// provider. The default is rate limit, server, context length and timeout
+// failures.
+func WithFallbackOn(classes ...ErrorClass) FallbackOption {
+	return func(m *FallbackModel) {
+		m.classes = classes
+	}
+}
+
+// WithErrorClassifier replaces ClassifyError, for example to recognize the
+// errors of a particular provider. It receives the provider that failed.
+func WithErrorClassifier(classify func(provider blades.ModelProvider, err error) ErrorClass) FallbackOption {
+	return func(m *FallbackModel) {
+		m.classify = classify
+	}
+}
+
+// FallbackModel is a ModelProvider that tries an ordered list of providers,
+// moving on to the next one when a call fails with an error of a fallback
+// class. Other errors are returned immediately. The name of the provider that
+// served a response is recorded in its Metadata under MetadataKeyProvider.
+//
+// A streaming call that fails midway restarts on the next provider. The
+// chunks yielded before the failure cannot be taken back, so consumers should
+// rely on the completed message, as the Agent does.
+type FallbackModel struct {
+	providers []blades.ModelProvider
+	classes   []ErrorClass
+	classify  func(blades.ModelProvider, error) ErrorClass
+}
+
+// NewFallbackModel returns a FallbackModel over providers, in order of preference.
+func NewFallbackModel(providers []blades.ModelProvider, opts ...FallbackOption) (*FallbackModel, error) {
+	if len(providers) == 0 {
+		return nil, errors.New("provider: fallback model needs at least one provider")
+	}
+	m := &FallbackModel{
+		providers: providers,
+		classes:   []ErrorClass{ErrorClassRateLimit, ErrorClassServer, ErrorClassContextLength, ErrorClassTimeout},
+		classify: func(_ blades.ModelProvider, err error) ErrorClass {
+			return ClassifyError(err)
+		},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// Name returns the name of the preferred provider.
+func (m *FallbackModel) Name() string {
+	return m.providers[0].Name()
+}
+
+// fallback reports whether the failure of provider should move the call on.
+// A done context always stops the call, whatever the class of the error.
+func (m *FallbackModel) fallback(ctx context.Context, provider blades.ModelProvider, err error) bool {
+	return ctx.Err() == nil && slices.Contains(m.classes, m.classify(provider, err))
+}
+
+// Generate implements blades.ModelProvider.
+func (m *FallbackModel) Generate(ctx context.Context, req *blades.ModelRequest) (*blades.ModelResponse, error) {
+	var errs []error
+	for _, provider := range m.providers {
+		res, err := provider.Generate(ctx, req)
+		if err == nil {
+			annotateProvider(res, provider)
+			return res, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+		if !m.fallback(ctx, provider, err) {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// NewStreaming implements blades.ModelProvider.
+func (m *FallbackModel) NewStreaming(ctx context.Context, req *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
+	return func(yield func(*blades.ModelResponse, error) bool) {
+		var errs []error
+		for _, provider := range m.providers {
+			var failed error
+			for res, err := range provider.NewStreaming(ctx, req) {
+				if err != nil {
+					failed = err
+					break
+				}
+				if res != nil && res.Message != nil && res.Message.Status == blades.StatusCompleted {
+					annotateProvider(res, provider)
+				}
+				if !yield(res, nil) {
+					return
+				}
+			}
+			if failed == nil {
+				return
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), failed))
+			if !m.fallback(ctx, provider, failed) {
+				break
+			}
+		}
+		yield(nil, errors.Join(errs...))
+	}
+}
+
+func annotateProvider(res *blades.ModelResponse, provider blades.ModelProvider) {
+	if res == nil || res.Message == nil {
+		return
+	}
+	if res.Message.Metadata == nil {
+		res.Message.Metadata = make(map[string]any)
+	}
+	res.Message.Metadata[MetadataKeyProvider] = provider.Name()
+}
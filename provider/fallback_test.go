@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-kratos/blades"
+)
+
+// stubModel fails with err, optionally after streaming its chunks, or
+// answers with its name.
+type stubModel struct {
+	name      string
+	err       error
+	chunks    []string
+	failAfter bool
+	calls     int
+}
+
+func (m *stubModel) Name() string { return m.name }
+
+func (m *stubModel) Generate(context.Context, *blades.ModelRequest) (*blades.ModelResponse, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	msg := blades.AssistantMessage(m.name)
+	msg.Status = blades.StatusCompleted
+	return &blades.ModelResponse{Message: msg}, nil
+}
+
+func (m *stubModel) NewStreaming(ctx context.Context, req *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
+	return func(yield func(*blades.ModelResponse, error) bool) {
+		if m.err != nil && !m.failAfter {
+			m.calls++
+			yield(nil, m.err)
+			return
+		}
+		for _, chunk := range m.chunks {
+			msg := blades.AssistantMessage(chunk)
+			msg.Status = blades.StatusIncomplete
+			if !yield(&blades.ModelResponse{Message: msg}, nil) {
+				return
+			}
+		}
+		if m.failAfter {
+			m.calls++
+			yield(nil, m.err)
+			return
+		}
+		yield(m.Generate(ctx, req))
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{err: &httpError{StatusCode: 429}, want: ErrorClassRateLimit},
+		{err: &httpError{StatusCode: 529}, want: ErrorClassServer},
+		{err: &httpError{StatusCode: 400}, want: ErrorClassUnknown},
+		{err: errors.New(`400: {"code":"context_length_exceeded"}`), want: ErrorClassContextLength},
+		{err: errors.New("prompt is too long: 210000 tokens > 200000 maximum"), want: ErrorClassContextLength},
+		{err: fmt.Errorf("context length exceeded: %w", &httpError{StatusCode: 400}), want: ErrorClassContextLength},
+		{err: fmt.Errorf("rate limit reached: too many tokens per minute, input token count 90000: %w", &httpError{StatusCode: 429}), want: ErrorClassRateLimit},
+		{err: fmt.Errorf("context window busy: %w", &httpError{StatusCode: 503}), want: ErrorClassServer},
+		{err: context.DeadlineExceeded, want: ErrorClassTimeout},
+		{err: context.Canceled, want: ErrorClassCanceled},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("ClassifyError(%v) = %v; want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestFallbackGenerate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		primaryErr error
+		want       string
+		wantErr    bool
+	}{
+		{name: "primary serves", want: "primary"},
+		{name: "overloaded", primaryErr: &httpError{StatusCode: 529}, want: "secondary"},
+		{name: "rate limited", primaryErr: &httpError{StatusCode: 429}, want: "secondary"},
+		{name: "bad request is not retried elsewhere", primaryErr: &httpError{StatusCode: 400}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			primary := &stubModel{name: "primary", err: tt.primaryErr}
+			secondary := &stubModel{name: "secondary"}
+			model, err := NewFallbackModel([]blades.ModelProvider{primary, secondary})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := model.Generate(context.Background(), &blades.ModelRequest{})
+			if tt.wantErr {
+				if err == nil || secondary.calls != 0 {
+					t.Fatalf("err = %v, secondary calls = %d", err, secondary.calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Message.Text() != tt.want || res.Message.Metadata[MetadataKeyProvider] != tt.want {
+				t.Fatalf("served by %q, metadata %v; want %q", res.Message.Text(), res.Message.Metadata, tt.want)
+			}
+		})
+	}
+}
+
+func TestFallbackAllFail(t *testing.T) {
+	t.Parallel()
+
+	overloaded := &httpError{StatusCode: 503}
+	model, err := NewFallbackModel([]blades.ModelProvider{
+		&stubModel{name: "a", err: overloaded},
+		&stubModel{name: "b", err: &httpError{StatusCode: 429}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = model.Generate(context.Background(), &blades.ModelRequest{})
+	if !errors.Is(err, overloaded) || ClassifyError(err) != ErrorClassServer {
+		t.Fatalf("err = %v; want the joined provider errors", err)
+	}
+}
+
+func TestFallbackStreamingRestartsMidStream(t *testing.T) {
+	t.Parallel()
+
+	primary := &stubModel{name: "primary", chunks: []string{"par"}, err: &httpError{StatusCode: 502}, failAfter: true}
+	secondary := &stubModel{name: "secondary", chunks: []string{"sec", "ond"}}
+	model, err := NewFallbackModel([]blades.ModelProvider{primary, secondary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		texts []string
+		final *blades.Message
+	)
+	for res, err := range model.NewStreaming(context.Background(), &blades.ModelRequest{}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts = append(texts, res.Message.Text())
+		final = res.Message
+	}
+	if len(texts) != 4 || final.Status != blades.StatusCompleted || final.Metadata[MetadataKeyProvider] != "secondary" {
+		t.Fatalf("texts = %q, final = %+v", texts, final)
+	}
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/go-kratos/blades"
+)
+
+// Logging returns a model middleware that logs every model call with the
+// given logger, or slog.Default when it is nil. Requests are logged at debug
+// level, successful responses at info level and failures at error level.
+func Logging(logger *slog.Logger) blades.ModelMiddleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next blades.ModelProvider) blades.ModelProvider {
+		logRequest := func(ctx context.Context, req *blades.ModelRequest, stream bool) {
+			logger.DebugContext(ctx, "model request",
+				slog.String("model", next.Name()),
+				slog.Bool("stream", stream),
+				slog.Int("messages", len(req.Messages)),
+				slog.Int("tools", len(req.Tools)),
+			)
+		}
+		logResponse := func(ctx context.Context, res *blades.ModelResponse, err error, start time.Time) {
+			attrs := []any{
+				slog.String("model", next.Name()),
+				slog.Duration("duration", time.Since(start)),
+			}
+			if err != nil {
+				logger.ErrorContext(ctx, "model response", append(attrs, slog.Any("error", err))...)
+				return
+			}
+			if res != nil && res.Message != nil {
+				attrs = append(attrs,
+					slog.String("role", string(res.Message.Role)),
+					slog.String("finish_reason", res.Message.FinishReason),
+					slog.Int64("input_tokens", res.Message.TokenUsage.InputTokens),
+					slog.Int64("output_tokens", res.Message.TokenUsage.OutputTokens),
+				)
+			}
+			logger.InfoContext(ctx, "model response", attrs...)
+		}
+		generate := func(ctx context.Context, req *blades.ModelRequest) (*blades.ModelResponse, error) {
+			start := time.Now()
+			logRequest(ctx, req, false)
+			res, err := next.Generate(ctx, req)
+			logResponse(ctx, res, err, start)
+			return res, err
+		}
+		streaming := func(ctx context.Context, req *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
+			return func(yield func(*blades.ModelResponse, error) bool) {
+				var (
+					last  *blades.ModelResponse
+					start = time.Now()
+				)
+				logRequest(ctx, req, true)
+				for res, err := range next.NewStreaming(ctx, req) {
+					if err != nil {
+						logResponse(ctx, nil, err, start)
+						yield(nil, err)
+						return
+					}
+					last = res
+					if !yield(res, nil) {
+						break
+					}
+				}
+				logResponse(ctx, last, nil, start)
+			}
+		}
+		return blades.NewModelWrapper(next, generate, streaming)
+	}
+}
@@ -0,0 +1,257 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/blades"
+)
+
+type httpError struct {
+	StatusCode int
+}
+
+func (e *httpError) Error() string { return fmt.Sprintf("http %d", e.StatusCode) }
+
+// flakyModel fails with the scripted errors before succeeding.
+type flakyModel struct {
+	errs   []error
+	calls  int
+	chunks []string
+	// failAfter makes the stream fail after yielding its chunks.
+	failAfter error
+}
+
+func (m *flakyModel) Name() string { return "flaky" }
+
+func (m *flakyModel) next() error {
+	m.calls++
+	if m.calls <= len(m.errs) {
+		return m.errs[m.calls-1]
+	}
+	return nil
+}
+
+func (m *flakyModel) Generate(ctx context.Context, _ *blades.ModelRequest) (*blades.ModelResponse, error) {
+	if err := m.next(); err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= 0 {
+		return nil, ctx.Err()
+	}
+	return &blades.ModelResponse{Message: blades.AssistantMessage("ok")}, nil
+}
+
+func (m *flakyModel) NewStreaming(ctx context.Context, _ *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
+	return func(yield func(*blades.ModelResponse, error) bool) {
+		if err := m.next(); err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, chunk := range m.chunks {
+			if !yield(&blades.ModelResponse{Message: blades.AssistantMessage(chunk)}, nil) {
+				return
+			}
+		}
+		if m.failAfter != nil {
+			yield(nil, m.failAfter)
+		}
+	}
+}
+
+func fastRetry(attempts int, opts ...RetryOption) blades.ModelMiddleware {
+	return Retry(attempts, append(opts, WithRetryBackoff(time.Millisecond, time.Millisecond))...)
+}
+
+func TestStatusCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err  error
+		code int
+		ok   bool
+	}{
+		{err: &httpError{StatusCode: 429}, code: 429, ok: true},
+		{err: fmt.Errorf("wrapped: %w", &httpError{StatusCode: 503}), code: 503, ok: true},
+		{err: errors.Join(errors.New("a"), &httpError{StatusCode: 500}), code: 500, ok: true},
+		{err: errors.New("plain")},
+		{err: &httpError{StatusCode: 7}},
+	}
+	for _, tt := range tests {
+		code, ok := StatusCode(tt.err)
+		if code != tt.code || ok != tt.ok {
+			t.Errorf("StatusCode(%v) = %d, %t; want %d, %t", tt.err, code, ok, tt.code, tt.ok)
+		}
+	}
+	for code, want := range map[int]bool{400: false, 408: true, 409: false, 429: true, 500: true, 501: false, 502: true, 503: true, 504: true, 505: false} {
+		if got := IsRetryable(&httpError{StatusCode: code}); got != want {
+			t.Errorf("IsRetryable(%d) = %t, want %t", code, got, want)
+		}
+	}
+}
+
+func TestRetryGenerate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "recovers from 503", errs: []error{&httpError{503}, &httpError{429}}, wantCalls: 3},
+		{name: "does not retry 400", errs: []error{&httpError{400}}, wantCalls: 1, wantErr: true},
+		{name: "gives up", errs: []error{&httpError{500}, &httpError{500}, &httpError{500}}, wantCalls: 3, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			model := &flakyModel{errs: tt.errs}
+			_, err := blades.WrapModel(model, fastRetry(3)).Generate(context.Background(), &blades.ModelRequest{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if model.calls != tt.wantCalls {
+				t.Fatalf("calls = %d, want %d", model.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryAttemptsBelowOne(t *testing.T) {
+	t.Parallel()
+
+	model := &flakyModel{errs: []error{&httpError{503}, &httpError{503}}}
+	if _, err := blades.WrapModel(model, fastRetry(0)).Generate(context.Background(), &blades.ModelRequest{}); err == nil {
+		t.Fatal("err = nil, want the first failure")
+	}
+	if model.calls != 1 {
+		t.Fatalf("calls = %d, want 1", model.calls)
+	}
+}
+
+func TestRetryStopsWaitingOnCancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	model := &flakyModel{errs: []error{&httpError{503}}}
+	retrying := blades.WrapModel(model, Retry(3, WithRetryBackoff(time.Hour, time.Hour)))
+	start := time.Now()
+	if _, err := retrying.Generate(ctx, &blades.ModelRequest{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	for _, err := range retrying.NewStreaming(ctx, &blades.ModelRequest{}) {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("stream err = %v, want deadline exceeded", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("retry returned after %s, want promptly", elapsed)
+	}
+}
+
+func TestRetryOptions(t *testing.T) {
+	t.Parallel()
+
+	model := &flakyModel{errs: []error{&httpError{400}}}
+	retryAll := WithRetryable(func(error) bool { return true })
+	if _, err := blades.WrapModel(model, fastRetry(2, retryAll)).Generate(context.Background(), &blades.ModelRequest{}); err != nil {
+		t.Fatalf("err = %v, want the retry to succeed", err)
+	}
+	if model.calls != 2 {
+		t.Fatalf("calls = %d, want 2", model.calls)
+	}
+
+	r := &retrier{baseDelay: 100 * time.Millisecond, maxDelay: 200 * time.Millisecond}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 160 * time.Millisecond, 5: 200 * time.Millisecond} {
+		if got := r.backoff(attempt); got < want*8/10 || got > want*12/10 {
+			t.Errorf("backoff(%d) = %s, want %s ±20%%", attempt, got, want)
+		}
+	}
+}
+
+func TestRetryStreaming(t *testing.T) {
+	t.Parallel()
+
+	model := &flakyModel{errs: []error{&httpError{502}}, chunks: []string{"a", "b"}}
+	var got []string
+	for res, err := range blades.WrapModel(model, fastRetry(3)).NewStreaming(context.Background(), &blades.ModelRequest{}) {
+		if err != nil {
+			t.Fatalf("stream: %v", err)
+		}
+		got = append(got, res.Message.Text())
+	}
+	if strings.Join(got, "") != "ab" || model.calls != 2 {
+		t.Fatalf("chunks = %v after %d calls", got, model.calls)
+	}
+
+	// A failure after the first chunk is reported instead of retried.
+	model = &flakyModel{chunks: []string{"a"}, failAfter: &httpError{503}}
+	var gotErr error
+	for _, err := range blades.WrapModel(model, fastRetry(3)).NewStreaming(context.Background(), &blades.ModelRequest{}) {
+		gotErr = err
+	}
+	if gotErr == nil || model.calls != 1 {
+		t.Fatalf("err = %v after %d calls, want mid-stream failure without retry", gotErr, model.calls)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	_, err := blades.WrapModel(&flakyModel{}, Timeout(-time.Second)).Generate(context.Background(), &blades.ModelRequest{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+}
+
+func TestLogging(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	model := blades.WrapModel(&flakyModel{errs: []error{&httpError{500}}}, Logging(logger))
+	if model.Name() != "flaky" {
+		t.Fatalf("name = %q, want the wrapped model name", model.Name())
+	}
+	if _, err := model.Generate(context.Background(), &blades.ModelRequest{}); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := model.Generate(context.Background(), &blades.ModelRequest{}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"model request", "level=ERROR", "level=INFO", "model=flaky"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("log output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	t.Parallel()
+
+	var order []string
+	tag := func(name string) blades.ModelMiddleware {
+		return func(next blades.ModelProvider) blades.ModelProvider {
+			return blades.NewModelWrapper(next, func(ctx context.Context, req *blades.ModelRequest) (*blades.ModelResponse, error) {
+				order = append(order, name)
+				return next.Generate(ctx, req)
+			}, nil)
+		}
+	}
+	if _, err := blades.WrapModel(&flakyModel{}, tag("outer"), tag("inner")).Generate(context.Background(), &blades.ModelRequest{}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if strings.Join(order, ",") != "outer,inner" {
+		t.Fatalf("order = %v", order)
+	}
+}
@@ -0,0 +1,251 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/blades"
+	"github.com/go-kratos/blades/internal/counter"
+)
+
+// MetadataKeyRateLimitWait is the Message.Metadata key holding the time, in
+// milliseconds, a model call waited for the rate limiter.
+const MetadataKeyRateLimitWait = "rate_limit_wait_ms"
+
+// RateLimitOption configures a RateLimiter.
+type RateLimitOption func(*RateLimiter)
+
+// WithRequestsPerMinute limits the number of model calls per minute.
+func WithRequestsPerMinute(n int) RateLimitOption {
+	return func(l *RateLimiter) {
+		l.requests = newBucket(float64(n), l.now())
+	}
+}
+
+// WithTokensPerMinute limits the number of tokens per minute. The tokens of a
+// call are estimated before it is sent and corrected with the usage reported
+// in its response.
+func WithTokensPerMinute(n int64) RateLimitOption {
+	return func(l *RateLimiter) {
+		l.tokens = newBucket(float64(n), l.now())
+	}
+}
+
+// WithRateLimitCounter sets the TokenCounter used to estimate the tokens of a
+// request. Defaults to a character-based counter (1 token ≈ 4 chars).
+func WithRateLimitCounter(counter blades.TokenCounter) RateLimitOption {
+	return func(l *RateLimiter) {
+		l.counter = counter
+	}
+}
+
+// WithRateLimitObserver sets a function called with the time each model call
+// waited, for example to export it as a metric.
+func WithRateLimitObserver(observe func(ctx context.Context, model string, wait time.Duration)) RateLimitOption {
+	return func(l *RateLimiter) {
+		l.observe = observe
+	}
+}
+
+// RateLimiter enforces requests-per-minute and tokens-per-minute limits with
+// token buckets that refill continuously and hold up to a minute of budget.
+// A single RateLimiter is meant to be shared by every provider that uses the
+// same API key, for example across the branches of a parallel flow.
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests *bucket
+	tokens   *bucket
+	counter  blades.TokenCounter
+	observe  func(context.Context, string, time.Duration)
+	now      func() time.Time
+}
+
+// NewRateLimiter returns a RateLimiter. Without limits it never waits.
+func NewRateLimiter(opts ...RateLimitOption) *RateLimiter {
+	l := &RateLimiter{
+		counter: counter.NewCharBasedCounter(),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Wait blocks until one request and the given number of tokens fit within
+// the limits, and returns the time it waited. Waiting requests are served in
+// arrival order. When the wait would outlast the deadline of ctx, Wait fails
+// immediately with an error wrapping context.DeadlineExceeded.
+func (l *RateLimiter) Wait(ctx context.Context, tokens int64) (time.Duration, error) {
+	wait := l.reserve(tokens)
+	if wait <= 0 {
+		return 0, nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		l.cancel(tokens)
+		return 0, fmt.Errorf("provider: rate limit wait of %v exceeds the context deadline: %w", wait, context.DeadlineExceeded)
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return wait, nil
+	case <-ctx.Done():
+		l.cancel(tokens)
+		return 0, ctx.Err()
+	}
+}
+
+// reserve takes a request and tokens from the buckets, going into debt if
+// needed, and returns how long the caller must wait for the debt to clear.
+func (l *RateLimiter) reserve(tokens int64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	var wait time.Duration
+	if l.requests != nil {
+		wait = max(wait, l.requests.take(1, now))
+	}
+	if l.tokens != nil {
+		wait = max(wait, l.tokens.take(float64(tokens), now))
+	}
+	return wait
+}
+
+// cancel returns a reservation that will not be used.
+func (l *RateLimiter) cancel(tokens int64) {
+	l.adjust(1, tokens)
+}
+
+// adjust puts requests and tokens back in the buckets; negative amounts take
+// them out.
+func (l *RateLimiter) adjust(requests, tokens int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if l.requests != nil && requests != 0 {
+		l.requests.take(-float64(requests), now)
+	}
+	if l.tokens != nil && tokens != 0 {
+		l.tokens.take(-float64(tokens), now)
+	}
+}
+
+// estimate returns the estimated input tokens of a request.
+func (l *RateLimiter) estimate(req *blades.ModelRequest) int64 {
+	if l.tokens == nil {
+		return 0
+	}
+	messages := req.Messages
+	if req.Instruction != nil {
+		messages = append([]*blades.Message{req.Instruction}, messages...)
+	}
+	return l.counter.Count(messages...)
+}
+
+// settle corrects the token estimate of a call with the usage it reported.
+func (l *RateLimiter) settle(estimate int64, message *blades.Message) {
+	if l.tokens == nil || message == nil {
+		return
+	}
+	usage := message.TokenUsage
+	actual := usage.TotalTokens
+	if actual == 0 {
+		actual = usage.InputTokens + usage.OutputTokens
+	}
+	if actual > 0 {
+		l.adjust(0, estimate-actual)
+	}
+}
+
+// bucket is a token bucket whose level may go negative when capacity is
+// reserved ahead of time.
+type bucket struct {
+	capacity float64
+	rate     float64 // per second
+	level    float64
+	last     time.Time
+}
+
+func newBucket(perMinute float64, now time.Time) *bucket {
+	return &bucket{capacity: perMinute, rate: perMinute / 60, level: perMinute, last: now}
+}
+
+// take removes n from the bucket and returns how long until its level is
+// back to zero.
+func (b *bucket) take(n float64, now time.Time) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	if now.After(b.last) {
+		b.level = min(b.capacity, b.level+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+	b.level = min(b.capacity, b.level-n)
+	if b.level >= 0 {
+		return 0
+	}
+	return time.Duration(-b.level / b.rate * float64(time.Second))
+}
+
+// RateLimit returns a model middleware that waits for l before every model
+// call. The time waited is reported in the response Metadata under
+// MetadataKeyRateLimitWait and to the observer of l. Use the same RateLimiter
+// for every provider that shares a quota.
+func RateLimit(l *RateLimiter) blades.ModelMiddleware {
+	return func(next blades.ModelProvider) blades.ModelProvider {
+		wait := func(ctx context.Context, req *blades.ModelRequest) (int64, time.Duration, error) {
+			estimate := l.estimate(req)
+			waited, err := l.Wait(ctx, estimate)
+			if err != nil {
+				return 0, 0, err
+			}
+			if l.observe != nil {
+				l.observe(ctx, next.Name(), waited)
+			}
+			return estimate, waited, nil
+		}
+		annotate := func(message *blades.Message, waited time.Duration) {
+			if message.Metadata == nil {
+				message.Metadata = make(map[string]any)
+			}
+			message.Metadata[MetadataKeyRateLimitWait] = waited.Milliseconds()
+		}
+		generate := func(ctx context.Context, req *blades.ModelRequest) (*blades.ModelResponse, error) {
+			estimate, waited, err := wait(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+			res, err := next.Generate(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+			if res != nil && res.Message != nil {
+				l.settle(estimate, res.Message)
+				annotate(res.Message, waited)
+			}
+			return res, nil
+		}
+		streaming := func(ctx context.Context, req *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
+			return func(yield func(*blades.ModelResponse, error) bool) {
+				estimate, waited, err := wait(ctx, req)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				for res, err := range next.NewStreaming(ctx, req) {
+					if err == nil && res != nil && res.Message != nil && res.Message.Status == blades.StatusCompleted {
+						l.settle(estimate, res.Message)
+						annotate(res.Message, waited)
+					}
+					if !yield(res, err) || err != nil {
+						return
+					}
+				}
+			}
+		}
+		return blades.NewModelWrapper(next, generate, streaming)
+	}
+}
@@ -0,0 +1,91 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/blades"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	t.Parallel()
+
+	l := NewRateLimiter(WithRequestsPerMinute(2), WithTokensPerMinute(600))
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	for i, want := range []time.Duration{0, 0, 30 * time.Second, time.Minute} {
+		if got := l.reserve(10); got != want {
+			t.Fatalf("reservation %d waits %v; want %v", i, got, want)
+		}
+	}
+	// After a minute the request debt of two requests is paid back.
+	now = now.Add(time.Minute)
+	if got := l.reserve(0); got != 30*time.Second {
+		t.Fatalf("wait = %v; want 30s", got)
+	}
+	// Tokens: 600 per minute is 10 per second.
+	l = NewRateLimiter(WithTokensPerMinute(600))
+	l.now = func() time.Time { return now }
+	if got := l.reserve(700); got != 10*time.Second {
+		t.Fatalf("token wait = %v; want 10s", got)
+	}
+}
+
+func TestRateLimiterDeadline(t *testing.T) {
+	t.Parallel()
+
+	l := NewRateLimiter(WithRequestsPerMinute(1))
+	if _, err := l.Wait(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := l.Wait(ctx, 0)
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 100*time.Millisecond {
+		t.Fatalf("err = %v after %v; want an immediate deadline error", err, time.Since(start))
+	}
+	// The failed wait gave its reservation back.
+	if got := l.reserve(0); got > time.Minute {
+		t.Fatalf("wait = %v; the canceled reservation was kept", got)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var observed []string
+	l := NewRateLimiter(
+		WithRequestsPerMinute(600),
+		WithTokensPerMinute(1000),
+		WithRateLimitObserver(func(_ context.Context, model string, _ time.Duration) {
+			observed = append(observed, model)
+		}),
+	)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	// Two providers share the limiter and therefore the quota.
+	a := blades.WrapModel(&echoModel{answer: "a"}, RateLimit(l))
+	b := blades.WrapModel(&stubModel{name: "b"}, RateLimit(l))
+	res, err := a.Generate(context.Background(), cacheRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait, ok := res.Message.Metadata[MetadataKeyRateLimitWait]; !ok || wait != int64(0) {
+		t.Fatalf("wait metadata = %v", res.Message.Metadata)
+	}
+	for _, err := range b.NewStreaming(context.Background(), cacheRequest()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(observed) != 2 || observed[0] != "echo" || observed[1] != "b" {
+		t.Fatalf("observed = %v", observed)
+	}
+	// echoModel reported 8 tokens, so the estimate was corrected to its usage.
+	if level := l.tokens.level; level <= 0 || level >= 1000 {
+		t.Fatalf("token level = %v", level)
+	}
+}
@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/go-kratos/blades"
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// CanonicalRequest is the part of a model request that determines the
+// response. Volatile fields such as message IDs, authors and usage are left
+// out, so the same conversation produces the same value across runs.
+type CanonicalRequest struct {
+	Model        string                   `json:"model"`
+	Instruction  *CanonicalMessage        `json:"instruction,omitempty"`
+	Messages     []CanonicalMessage       `json:"messages"`
+	Tools        []CanonicalTool          `json:"tools,omitempty"`
+	InputSchema  *jsonschema.Schema       `json:"inputSchema,omitempty"`
+	OutputSchema *jsonschema.Schema       `json:"outputSchema,omitempty"`
+	Generation   *blades.GenerationConfig `json:"generation,omitempty"`
+	ToolChoice   *blades.ToolChoice       `json:"toolChoice,omitempty"`
+}
+
+// CanonicalMessage is the role and the JSON encoded parts of a message.
+type CanonicalMessage struct {
+	Role  blades.Role     `json:"role"`
+	Parts json.RawMessage `json:"parts"`
+}
+
+// CanonicalTool is the definition of a tool offered to the model.
+type CanonicalTool struct {
+	Name         string             `json:"name"`
+	Description  string             `json:"description,omitempty"`
+	InputSchema  *jsonschema.Schema `json:"inputSchema,omitempty"`
+	OutputSchema *jsonschema.Schema `json:"outputSchema,omitempty"`
+}
+
+func newCanonicalMessage(m *blades.Message) (CanonicalMessage, error) {
+	data, err := json.Marshal(blades.Message{Parts: m.Parts})
+	if err != nil {
+		return CanonicalMessage{}, err
+	}
+	var v struct {
+		Parts json.RawMessage `json:"parts"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return CanonicalMessage{}, err
+	}
+	return CanonicalMessage{Role: m.Role, Parts: v.Parts}, nil
+}
+
+// NewCanonicalRequest returns the canonical form of a request sent to the named model.
+func NewCanonicalRequest(model string, req *blades.ModelRequest) (*CanonicalRequest, error) {
+	c := &CanonicalRequest{
+		Model:        model,
+		Messages:     make([]CanonicalMessage, 0, len(req.Messages)),
+		InputSchema:  req.InputSchema,
+		OutputSchema: req.OutputSchema,
+		Generation:   req.Generation,
+		ToolChoice:   req.ToolChoice,
+	}
+	if req.Instruction != nil {
+		instruction, err := newCanonicalMessage(req.Instruction)
+		if err != nil {
+			return nil, err
+		}
+		c.Instruction = &instruction
+	}
+	for _, m := range req.Messages {
+		if m == nil {
+			continue
+		}
+		message, err := newCanonicalMessage(m)
+		if err != nil {
+			return nil, err
+		}
+		c.Messages = append(c.Messages, message)
+	}
+	for _, tool := range req.Tools {
+		c.Tools = append(c.Tools, CanonicalTool{
+			Name:         tool.Name(),
+			Description:  tool.Description(),
+			InputSchema:  tool.InputSchema(),
+			OutputSchema: tool.OutputSchema(),
+		})
+	}
+	return c, nil
+}
+
+// RequestKey returns a stable hash of the parts of a request that determine
+// the response of the named model: the instruction, the roles and parts of
+// the messages, the tool definitions, the schemas, the generation settings
+// and the tool choice. Message IDs, authors,
+// statuses, usage and metadata do not affect the key.
+func RequestKey(model string, req *blades.ModelRequest) (string, error) {
+	c, err := NewCanonicalRequest(model, req)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-kratos/blades"
+)
+
+// RetryOption configures Retry.
+type RetryOption func(*retrier)
+
+// WithRetryable sets the function deciding which errors are retried. The
+// default is IsRetryable.
+func WithRetryable(retryable func(error) bool) RetryOption {
+	return func(r *retrier) {
+		r.retryable = retryable
+	}
+}
+
+// WithRetryBackoff sets the wait before the first retry and the longest wait
+// between retries. The wait grows by a factor of 1.6 after every retry, with
+// 20% jitter. The defaults are 100ms and 15s.
+func WithRetryBackoff(base, max time.Duration) RetryOption {
+	return func(r *retrier) {
+		if base > 0 {
+			r.baseDelay = base
+		}
+		if max > 0 {
+			r.maxDelay = max
+		}
+	}
+}
+
+// Retry returns a model middleware that retries failed model calls with
+// exponential backoff. attempts is the total number of attempts, including
+// the first one; values below 1 mean a single attempt. By default only errors
+// reported by IsRetryable are retried; use WithRetryable to change that.
+// A done context ends the backoff wait at once with the context's error.
+//
+// A streaming call is retried only when it fails before yielding its first
+// response, so callers never see a response twice.
+func Retry(attempts int, opts ...RetryOption) blades.ModelMiddleware {
+	r := &retrier{
+		attempts:  max(attempts, 1),
+		retryable: IsRetryable,
+		baseDelay: 100 * time.Millisecond,
+		maxDelay:  15 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return func(next blades.ModelProvider) blades.ModelProvider {
+		generate := func(ctx context.Context, req *blades.ModelRequest) (*blades.ModelResponse, error) {
+			var res *blades.ModelResponse
+			err := r.do(ctx, func(ctx context.Context) error {
+				var err error
+				res, err = next.Generate(ctx, req)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			return res, nil
+		}
+		streaming := func(ctx context.Context, req *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
+			return func(yield func(*blades.ModelResponse, error) bool) {
+				var (
+					started bool
+					stopped bool
+					failed  error
+				)
+				err := r.do(ctx, func(ctx context.Context) error {
+					for res, err := range next.NewStreaming(ctx, req) {
+						if err != nil {
+							if started {
+								// Responses already yielded cannot be taken
+								// back, so the failure ends the retries.
+								failed = err
+								return nil
+							}
+							return err
+						}
+						started = true
+						if !yield(res, nil) {
+							stopped = true
+							return nil
+						}
+					}
+					return nil
+				})
+				if failed != nil {
+					err = failed
+				}
+				if err != nil && !stopped {
+					yield(nil, err)
+				}
+			}
+		}
+		return blades.NewModelWrapper(next, generate, streaming)
+	}
+}
+
+// retrier runs a call until it succeeds, fails with an error that is not
+// retryable, or runs out of attempts.
+type retrier struct {
+	attempts  int
+	retryable func(error) bool
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+func (r *retrier) do(ctx context.Context, fn func(context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= r.attempts || !r.retryable(err) {
+			return err
+		}
+		timer := time.NewTimer(r.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the wait after the given failed attempt, counted from 1.
+func (r *retrier) backoff(attempt int) time.Duration {
+	delay := float64(r.baseDelay)
+	for i := 1; i < attempt && delay < float64(r.maxDelay); i++ {
+		delay *= 1.6
+	}
+	delay = min(delay, float64(r.maxDelay))
+	return time.Duration(delay * (0.8 + 0.4*rand.Float64()))
+}
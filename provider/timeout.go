@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kratos/blades"
+)
+
+// Timeout returns a model middleware that bounds every model call to d. For
+// streaming calls the timeout covers the whole stream.
+func Timeout(d time.Duration) blades.ModelMiddleware {
+	return func(next blades.ModelProvider) blades.ModelProvider {
+		generate := func(ctx context.Context, req *blades.ModelRequest) (*blades.ModelResponse, error) {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next.Generate(ctx, req)
+		}
+		streaming := func(ctx context.Context, req *blades.ModelRequest) blades.Generator[*blades.ModelResponse, error] {
+			return func(yield func(*blades.ModelResponse, error) bool) {
+				ctx, cancel := context.WithTimeout(ctx, d)
+				defer cancel()
+				for res, err := range next.NewStreaming(ctx, req) {
+					if !yield(res, err) || err != nil {
+						return
+					}
+				}
+			}
+		}
+		return blades.NewModelWrapper(next, generate, streaming)
+	}
+}
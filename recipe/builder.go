@@ -1,6 +1,7 @@
 package recipe
 
 import (
+	"cmp"
 	"fmt"
 
 	"github.com/go-kratos/blades"
@@ -132,6 +133,9 @@ func buildSingleAgent(spec *AgentSpec, params map[string]any, o *buildOptions) (
 	if o.contextEnabled != nil {
 		agentOpts = append(agentOpts, blades.WithContext(*o.contextEnabled))
 	}
+	if spec.Generation != nil {
+		agentOpts = append(agentOpts, blades.WithGenerationConfig(generationConfig(spec.Generation)))
+	}
 
 	// Resolve external tools
 	resolvedTools, err := resolveTools(spec.Tools, o)
@@ -159,11 +163,12 @@ func buildSingleAgent(spec *AgentSpec, params map[string]any, o *buildOptions) (
 }
 
 // buildSubAgent creates a blades.Agent from a SubAgentSpec.
-// parentModel is the fallback model name from the parent spec.
-func buildSubAgent(sub *SubAgentSpec, parentModel string, params map[string]any, o *buildOptions) (blades.Agent, error) {
+// The model and generation settings of the parent spec are used when the
+// sub-agent sets none.
+func buildSubAgent(sub *SubAgentSpec, parent *AgentSpec, params map[string]any, o *buildOptions) (blades.Agent, error) {
 	modelName := sub.Model
 	if modelName == "" {
-		modelName = parentModel
+		modelName = parent.Model
 	}
 	if modelName == "" {
 		return nil, fmt.Errorf("recipe: sub_agent %q has no model and parent has no model", sub.Name)
@@ -207,6 +212,9 @@ func buildSubAgent(sub *SubAgentSpec, parentModel string, params map[string]any,
 	if o.contextEnabled != nil {
 		agentOpts = append(agentOpts, blades.WithContext(*o.contextEnabled))
 	}
+	if generation := cmp.Or(sub.Generation, parent.Generation); generation != nil {
+		agentOpts = append(agentOpts, blades.WithGenerationConfig(generationConfig(generation)))
+	}
 
 	resolvedTools, err := resolveTools(sub.Tools, o)
 	if err != nil {
@@ -240,7 +248,7 @@ func buildSubAgent(sub *SubAgentSpec, parentModel string, params map[string]any,
 func buildSequentialAgent(spec *AgentSpec, params map[string]any, o *buildOptions) (blades.Agent, error) {
 	subAgents := make([]blades.Agent, 0, len(spec.SubAgents))
 	for i := range spec.SubAgents {
-		agent, err := buildSubAgent(&spec.SubAgents[i], spec.Model, params, o)
+		agent, err := buildSubAgent(&spec.SubAgents[i], spec, params, o)
 		if err != nil {
 			return nil, fmt.Errorf("recipe %q: %w", spec.Name, err)
 		}
@@ -257,7 +265,7 @@ func buildSequentialAgent(spec *AgentSpec, params map[string]any, o *buildOption
 func buildParallelAgent(spec *AgentSpec, params map[string]any, o *buildOptions) (blades.Agent, error) {
 	subAgents := make([]blades.Agent, 0, len(spec.SubAgents))
 	for i := range spec.SubAgents {
-		agent, err := buildSubAgent(&spec.SubAgents[i], spec.Model, params, o)
+		agent, err := buildSubAgent(&spec.SubAgents[i], spec, params, o)
 		if err != nil {
 			return nil, fmt.Errorf("recipe %q: %w", spec.Name, err)
 		}
@@ -276,7 +284,7 @@ func buildParallelAgent(spec *AgentSpec, params map[string]any, o *buildOptions)
 func buildLoopAgent(spec *AgentSpec, params map[string]any, o *buildOptions) (blades.Agent, error) {
 	subAgents := make([]blades.Agent, 0, len(spec.SubAgents))
 	for i := range spec.SubAgents {
-		agent, err := buildSubAgent(&spec.SubAgents[i], spec.Model, params, o)
+		agent, err := buildSubAgent(&spec.SubAgents[i], spec, params, o)
 		if err != nil {
 			return nil, fmt.Errorf("recipe %q: %w", spec.Name, err)
 		}
@@ -300,7 +308,7 @@ func buildToolAgent(spec *AgentSpec, params map[string]any, o *buildOptions) (bl
 	// Build each sub-agent as an agent, then wrap as a tool
 	agentTools := make([]tools.Tool, 0, len(spec.SubAgents))
 	for i := range spec.SubAgents {
-		subAgent, err := buildSubAgent(&spec.SubAgents[i], spec.Model, params, o)
+		subAgent, err := buildSubAgent(&spec.SubAgents[i], spec, params, o)
 		if err != nil {
 			return nil, fmt.Errorf("recipe %q: %w", spec.Name, err)
 		}
@@ -336,6 +344,9 @@ func buildToolAgent(spec *AgentSpec, params map[string]any, o *buildOptions) (bl
 	if o.contextEnabled != nil {
 		agentOpts = append(agentOpts, blades.WithContext(*o.contextEnabled))
 	}
+	if spec.Generation != nil {
+		agentOpts = append(agentOpts, blades.WithGenerationConfig(generationConfig(spec.Generation)))
+	}
 
 	// Resolve middlewares
 	middlewares, err := resolveMiddlewares(spec.Middlewares, o)
@@ -386,3 +397,17 @@ func resolveMiddlewares(specs []MiddlewareSpec, o *buildOptions) ([]blades.Middl
 	}
 	return resolved, nil
 }
+
+// generationConfig converts a GenerationSpec into a blades.GenerationConfig.
+func generationConfig(spec *GenerationSpec) blades.GenerationConfig {
+	return blades.GenerationConfig{
+		Temperature:      spec.Temperature,
+		TopP:             spec.TopP,
+		TopK:             spec.TopK,
+		MaxOutputTokens:  spec.MaxOutputTokens,
+		StopSequences:    spec.StopSequences,
+		Seed:             spec.Seed,
+		FrequencyPenalty: spec.FrequencyPenalty,
+		PresencePenalty:  spec.PresencePenalty,
+	}
+}
@@ -35,6 +35,7 @@ type captureRequestModel struct {
 	messages    []*blades.Message
 	instruction string
 	toolNames   []string
+	generation  *blades.GenerationConfig
 }
 
 func (m *captureRequestModel) Name() string { return m.name }
@@ -48,6 +49,7 @@ func (m *captureRequestModel) Generate(_ context.Context, req *blades.ModelReque
 	if req.Instruction != nil {
 		m.instruction = req.Instruction.Text()
 	}
+	m.generation = req.Generation
 	msg := blades.NewAssistantMessage(blades.StatusCompleted)
 	text := m.response
 	if text == "" {
@@ -2417,3 +2419,60 @@ func TestE2ELoopAgentRunsSubAgents(t *testing.T) {
 		t.Fatal("expected model to have been called")
 	}
 }
+
+func TestBuildAppliesGeneration(t *testing.T) {
+	spec, err := Parse([]byte(`
+version: "1.0"
+name: pipeline
+instruction: Run the pipeline.
+model: writer
+execution: sequential
+generation:
+  temperature: 0.9
+  max_output_tokens: 512
+sub_agents:
+  - name: draft
+    instruction: Draft it.
+  - name: check
+    instruction: Check it.
+    model: checker
+    generation:
+      temperature: 0
+      stop_sequences: ["END"]
+`))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	writer := &captureRequestModel{name: "writer"}
+	checker := &captureRequestModel{name: "checker"}
+	registry := NewModelRegistry()
+	registry.Register("writer", writer)
+	registry.Register("checker", checker)
+	agent, err := Build(spec, WithModelRegistry(registry))
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	if _, err := blades.NewRunner(agent).Run(context.Background(), blades.UserMessage("go")); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	// The draft sub-agent inherits the settings of the recipe.
+	if g := writer.generation; g == nil || *g.Temperature != 0.9 || *g.MaxOutputTokens != 512 {
+		t.Fatalf("draft generation = %+v", g)
+	}
+	if g := checker.generation; g == nil || *g.Temperature != 0 || g.MaxOutputTokens != nil || !slices.Equal(g.StopSequences, []string{"END"}) {
+		t.Fatalf("check generation = %+v", g)
+	}
+}
+
+func TestValidateGeneration(t *testing.T) {
+	spec := &AgentSpec{
+		Version:     "1.0",
+		Name:        "hot",
+		Model:       "gpt-4o",
+		Instruction: "Be creative.",
+		Generation:  &GenerationSpec{TopP: blades.Ptr(1.5)},
+	}
+	if err := Validate(spec); err == nil || !strings.Contains(err.Error(), "top_p") {
+		t.Fatalf("expected top_p error, got %v", err)
+	}
+}
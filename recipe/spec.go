@@ -77,6 +77,27 @@ type ContextSpec struct {
 	Model string `yaml:"model,omitempty"`
 }
 
+// GenerationSpec sets the generation settings of the agent's model requests.
+// It maps to blades.GenerationConfig; omitted fields keep the settings of the
+// model provider.
+//
+// Example:
+//
+//	generation:
+//	  temperature: 0.2
+//	  max_output_tokens: 1024
+//	  stop_sequences: ["END"]
+type GenerationSpec struct {
+	Temperature      *float64 `yaml:"temperature,omitempty"`
+	TopP             *float64 `yaml:"top_p,omitempty"`
+	TopK             *int64   `yaml:"top_k,omitempty"`
+	MaxOutputTokens  *int64   `yaml:"max_output_tokens,omitempty"`
+	StopSequences    []string `yaml:"stop_sequences,omitempty"`
+	Seed             *int64   `yaml:"seed,omitempty"`
+	FrequencyPenalty *float64 `yaml:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64 `yaml:"presence_penalty,omitempty"`
+}
+
 // MiddlewareSpec declares a single middleware to apply to an agent.
 // The middleware is resolved by name from the MiddlewareRegistry at build time,
 // with Options passed as-is to the factory function.
@@ -109,6 +130,7 @@ type AgentSpec struct {
 	OutputKey     string           `yaml:"output_key,omitempty"`
 	MaxIterations int              `yaml:"max_iterations,omitempty"`
 	Context       *ContextSpec     `yaml:"context,omitempty"`
+	Generation    *GenerationSpec  `yaml:"generation,omitempty"`
 	Middlewares   []MiddlewareSpec `yaml:"middlewares,omitempty"`
 }
 
@@ -124,6 +146,7 @@ type SubAgentSpec struct {
 	OutputKey     string           `yaml:"output_key,omitempty"`
 	MaxIterations int              `yaml:"max_iterations,omitempty"`
 	Context       *ContextSpec     `yaml:"context,omitempty"`
+	Generation    *GenerationSpec  `yaml:"generation,omitempty"`
 	Middlewares   []MiddlewareSpec `yaml:"middlewares,omitempty"`
 }
 
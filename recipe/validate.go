@@ -56,6 +56,9 @@ func Validate(spec *AgentSpec) error {
 	if err := validateContextSpec(spec.Context); err != nil {
 		return fmt.Errorf("recipe %q: context: %w", spec.Name, err)
 	}
+	if err := validateGenerationSpec(spec.Generation); err != nil {
+		return fmt.Errorf("recipe %q: generation: %w", spec.Name, err)
+	}
 	if err := validateMiddlewares(fmt.Sprintf("recipe %q", spec.Name), spec.Middlewares); err != nil {
 		return err
 	}
@@ -122,6 +125,25 @@ func validateContextSpec(spec *ContextSpec) error {
 	return nil
 }
 
+func validateGenerationSpec(spec *GenerationSpec) error {
+	if spec == nil {
+		return nil
+	}
+	if spec.Temperature != nil && *spec.Temperature < 0 {
+		return fmt.Errorf("temperature must be >= 0")
+	}
+	if spec.TopP != nil && (*spec.TopP < 0 || *spec.TopP > 1) {
+		return fmt.Errorf("top_p must be between 0 and 1")
+	}
+	if spec.TopK != nil && *spec.TopK < 1 {
+		return fmt.Errorf("top_k must be > 0")
+	}
+	if spec.MaxOutputTokens != nil && *spec.MaxOutputTokens < 1 {
+		return fmt.Errorf("max_output_tokens must be > 0")
+	}
+	return nil
+}
+
 func validateParameters(params []ParameterSpec) error {
 	seen := make(map[string]bool, len(params))
 	for _, p := range params {
@@ -170,6 +192,9 @@ func validateSubAgent(sub *SubAgentSpec, index int) error {
 	if err := validateContextSpec(sub.Context); err != nil {
 		return fmt.Errorf("sub_agent %q: context: %w", sub.Name, err)
 	}
+	if err := validateGenerationSpec(sub.Generation); err != nil {
+		return fmt.Errorf("sub_agent %q: generation: %w", sub.Name, err)
+	}
 	if err := validateMiddlewares(fmt.Sprintf("sub_agent %q", sub.Name), sub.Middlewares); err != nil {
 		return err
 	}
@@ -29,19 +29,57 @@ func WithInvocationID(invocationID string) RunOption {
 	}
 }
 
+// WithToolDecisions supplies decisions for tool calls that paused the run for
+// approval. Use it together with WithResume(true) and the paused invocation ID.
+func WithToolDecisions(decisions ...ToolDecision) RunOption {
+	return func(r *RunOptions) {
+		r.ToolDecisions = append(r.ToolDecisions, decisions...)
+	}
+}
+
 // RunOptions holds configuration options for running the agent.
 type RunOptions struct {
-	Session      Session
-	Resume       bool
-	InvocationID string
+	Session       Session
+	Resume        bool
+	InvocationID  string
+	ToolDecisions []ToolDecision
 }
 
 // RunnerOption configures a Runner at construction time.
 type RunnerOption func(*Runner)
 
+// WithTokenBudget limits the tokens each run may consume. A run that goes over
+// budget fails with a TokenBudgetError.
+func WithTokenBudget(budget TokenBudget) RunnerOption {
+	return func(r *Runner) {
+		r.budget = budget
+	}
+}
+
+// WithArtifactStore makes the store available to the agents and tools of every
+// run, see ArtifactsFromContext.
+func WithArtifactStore(store ArtifactStore) RunnerOption {
+	return func(r *Runner) {
+		r.artifacts = store
+	}
+}
+
+// WithDataPartOffload moves DataParts of at least minBytes bytes out of the
+// session history into the artifact store set with WithArtifactStore, see
+// NewArtifactSession.
+func WithDataPartOffload(minBytes int) RunnerOption {
+	return func(r *Runner) {
+		r.offloadBytes = minBytes
+	}
+}
+
 // Runner is responsible for executing a Runnable agent within a session context.
+// It accounts the token usage of every run, see UsageReport.
 type Runner struct {
-	rootAgent Agent
+	rootAgent    Agent
+	budget       TokenBudget
+	artifacts    ArtifactStore
+	offloadBytes int
 }
 
 // NewRunner creates a new Runner with the given agent and options.
@@ -55,19 +93,44 @@ func NewRunner(rootAgent Agent, opts ...RunnerOption) *Runner {
 	return r
 }
 
+// prepareSession wraps the session of a run to offload large DataParts.
+func (r *Runner) prepareSession(o *RunOptions) {
+	if r.artifacts != nil && r.offloadBytes > 0 {
+		o.Session = NewArtifactSession(o.Session, r.artifacts, r.offloadBytes)
+	}
+}
+
+// runContext returns the context the root agent runs in.
+func (r *Runner) runContext(ctx context.Context, session Session, tracker *usageTracker) context.Context {
+	if r.artifacts != nil {
+		ctx = NewArtifactContext(ctx, r.artifacts)
+	}
+	return newUsageContext(NewSessionContext(ctx, session), tracker)
+}
+
 // buildInvocation constructs an Invocation object for the given message and options.
 func (r *Runner) buildInvocation(message *Message, stream bool, o *RunOptions) *Invocation {
+	var decisions map[string]ToolDecision
+	if len(o.ToolDecisions) > 0 {
+		decisions = make(map[string]ToolDecision, len(o.ToolDecisions))
+		for _, decision := range o.ToolDecisions {
+			decisions[decision.ID] = decision
+		}
+	}
 	return &Invocation{
-		ID:        o.InvocationID,
-		Session:   o.Session,
-		Resume:    o.Resume,
-		Stream:    stream,
-		Message:   message,
-		committed: new(atomic.Bool),
+		ID:            o.InvocationID,
+		Session:       o.Session,
+		Resume:        o.Resume,
+		Stream:        stream,
+		Message:       message,
+		ToolDecisions: decisions,
+		committed:     new(atomic.Bool),
 	}
 }
 
 // Run executes the agent with the provided prompt and options within the session context.
+// The usage report of the run is attached to the final message (see MessageUsage)
+// and stored in the session state.
 func (r *Runner) Run(ctx context.Context, message *Message, opts ...RunOption) (*Message, error) {
 	o := &RunOptions{
 		Session:      NewSession(),
@@ -76,25 +139,37 @@ func (r *Runner) Run(ctx context.Context, message *Message, opts ...RunOption) (
 	for _, opt := range opts {
 		opt(o)
 	}
+	r.prepareSession(o)
 	var (
 		err    error
 		output *Message
 	)
 	invocation := r.buildInvocation(message, false, o)
-	runCtx := NewSessionContext(ctx, o.Session)
+	tracker := newUsageTracker(invocation.ID, o.Session, r.budget)
+	runCtx := r.runContext(ctx, o.Session, tracker)
 	iter := r.rootAgent.Run(runCtx, invocation)
 	for output, err = range iter {
 		if err != nil {
+			tracker.save(o.Session)
 			return nil, err
 		}
 	}
+	report := tracker.save(o.Session)
 	if output == nil {
 		return nil, ErrNoFinalResponse
 	}
+	// The usage report goes on a copy, so the message kept in the session
+	// history is not modified.
+	output = output.Clone()
+	if output.Metadata == nil {
+		output.Metadata = make(map[string]any)
+	}
+	output.Metadata[MetadataKeyUsage] = report
 	return output, nil
 }
 
 // RunStream executes the agent in a streaming manner, yielding messages as they are produced.
+// The usage report of the run is stored in the session state once the stream ends.
 func (r *Runner) RunStream(ctx context.Context, message *Message, opts ...RunOption) Generator[*Message, error] {
 	o := &RunOptions{
 		Session:      NewSession(),
@@ -103,9 +178,12 @@ func (r *Runner) RunStream(ctx context.Context, message *Message, opts ...RunOpt
 	for _, opt := range opts {
 		opt(o)
 	}
+	r.prepareSession(o)
 	invocation := r.buildInvocation(message, true, o)
-	runCtx := NewSessionContext(ctx, o.Session)
 	return func(yield func(*Message, error) bool) {
+		tracker := newUsageTracker(invocation.ID, o.Session, r.budget)
+		defer tracker.save(o.Session)
+		runCtx := r.runContext(ctx, o.Session, tracker)
 		iter := r.rootAgent.Run(runCtx, invocation)
 		for output, err := range iter {
 			if err != nil {
@@ -2,6 +2,9 @@ package blades
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/go-kratos/kit/container/maps"
 	"github.com/go-kratos/kit/container/slices"
@@ -20,6 +23,24 @@ type Session interface {
 	History(ctx context.Context) ([]*Message, error)
 }
 
+// SessionInfo describes a session persisted by a SessionStore.
+type SessionInfo struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// SessionStore persists sessions so they survive process restarts.
+type SessionStore interface {
+	// Get returns the session with the given ID, restoring its state and
+	// history from the store. A new empty session is created when none exists.
+	Get(ctx context.Context, id string) (Session, error)
+	// List returns the sessions known to the store, most recently updated first.
+	List(ctx context.Context) ([]SessionInfo, error)
+	// Delete removes the session and its history from the store.
+	Delete(ctx context.Context, id string) error
+}
+
 // SessionOption configures a Session at construction time.
 type SessionOption func(*sessionInMemory)
 
@@ -44,6 +65,16 @@ func NewSession(opts ...SessionOption) Session {
 
 type ctxSessionKey struct{}
 
+// ValidateSessionID returns an error wrapping ErrInvalidSessionID when id
+// cannot be used as a single file or directory name, as required by stores
+// that keep each session in its own file or directory.
+func ValidateSessionID(id string) error {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return fmt.Errorf("%w: %q", ErrInvalidSessionID, id)
+	}
+	return nil
+}
+
 // NewSessionContext returns a new Context that carries the session value.
 func NewSessionContext(ctx context.Context, session Session) context.Context {
 	return context.WithValue(ctx, ctxSessionKey{}, session)
@@ -0,0 +1,23 @@
+package filestore
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-kratos/blades"
+)
+
+const (
+	recordCreate  = "create"
+	recordMessage = "message"
+	recordState   = "state"
+)
+
+// record is a single line of a session file.
+type record struct {
+	Kind    string          `json:"kind"`
+	Time    time.Time       `json:"time"`
+	Message *blades.Message `json:"message,omitempty"`
+	Key     string          `json:"key,omitempty"`
+	Value   json.RawMessage `json:"value,omitempty"`
+}
@@ -0,0 +1,296 @@
+// Package filestore provides a blades.SessionStore that persists every session
+// as an append-only JSON Lines file, so history and state survive restarts.
+package filestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/blades"
+)
+
+const fileExt = ".jsonl"
+
+// Store is a blades.SessionStore backed by a directory of JSONL files.
+// Each session is stored in <dir>/<id>.jsonl; messages and state changes are
+// appended as they happen, so a session can be restored after a crash.
+type Store struct {
+	dir      string
+	opts     []blades.SessionOption
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+var _ blades.SessionStore = (*Store)(nil)
+
+// NewStore creates a Store rooted at dir, creating the directory if needed.
+// The SessionOptions (e.g. blades.WithContextCompressor) are applied to every
+// session returned by Get.
+func NewStore(dir string, opts ...blades.SessionOption) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("filestore: mkdir: %w", err)
+	}
+	return &Store{
+		dir:      dir,
+		opts:     opts,
+		sessions: make(map[string]*session),
+	}, nil
+}
+
+// Get returns the session with the given ID, loading it from disk when it is
+// not already open. A new session file is created when none exists.
+func (s *Store) Get(ctx context.Context, id string) (blades.Session, error) {
+	if err := blades.ValidateSessionID(id); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess, ok := s.sessions[id]; ok {
+		return sess, nil
+	}
+	sess := &session{
+		id:   id,
+		path: s.path(id),
+		base: blades.NewSession(s.opts...),
+	}
+	if err := sess.load(ctx); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err := sess.write(record{Kind: recordCreate}); err != nil {
+			return nil, err
+		}
+	}
+	s.sessions[id] = sess
+	return sess, nil
+}
+
+// List returns the sessions stored on disk, most recently updated first.
+func (s *Store) List(ctx context.Context) ([]blades.SessionInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("filestore: read dir: %w", err)
+	}
+	infos := make([]blades.SessionInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), fileExt) {
+			continue
+		}
+		info, err := s.stat(strings.TrimSuffix(entry.Name(), fileExt))
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].UpdatedAt.After(infos[j].UpdatedAt)
+	})
+	return infos, nil
+}
+
+// Delete removes the session file from disk and drops the open session.
+// Sessions still held by callers are invalidated: their writes fail with
+// blades.ErrSessionDeleted instead of recreating the file.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	if err := blades.ValidateSessionID(id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess, ok := s.sessions[id]; ok {
+		delete(s.sessions, id)
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		sess.deleted = true
+	}
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("filestore: delete %s: %w", id, err)
+	}
+	return nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+fileExt)
+}
+
+// stat reads the creation record and the modification time of a session file.
+func (s *Store) stat(id string) (blades.SessionInfo, error) {
+	f, err := os.Open(s.path(id))
+	if err != nil {
+		return blades.SessionInfo{}, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return blades.SessionInfo{}, err
+	}
+	var first record
+	if err := json.NewDecoder(f).Decode(&first); err != nil {
+		return blades.SessionInfo{}, err
+	}
+	return blades.SessionInfo{
+		ID:        id,
+		CreatedAt: first.Time,
+		UpdatedAt: fi.ModTime(),
+	}, nil
+}
+
+// session is a blades.Session that mirrors every change into its JSONL file.
+// An in-memory session holds the loaded history and applies compression.
+type session struct {
+	mu   sync.Mutex
+	id   string
+	path string
+	base blades.Session
+	// err records a failed state write; SetState cannot return errors,
+	// so the failure is reported by the next Append.
+	err error
+	// deleted is set once the store deletes the session file.
+	deleted bool
+}
+
+func (s *session) ID() string {
+	return s.id
+}
+
+func (s *session) State() blades.State {
+	return s.base.State()
+}
+
+func (s *session) SetState(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.base.SetState(key, value)
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = s.write(record{Kind: recordState, Key: key, Value: data})
+	}
+	if err != nil && s.err == nil {
+		s.err = fmt.Errorf("filestore: set state %q: %w", key, err)
+	}
+}
+
+func (s *session) Append(ctx context.Context, message *blades.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		err := s.err
+		s.err = nil
+		return err
+	}
+	if err := s.write(record{Kind: recordMessage, Message: message}); err != nil {
+		return err
+	}
+	return s.base.Append(ctx, message)
+}
+
+func (s *session) History(ctx context.Context) ([]*blades.Message, error) {
+	return s.base.History(ctx)
+}
+
+// write appends a single record to the session file and syncs it to disk.
+func (s *session) write(r record) error {
+	if s.deleted {
+		return fmt.Errorf("filestore: write %s: %w", s.id, blades.ErrSessionDeleted)
+	}
+	r.Time = time.Now()
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("filestore: marshal %s record: %w", r.Kind, err)
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("filestore: open %s: %w", s.id, err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("filestore: write %s: %w", s.id, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("filestore: sync %s: %w", s.id, err)
+	}
+	return f.Close()
+}
+
+// load replays the session file into the in-memory session. A truncated
+// trailing record, left behind by a crash during write, is cut off the file
+// so that later records are appended after the last complete one. A file
+// without a complete record gets its creation record written again.
+func (s *session) load(ctx context.Context) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	var end int64
+	for {
+		var r record
+		if err := dec.Decode(&r); err != nil {
+			if errors.Is(err, io.EOF) && end > 0 {
+				return nil
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return s.truncate(end)
+			}
+			return fmt.Errorf("filestore: decode %s: %w", s.id, err)
+		}
+		end = dec.InputOffset()
+		switch r.Kind {
+		case recordMessage:
+			if r.Message == nil {
+				continue
+			}
+			if err := s.base.Append(ctx, r.Message); err != nil {
+				return err
+			}
+		case recordState:
+			var value any
+			if err := json.Unmarshal(r.Value, &value); err != nil {
+				return fmt.Errorf("filestore: decode state %q: %w", r.Key, err)
+			}
+			s.base.SetState(r.Key, value)
+		}
+	}
+}
+
+// truncate cuts the session file after the record ending at offset end and
+// terminates that record with a newline. When no record is complete, the
+// emptied file starts over with a creation record.
+func (s *session) truncate(end int64) error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("filestore: open %s: %w", s.id, err)
+	}
+	if err := f.Truncate(end); err != nil {
+		f.Close()
+		return fmt.Errorf("filestore: truncate %s: %w", s.id, err)
+	}
+	if end > 0 {
+		if _, err := f.WriteAt([]byte{'\n'}, end); err != nil {
+			f.Close()
+			return fmt.Errorf("filestore: truncate %s: %w", s.id, err)
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("filestore: sync %s: %w", s.id, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if end == 0 {
+		return s.write(record{Kind: recordCreate})
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package filestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/go-kratos/blades"
+)
+
+type lastCompressor struct{}
+
+func (lastCompressor) Compress(_ context.Context, messages []*blades.Message) ([]*blades.Message, error) {
+	if len(messages) <= 1 {
+		return messages, nil
+	}
+	return messages[len(messages)-1:], nil
+}
+
+func TestStoreSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	sess, err := store.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	sess.SetState("topic", "weather")
+	if err := sess.Append(ctx, blades.UserMessage("hi")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	tool := blades.NewAssistantMessage(blades.StatusCompleted)
+	tool.Role = blades.RoleTool
+	tool.Parts = append(tool.Parts, blades.ToolPart{ID: "call_1", Name: "lookup", Request: `{"q":1}`})
+	if err := sess.Append(ctx, tool); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	reopened, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("reopen store: %v", err)
+	}
+	restored, err := reopened.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("get restored: %v", err)
+	}
+	if got := restored.State()["topic"]; got != "weather" {
+		t.Fatalf("state topic = %v, want weather", got)
+	}
+	history, err := restored.History(ctx)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history len = %d, want 2", len(history))
+	}
+	if got := history[0].Text(); got != "hi" {
+		t.Fatalf("first message = %q, want hi", got)
+	}
+	part, ok := history[1].Parts[0].(blades.ToolPart)
+	if !ok {
+		t.Fatalf("part type = %T, want ToolPart", history[1].Parts[0])
+	}
+	if part.ID != "call_1" || part.Completed {
+		t.Fatalf("tool part = %+v, want pending call_1", part)
+	}
+}
+
+func TestStoreAppliesContextCompressor(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewStore(t.TempDir(), blades.WithContextCompressor(lastCompressor{}))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	sess, err := store.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	for _, text := range []string{"a", "b", "c"} {
+		if err := sess.Append(ctx, blades.UserMessage(text)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	history, err := sess.History(ctx)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 1 || history[0].Text() != "c" {
+		t.Fatalf("history = %v, want only c", history)
+	}
+}
+
+func TestStoreListAndDelete(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if _, err := store.Get(ctx, id); err != nil {
+			t.Fatalf("get %s: %v", id, err)
+		}
+	}
+	infos, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("list len = %d, want 2", len(infos))
+	}
+	for _, info := range infos {
+		if info.CreatedAt.IsZero() {
+			t.Fatalf("session %s has zero CreatedAt", info.ID)
+		}
+	}
+	if err := store.Delete(ctx, "a"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := os.Stat(store.path("a")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("session file still exists: %v", err)
+	}
+	infos, err = store.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(infos) != 1 || infos[0].ID != "b" {
+		t.Fatalf("list = %v, want [b]", infos)
+	}
+}
+
+func TestStoreRejectsInvalidID(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	for _, id := range []string{"", "..", "../escape", `a\b`} {
+		if _, err := store.Get(context.Background(), id); !errors.Is(err, blades.ErrInvalidSessionID) {
+			t.Fatalf("Get(%q) err = %v, want ErrInvalidSessionID", id, err)
+		}
+	}
+}
+
+func TestStoreIgnoresTruncatedRecord(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	sess, err := store.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if err := sess.Append(ctx, blades.UserMessage("kept")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	f, err := os.OpenFile(store.path("s1"), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := f.WriteString(`{"kind":"message","message":{"id":`); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	f.Close()
+
+	reopened, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("reopen store: %v", err)
+	}
+	restored, err := reopened.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("get restored: %v", err)
+	}
+	history, err := restored.History(ctx)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 1 || history[0].Text() != "kept" {
+		t.Fatalf("history = %v, want only kept", history)
+	}
+}
+
+func TestStoreAppendsAfterTruncatedRecord(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	sess, err := store.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if err := sess.Append(ctx, blades.UserMessage("first")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	f, err := os.OpenFile(store.path("s1"), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := f.WriteString(`{"kind":"message","message":{"id":`); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	f.Close()
+
+	// The session is reloaded after the crash and appended to.
+	for _, text := range []string{"second", "third"} {
+		reopened, err := NewStore(dir)
+		if err != nil {
+			t.Fatalf("reopen store: %v", err)
+		}
+		restored, err := reopened.Get(ctx, "s1")
+		if err != nil {
+			t.Fatalf("get restored: %v", err)
+		}
+		if err := restored.Append(ctx, blades.UserMessage(text)); err != nil {
+			t.Fatalf("append %s: %v", text, err)
+		}
+	}
+
+	reopened, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("reopen store: %v", err)
+	}
+	restored, err := reopened.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("get restored: %v", err)
+	}
+	history, err := restored.History(ctx)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	var texts []string
+	for _, m := range history {
+		texts = append(texts, m.Text())
+	}
+	if want := []string{"first", "second", "third"}; !slices.Equal(texts, want) {
+		t.Fatalf("history = %v, want %v", texts, want)
+	}
+}
+
+func TestStoreRestoresCreateRecord(t *testing.T) {
+	ctx := context.Background()
+	for name, content := range map[string]string{
+		"partial first record": `{"kind":"create","ti`,
+		"empty file":           "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := NewStore(dir)
+			if err != nil {
+				t.Fatalf("new store: %v", err)
+			}
+			if err := os.WriteFile(store.path("s1"), []byte(content), 0o644); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			sess, err := store.Get(ctx, "s1")
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			if err := sess.Append(ctx, blades.UserMessage("hi")); err != nil {
+				t.Fatalf("append: %v", err)
+			}
+			f, err := os.Open(store.path("s1"))
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			defer f.Close()
+			var first record
+			if err := json.NewDecoder(f).Decode(&first); err != nil {
+				t.Fatalf("decode first record: %v", err)
+			}
+			if first.Kind != recordCreate {
+				t.Fatalf("first record kind = %q, want %q", first.Kind, recordCreate)
+			}
+		})
+	}
+}
+
+func TestStoreDeleteInvalidatesHeldSession(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	held, err := store.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if err := store.Delete(ctx, "s1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	held.SetState("topic", "weather")
+	if err := held.Append(ctx, blades.UserMessage("hi")); !errors.Is(err, blades.ErrSessionDeleted) {
+		t.Fatalf("append after delete = %v, want %v", err, blades.ErrSessionDeleted)
+	}
+	if err := held.Append(ctx, blades.UserMessage("hi")); !errors.Is(err, blades.ErrSessionDeleted) {
+		t.Fatalf("append after delete = %v, want %v", err, blades.ErrSessionDeleted)
+	}
+	if _, err := os.Stat(store.path("s1")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("held session recreated the file: %v", err)
+	}
+
+	recreated, err := store.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("get recreated: %v", err)
+	}
+	if err := recreated.Append(ctx, blades.UserMessage("again")); err != nil {
+		t.Fatalf("append recreated: %v", err)
+	}
+	if infos, err := store.List(ctx); err != nil || len(infos) != 1 {
+		t.Fatalf("list = %v, %v", infos, err)
+	}
+}
@@ -2,6 +2,7 @@ package blades
 
 import (
 	"context"
+	"errors"
 	"testing"
 )
 
@@ -68,3 +69,14 @@ func (l *limitCompressor) Compress(_ context.Context, messages []*Message) ([]*M
 	}
 	return messages[len(messages)-l.max:], nil
 }
+
+func TestValidateSessionID(t *testing.T) {
+	for _, id := range []string{"", ".", "..", "../escape", `a\b`} {
+		if err := ValidateSessionID(id); !errors.Is(err, ErrInvalidSessionID) {
+			t.Fatalf("ValidateSessionID(%q) = %v, want ErrInvalidSessionID", id, err)
+		}
+	}
+	if err := ValidateSessionID("0b6e3c1a-session"); err != nil {
+		t.Fatalf("ValidateSessionID = %v, want nil", err)
+	}
+}
@@ -0,0 +1,71 @@
+package blades
+
+import "context"
+
+// ToolChoiceMode controls whether the model calls tools.
+type ToolChoiceMode string
+
+const (
+	// ToolChoiceAuto lets the model decide whether to call tools.
+	ToolChoiceAuto ToolChoiceMode = "auto"
+	// ToolChoiceNone forbids tool calls; the model answers with text.
+	ToolChoiceNone ToolChoiceMode = "none"
+	// ToolChoiceRequired makes the model call at least one tool.
+	ToolChoiceRequired ToolChoiceMode = "required"
+	// ToolChoiceTool makes the model call the tool named by ToolChoice.Name.
+	ToolChoiceTool ToolChoiceMode = "tool"
+)
+
+// ToolChoice controls which tools the model may call for a request.
+type ToolChoice struct {
+	Mode ToolChoiceMode `json:"mode"`
+	// Name is the tool the model must call when Mode is ToolChoiceTool.
+	Name string `json:"name,omitempty"`
+}
+
+// ToolChoiceNamed returns a ToolChoice that makes the model call the named tool.
+func ToolChoiceNamed(name string) ToolChoice {
+	return ToolChoice{Mode: ToolChoiceTool, Name: name}
+}
+
+// forcesCall reports whether the choice makes the model call a tool.
+func (c ToolChoice) forcesCall() bool {
+	return c.Mode == ToolChoiceRequired || c.Mode == ToolChoiceTool
+}
+
+// ToolChoiceProvider returns the tool choice for an iteration of the agent
+// loop, counted from 0, given the request about to be sent. Returning nil
+// leaves the choice to the provider's default, which is usually auto.
+type ToolChoiceProvider func(ctx context.Context, iteration int, req *ModelRequest) (*ToolChoice, error)
+
+// WithToolChoice sets the tool choice of the Agent's model requests. A
+// choice that forces a tool call applies until the model has called a tool in
+// the invocation, so the Agent can then answer; use WithToolChoiceProvider to
+// choose differently for each iteration.
+func WithToolChoice(choice ToolChoice) AgentOption {
+	return func(a *agent) {
+		a.toolChoice = &choice
+	}
+}
+
+// WithToolChoiceProvider sets a function that picks the tool choice for each
+// iteration of the agent loop, for example to require a tool first and forbid
+// tools for the final answer. It takes precedence over WithToolChoice.
+func WithToolChoiceProvider(p ToolChoiceProvider) AgentOption {
+	return func(a *agent) {
+		a.toolChoiceProvider = p
+	}
+}
+
+// nextToolChoice returns the tool choice of an iteration of the agent loop;
+// toolCalled reports whether tools were called earlier in the invocation.
+func (a *agent) nextToolChoice(ctx context.Context, iteration int, toolCalled bool, req *ModelRequest) (*ToolChoice, error) {
+	if a.toolChoiceProvider != nil {
+		return a.toolChoiceProvider(ctx, iteration, req)
+	}
+	if a.toolChoice == nil || (toolCalled && a.toolChoice.forcesCall()) {
+		return nil, nil
+	}
+	choice := *a.toolChoice
+	return &choice, nil
+}
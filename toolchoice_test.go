@@ -0,0 +1,89 @@
+package blades
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	bladestools "github.com/go-kratos/blades/tools"
+)
+
+// toolChoiceModel records the tool choice of every request.
+type toolChoiceModel struct {
+	multiToolModel
+	choices []*ToolChoice
+}
+
+func (m *toolChoiceModel) Generate(ctx context.Context, req *ModelRequest) (*ModelResponse, error) {
+	m.choices = append(m.choices, req.ToolChoice)
+	return m.multiToolModel.Generate(ctx, req)
+}
+
+func noopTool() bladestools.Tool {
+	return bladestools.NewTool("extract", "extracts fields", bladestools.HandleFunc(func(context.Context, string) (string, error) {
+		return "ok", nil
+	}))
+}
+
+func TestToolChoiceForcedUntilToolCalled(t *testing.T) {
+	t.Parallel()
+
+	model := &toolChoiceModel{multiToolModel: multiToolModel{calls: []string{"extract"}}}
+	if _, err := runToolAgent(t, model, WithTools(noopTool()), WithToolChoice(ToolChoiceNamed("extract"))); err != nil {
+		t.Fatal(err)
+	}
+	if len(model.choices) != 2 {
+		t.Fatalf("requests = %d, want 2", len(model.choices))
+	}
+	if got := model.choices[0]; got == nil || *got != ToolChoiceNamed("extract") {
+		t.Fatalf("first choice = %+v, want the extract tool", got)
+	}
+	if got := model.choices[1]; got != nil {
+		t.Fatalf("choice after the tool call = %+v, want nil", got)
+	}
+}
+
+func TestToolChoiceNoneIsKept(t *testing.T) {
+	t.Parallel()
+
+	model := &toolChoiceModel{multiToolModel: multiToolModel{calls: []string{"extract"}}}
+	if _, err := runToolAgent(t, model, WithTools(noopTool()), WithToolChoice(ToolChoice{Mode: ToolChoiceNone})); err != nil {
+		t.Fatal(err)
+	}
+	for i, choice := range model.choices {
+		if choice == nil || choice.Mode != ToolChoiceNone {
+			t.Fatalf("choice %d = %+v, want none", i, choice)
+		}
+	}
+}
+
+func TestToolChoiceProvider(t *testing.T) {
+	t.Parallel()
+
+	model := &toolChoiceModel{multiToolModel: multiToolModel{calls: []string{"extract"}}}
+	var iterations []int
+	provider := func(_ context.Context, iteration int, _ *ModelRequest) (*ToolChoice, error) {
+		iterations = append(iterations, iteration)
+		if iteration == 0 {
+			return &ToolChoice{Mode: ToolChoiceRequired}, nil
+		}
+		return &ToolChoice{Mode: ToolChoiceNone}, nil
+	}
+	if _, err := runToolAgent(t, model, WithTools(noopTool()),
+		WithToolChoice(ToolChoice{Mode: ToolChoiceAuto}), WithToolChoiceProvider(provider)); err != nil {
+		t.Fatal(err)
+	}
+	if len(iterations) != 2 || iterations[0] != 0 || iterations[1] != 1 {
+		t.Fatalf("iterations = %v, want [0 1]", iterations)
+	}
+	if model.choices[0].Mode != ToolChoiceRequired || model.choices[1].Mode != ToolChoiceNone {
+		t.Fatalf("choices = %+v, %+v", model.choices[0], model.choices[1])
+	}
+
+	failing := func(context.Context, int, *ModelRequest) (*ToolChoice, error) {
+		return nil, errors.New("no choice")
+	}
+	if _, err := runToolAgent(t, &multiToolModel{}, WithToolChoiceProvider(failing)); err == nil || err.Error() != "no choice" {
+		t.Fatalf("err = %v, want the provider error", err)
+	}
+}
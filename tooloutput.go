@@ -0,0 +1,182 @@
+package blades
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/go-kratos/blades/tools"
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// ReadArtifactToolName is the name of the tool an agent provides to page
+// through tool outputs offloaded by its ToolOutputPolicy.
+const ReadArtifactToolName = "read_artifact"
+
+// ToolOutputPolicy keeps large tool outputs out of the context window. A tool
+// response larger than MaxBytes is saved as an artifact and replaced with a
+// preview and the artifact name; the model reads the rest with the
+// read_artifact tool, which the agent provides automatically.
+type ToolOutputPolicy struct {
+	// MaxBytes is the largest response sent to the model as is. It also
+	// bounds each page returned by read_artifact. Zero disables offloading.
+	MaxBytes int
+	// PreviewBytes is how much of an offloaded response is kept inline.
+	// Zero keeps a quarter of MaxBytes.
+	PreviewBytes int
+	// Store keeps the full responses. When nil, the store from the context
+	// (see NewArtifactContext) is used, or else an in-memory store owned by
+	// the agent.
+	Store ArtifactStore
+}
+
+// previewBytes returns the size of the inline preview.
+func (p ToolOutputPolicy) previewBytes() int {
+	if p.PreviewBytes > 0 {
+		return min(p.PreviewBytes, p.MaxBytes)
+	}
+	return p.MaxBytes / 4
+}
+
+// WithToolOutputPolicy offloads tool responses larger than the policy allows
+// into an artifact store, see ToolOutputPolicy. By default responses are sent
+// to the model whole.
+func WithToolOutputPolicy(policy ToolOutputPolicy) AgentOption {
+	return func(a *agent) {
+		a.toolOutput = policy
+		if policy.Store == nil {
+			a.toolOutputStore = NewInMemoryArtifactStore()
+		}
+	}
+}
+
+// artifactStore returns the store used for offloaded tool outputs.
+func (a *agent) artifactStore(ctx context.Context) ArtifactStore {
+	if a.toolOutput.Store != nil {
+		return a.toolOutput.Store
+	}
+	if store, ok := ArtifactStoreFromContext(ctx); ok {
+		return store
+	}
+	return a.toolOutputStore
+}
+
+// offloadToolOutput saves a response larger than the policy allows as an
+// artifact and replaces it with a preview.
+func (a *agent) offloadToolOutput(ctx context.Context, part ToolPart) (ToolPart, error) {
+	policy := a.toolOutput
+	if policy.MaxBytes <= 0 || len(part.Response) <= policy.MaxBytes || part.Name == ReadArtifactToolName {
+		return part, nil
+	}
+	session, ok := SessionFromContext(ctx)
+	if !ok {
+		return part, ErrNoSessionContext
+	}
+	name := "tool_output/" + part.Name
+	if part.ID != "" {
+		name += "/" + part.ID
+	}
+	version, err := a.artifactStore(ctx).Save(ctx, session.ID(), &Artifact{
+		Name:     name,
+		MIMEType: MIMEText,
+		Data:     []byte(part.Response),
+	})
+	if err != nil {
+		return part, err
+	}
+	preview := utf8Prefix(part.Response, policy.previewBytes())
+	part.Response = fmt.Sprintf("%s\n\n[Output truncated: showing the first %d of %d bytes. "+
+		"The full output is stored as artifact %q (version %d); call %s with offset %d to read the rest.]",
+		preview, len(preview), len(part.Response), name, version, ReadArtifactToolName, len(preview))
+	return part, nil
+}
+
+// utf8Prefix returns at most n bytes of s without splitting a character.
+func utf8Prefix(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// withReadArtifactTool adds the read_artifact tool when tool outputs may be
+// offloaded and no tool of that name is already present.
+func (a *agent) withReadArtifactTool(list []tools.Tool) []tools.Tool {
+	if a.toolOutput.MaxBytes <= 0 {
+		return list
+	}
+	if slices.ContainsFunc(list, func(t tools.Tool) bool { return t.Name() == ReadArtifactToolName }) {
+		return list
+	}
+	return append(list, newReadArtifactTool(a))
+}
+
+// ReadArtifactInput is the argument schema of the read_artifact tool.
+type ReadArtifactInput struct {
+	Name    string `json:"name"              jsonschema:"Name of the artifact to read."`
+	Version int    `json:"version,omitempty" jsonschema:"Version of the artifact; 0 or omitted reads the latest."`
+	Offset  int    `json:"offset,omitempty"  jsonschema:"Byte offset to start reading from."`
+	Limit   int    `json:"limit,omitempty"   jsonschema:"Maximum number of bytes to read."`
+}
+
+// readArtifactTool pages through artifacts saved for the current session.
+type readArtifactTool struct {
+	agent       *agent
+	inputSchema *jsonschema.Schema
+}
+
+func newReadArtifactTool(a *agent) *readArtifactTool {
+	schema, _ := jsonschema.For[ReadArtifactInput](nil)
+	return &readArtifactTool{agent: a, inputSchema: schema}
+}
+
+func (t *readArtifactTool) Name() string                     { return ReadArtifactToolName }
+func (t *readArtifactTool) InputSchema() *jsonschema.Schema  { return t.inputSchema }
+func (t *readArtifactTool) OutputSchema() *jsonschema.Schema { return nil }
+
+func (t *readArtifactTool) Description() string {
+	return "Read a page of an artifact, such as a truncated tool output. Continue from the next offset reported until the end is reached."
+}
+
+// Handle returns the requested page, preceded by a line giving its byte range
+// and the offset of the next page.
+func (t *readArtifactTool) Handle(ctx context.Context, input string) (string, error) {
+	var req ReadArtifactInput
+	if err := json.Unmarshal([]byte(input), &req); err != nil {
+		return "", err
+	}
+	session, ok := SessionFromContext(ctx)
+	if !ok {
+		return "", ErrNoSessionContext
+	}
+	artifact, err := t.agent.artifactStore(ctx).Load(ctx, session.ID(), req.Name, req.Version)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, req.Name)
+	}
+	data := string(artifact.Data)
+	if req.Offset < 0 || req.Offset > len(data) {
+		return "", fmt.Errorf("offset %d out of range [0, %d]", req.Offset, len(data))
+	}
+	limit := t.agent.toolOutput.MaxBytes
+	if req.Limit > 0 && req.Limit < limit {
+		limit = req.Limit
+	}
+	// Start on a character boundary, then cut the page at one.
+	start := req.Offset
+	for start < len(data) && !utf8.RuneStart(data[start]) {
+		start++
+	}
+	page := utf8Prefix(data[start:], limit)
+	end := start + len(page)
+	next := fmt.Sprintf("next offset %d", end)
+	if end == len(data) {
+		next = "end of artifact"
+	}
+	return fmt.Sprintf("[%s version %d: bytes %d-%d of %d, %s]\n%s",
+		artifact.Name, artifact.Version, start, end, len(data), next, page), nil
+}
@@ -0,0 +1,146 @@
+package blades
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	bladestools "github.com/go-kratos/blades/tools"
+)
+
+// pagingModel calls each scripted tool in turn, one per iteration, then
+// answers with every tool response it received joined by "|".
+type pagingModel struct {
+	calls []ToolPart
+	tools []string // names of the tools offered on the first request
+}
+
+func (m *pagingModel) Name() string { return "paging" }
+
+func (m *pagingModel) Generate(_ context.Context, req *ModelRequest) (*ModelResponse, error) {
+	if m.tools == nil {
+		for _, tool := range req.Tools {
+			m.tools = append(m.tools, tool.Name())
+		}
+	}
+	var responses []string
+	for _, message := range req.Messages {
+		for _, part := range message.Parts {
+			if tool, ok := part.(ToolPart); ok && message.Role == RoleTool {
+				responses = append(responses, tool.Response)
+			}
+		}
+	}
+	if len(responses) < len(m.calls) {
+		msg := NewAssistantMessage(StatusCompleted)
+		msg.Role = RoleTool
+		msg.Parts = append(msg.Parts, m.calls[len(responses)])
+		return &ModelResponse{Message: msg}, nil
+	}
+	return &ModelResponse{Message: AssistantMessage(strings.Join(responses, "|"))}, nil
+}
+
+func (m *pagingModel) NewStreaming(context.Context, *ModelRequest) Generator[*ModelResponse, error] {
+	return nil
+}
+
+func readArtifactCall(id, name string, offset int) ToolPart {
+	args, _ := json.Marshal(ReadArtifactInput{Name: name, Offset: offset})
+	return NewToolPart(id, ReadArtifactToolName, string(args))
+}
+
+func TestToolOutputOffloadAndPaging(t *testing.T) {
+	t.Parallel()
+
+	full := strings.Repeat("0123456789", 10) // 100 bytes
+	big := bladestools.NewTool("big", "big output", bladestools.HandleFunc(func(context.Context, string) (string, error) {
+		return full, nil
+	}))
+	store := NewInMemoryArtifactStore()
+	model := &pagingModel{calls: []ToolPart{
+		NewToolPart("call_1", "big", "{}"),
+		readArtifactCall("call_2", "tool_output/big/call_1", 10),
+		readArtifactCall("call_3", "tool_output/big/call_1", 50),
+	}}
+	session := NewSession()
+	agent, err := NewAgent("pager", WithModel(model), WithTools(big),
+		WithToolOutputPolicy(ToolOutputPolicy{MaxBytes: 40, PreviewBytes: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := NewArtifactContext(context.Background(), store)
+	output, err := NewRunner(agent).Run(ctx, UserMessage("go"), WithSession(session))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"big", ReadArtifactToolName}; strings.Join(model.tools, ",") != strings.Join(want, ",") {
+		t.Fatalf("tools = %v, want %v", model.tools, want)
+	}
+
+	responses := strings.Split(output.Text(), "|")
+	if len(responses) != 3 {
+		t.Fatalf("responses = %q", responses)
+	}
+	if !strings.HasPrefix(responses[0], "0123456789\n\n[Output truncated: showing the first 10 of 100 bytes.") ||
+		!strings.Contains(responses[0], `artifact "tool_output/big/call_1" (version 1)`) {
+		t.Fatalf("truncated response = %q", responses[0])
+	}
+	if want := "[tool_output/big/call_1 version 1: bytes 10-50 of 100, next offset 50]\n" + full[10:50]; responses[1] != want {
+		t.Fatalf("page 1 = %q, want %q", responses[1], want)
+	}
+	if want := "[tool_output/big/call_1 version 1: bytes 50-90 of 100, next offset 90]\n" + full[50:90]; responses[2] != want {
+		t.Fatalf("page 2 = %q, want %q", responses[2], want)
+	}
+
+	artifact, err := store.Load(context.Background(), session.ID(), "tool_output/big/call_1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(artifact.Data) != full || artifact.MIMEType != MIMEText {
+		t.Fatalf("artifact = %+v", artifact)
+	}
+}
+
+func TestToolOutputBelowThresholdIsKept(t *testing.T) {
+	t.Parallel()
+
+	small := bladestools.NewTool("small", "small output", bladestools.HandleFunc(func(context.Context, string) (string, error) {
+		return "short", nil
+	}))
+	output, err := runToolAgent(t, &multiToolModel{calls: []string{"small"}},
+		WithTools(small), WithToolOutputPolicy(ToolOutputPolicy{MaxBytes: 40}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Text() != "short" {
+		t.Fatalf("output = %q", output.Text())
+	}
+}
+
+func TestReadArtifactKeepsCharactersWhole(t *testing.T) {
+	t.Parallel()
+
+	a := &agent{toolOutput: ToolOutputPolicy{MaxBytes: 4}, toolOutputStore: NewInMemoryArtifactStore()}
+	session := NewSession()
+	ctx := NewSessionContext(context.Background(), session)
+	if _, err := a.toolOutputStore.Save(ctx, session.ID(), &Artifact{Name: "text", Data: []byte("héllo")}); err != nil {
+		t.Fatal(err)
+	}
+	tool := newReadArtifactTool(a)
+	// Offset 2 falls inside "é"; the page starts after it and stops before
+	// a character that would not fit.
+	page, err := tool.Handle(ctx, `{"name":"text","offset":2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[text version 1: bytes 3-6 of 6, end of artifact]\nllo"; page != want {
+		t.Fatalf("page = %q, want %q", page, want)
+	}
+	if got := utf8Prefix("héllo", 2); got != "h" {
+		t.Fatalf("utf8Prefix = %q, want %q", got, "h")
+	}
+	if _, err := tool.Handle(ctx, `{"name":"missing"}`); err == nil {
+		t.Fatal("expected error for missing artifact")
+	}
+}
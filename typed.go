@@ -0,0 +1,171 @@
+package blades
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// TypedAgent is an Agent whose input and output are Go values of types I and O.
+// The input and output schemas are derived from the types, the input is sent to
+// the model as JSON and the final answer is decoded into O. A TypedAgent is
+// still an Agent, so it can be composed with flow agents or wrapped with
+// NewAgentTool, which then exposes the derived schemas.
+//
+// A string input is sent as plain text, and a string output is the plain text
+// of the final answer without an output schema.
+type TypedAgent[I, O any] struct {
+	Agent
+	inputSchema  *jsonschema.Schema
+	outputSchema *jsonschema.Schema
+}
+
+// TypedChunk is an element of a TypedAgent stream.
+type TypedChunk[O any] struct {
+	// Message is a message yielded by the agent.
+	Message *Message
+	// Output is the decoded final answer; it is set only when Final is true.
+	Output O
+	// Final marks the last chunk, which repeats the final message.
+	Final bool
+}
+
+// InvokeOption configures TypedAgent.Invoke and InvokeStream. Both RunOption
+// and RunnerOption values are InvokeOptions: the former configure the run and
+// the latter the Runner that executes it.
+type InvokeOption interface {
+	applyInvoke(*invokeOptions)
+}
+
+type invokeOptions struct {
+	run    []RunOption
+	runner []RunnerOption
+}
+
+func (o RunOption) applyInvoke(opts *invokeOptions) {
+	opts.run = append(opts.run, o)
+}
+
+func (o RunnerOption) applyInvoke(opts *invokeOptions) {
+	opts.runner = append(opts.runner, o)
+}
+
+func newInvokeOptions(opts []InvokeOption) invokeOptions {
+	var o invokeOptions
+	for _, opt := range opts {
+		opt.applyInvoke(&o)
+	}
+	return o
+}
+
+// NewTypedAgent creates a TypedAgent. The schemas derived from I and O replace
+// any set with WithInputSchema or WithOutputSchema.
+func NewTypedAgent[I, O any](name string, opts ...AgentOption) (*TypedAgent[I, O], error) {
+	inputSchema, err := jsonschema.For[I](nil)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithInputSchema(inputSchema))
+	var outputSchema *jsonschema.Schema
+	if !isString[O]() {
+		if outputSchema, err = jsonschema.For[O](nil); err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithOutputSchema(outputSchema))
+	}
+	agent, err := NewAgent(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedAgent[I, O]{
+		Agent:        agent,
+		inputSchema:  inputSchema,
+		outputSchema: outputSchema,
+	}, nil
+}
+
+// InputSchema returns the schema derived from I.
+func (a *TypedAgent[I, O]) InputSchema() *jsonschema.Schema {
+	return a.inputSchema
+}
+
+// OutputSchema returns the schema derived from O, or nil when O is a string.
+func (a *TypedAgent[I, O]) OutputSchema() *jsonschema.Schema {
+	return a.outputSchema
+}
+
+// Invoke runs the agent with the given input and returns the decoded final
+// answer. The options may mix RunOptions and RunnerOptions.
+func (a *TypedAgent[I, O]) Invoke(ctx context.Context, input I, opts ...InvokeOption) (O, error) {
+	var output O
+	message, err := encodeInput(input)
+	if err != nil {
+		return output, err
+	}
+	o := newInvokeOptions(opts)
+	final, err := NewRunner(a, o.runner...).Run(ctx, message, o.run...)
+	if err != nil {
+		return output, err
+	}
+	return decodeTyped[O](final)
+}
+
+// InvokeStream runs the agent in streaming mode. Every message produced by the
+// agent is yielded as it arrives, followed by a final chunk with the decoded
+// answer.
+func (a *TypedAgent[I, O]) InvokeStream(ctx context.Context, input I, opts ...InvokeOption) Generator[TypedChunk[O], error] {
+	return func(yield func(TypedChunk[O], error) bool) {
+		message, err := encodeInput(input)
+		if err != nil {
+			yield(TypedChunk[O]{}, err)
+			return
+		}
+		o := newInvokeOptions(opts)
+		var last *Message
+		for message, err := range NewRunner(a, o.runner...).RunStream(ctx, message, o.run...) {
+			if err != nil {
+				yield(TypedChunk[O]{}, err)
+				return
+			}
+			last = message
+			if !yield(TypedChunk[O]{Message: message}, nil) {
+				return
+			}
+		}
+		output, err := decodeTyped[O](last)
+		if err != nil {
+			yield(TypedChunk[O]{}, err)
+			return
+		}
+		yield(TypedChunk[O]{Message: last, Output: output, Final: true}, nil)
+	}
+}
+
+func isString[T any]() bool {
+	var v T
+	_, ok := any(v).(string)
+	return ok
+}
+
+func encodeInput[I any](input I) (*Message, error) {
+	if s, ok := any(input).(string); ok {
+		return UserMessage(s), nil
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	return UserMessage(string(data)), nil
+}
+
+func decodeTyped[O any](message *Message) (O, error) {
+	if isString[O]() {
+		var output O
+		if message == nil {
+			return output, ErrNoFinalResponse
+		}
+		return any(message.Text()).(O), nil
+	}
+	return DecodeOutput[O](message)
+}
@@ -0,0 +1,135 @@
+package blades
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type translateInput struct {
+	Text     string `json:"text"`
+	Language string `json:"language"`
+}
+
+type translateOutput struct {
+	Translation string `json:"translation"`
+}
+
+func TestTypedAgentInvoke(t *testing.T) {
+	t.Parallel()
+
+	model := &scriptedOutputModel{answers: []string{`{"translation":"bonjour"}`}}
+	agent, err := NewTypedAgent[translateInput, translateOutput]("translator", WithModel(model))
+	if err != nil {
+		t.Fatalf("new typed agent: %v", err)
+	}
+	output, err := agent.Invoke(context.Background(), translateInput{Text: "hello", Language: "fr"})
+	if err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+	if output.Translation != "bonjour" {
+		t.Fatalf("output = %+v", output)
+	}
+	req := model.requests[0]
+	if req.InputSchema == nil || req.OutputSchema == nil {
+		t.Fatal("model request should carry the derived schemas")
+	}
+	var sent translateInput
+	if err := json.Unmarshal([]byte(req.Messages[len(req.Messages)-1].Text()), &sent); err != nil {
+		t.Fatalf("input was not sent as JSON: %v", err)
+	}
+	if sent != (translateInput{Text: "hello", Language: "fr"}) {
+		t.Fatalf("sent input = %+v", sent)
+	}
+
+	tool := NewAgentTool(agent)
+	if tool.InputSchema() == nil || tool.OutputSchema() == nil {
+		t.Fatal("agent tool should expose the typed agent schemas")
+	}
+}
+
+func TestTypedAgentStringOutput(t *testing.T) {
+	t.Parallel()
+
+	model := &scriptedOutputModel{answers: []string{"plain answer"}}
+	agent, err := NewTypedAgent[string, string]("echo", WithModel(model))
+	if err != nil {
+		t.Fatalf("new typed agent: %v", err)
+	}
+	output, err := agent.Invoke(context.Background(), "question")
+	if err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+	if output != "plain answer" {
+		t.Fatalf("output = %q", output)
+	}
+	if model.requests[0].OutputSchema != nil {
+		t.Fatal("string output should not set an output schema")
+	}
+	if got := model.requests[0].Messages[0].Text(); got != "question" {
+		t.Fatalf("string input sent as %q", got)
+	}
+}
+
+func TestTypedAgentInvokeStream(t *testing.T) {
+	t.Parallel()
+
+	model := &scriptedStreamingModel{
+		streamResponses: []*ModelResponse{
+			streamingResponse(StatusIncomplete, `{"translation":`),
+			streamingResponse(StatusCompleted, `{"translation":"hola"}`),
+		},
+	}
+	agent, err := NewTypedAgent[translateInput, translateOutput]("translator", WithModel(model))
+	if err != nil {
+		t.Fatalf("new typed agent: %v", err)
+	}
+	var chunks []TypedChunk[translateOutput]
+	for chunk, err := range agent.InvokeStream(context.Background(), translateInput{Text: "hello", Language: "es"}) {
+		if err != nil {
+			t.Fatalf("stream: %v", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("chunks = %d, want 3", len(chunks))
+	}
+	last := chunks[len(chunks)-1]
+	if !last.Final || last.Output.Translation != "hola" {
+		t.Fatalf("final chunk = %+v", last)
+	}
+	if chunks[0].Final || chunks[1].Final {
+		t.Fatal("only the last chunk should be final")
+	}
+}
+
+func TestTypedAgentInvokeRunnerOptions(t *testing.T) {
+	t.Parallel()
+
+	model := &usageModel{
+		ModelProvider: &scriptedOutputModel{answers: []string{`{"translation":"ciao"}`, `{"translation":"ciao"}`}},
+		usage:         TokenUsage{InputTokens: 10, OutputTokens: 5},
+	}
+	agent, err := NewTypedAgent[translateInput, translateOutput]("translator", WithModel(model))
+	if err != nil {
+		t.Fatalf("new typed agent: %v", err)
+	}
+	session := NewSession()
+	budget := WithTokenBudget(TokenBudget{Invocation: TokenUsage{OutputTokens: 4}})
+	input := translateInput{Text: "hello", Language: "it"}
+	if _, err := agent.Invoke(context.Background(), input, WithSession(session), budget); !errors.Is(err, ErrTokenBudgetExceeded) {
+		t.Fatalf("invoke error = %v, want %v", err, ErrTokenBudgetExceeded)
+	}
+	if got := SessionUsage(session); got.OutputTokens != 5 {
+		t.Fatalf("session usage = %+v, want the run recorded in the session", got)
+	}
+	for _, err = range agent.InvokeStream(context.Background(), input, budget) {
+		if err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, ErrTokenBudgetExceeded) {
+		t.Fatalf("stream error = %v, want %v", err, ErrTokenBudgetExceeded)
+	}
+}
@@ -0,0 +1,161 @@
+package blades
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+const (
+	// StateKeyUsage is the session state key holding the TokenUsage accumulated
+	// by all invocations of the session.
+	StateKeyUsage = "blades:usage"
+	// StateKeyInvocationUsage is the session state key holding the UsageReport
+	// of the latest invocation.
+	StateKeyInvocationUsage = "blades:invocation_usage"
+	// MetadataKeyUsage is the Message.Metadata key under which Runner.Run
+	// attaches the UsageReport of the invocation to the final message.
+	MetadataKeyUsage = "usage"
+)
+
+// UsageReport summarizes the tokens consumed by an invocation, across all
+// iterations, sub-agents and flow agents.
+type UsageReport struct {
+	InvocationID string     `json:"invocationId"`
+	ModelCalls   int        `json:"modelCalls"`
+	Usage        TokenUsage `json:"usage"`
+}
+
+// TokenBudget limits the tokens a run may consume. Zero fields are unlimited.
+type TokenBudget struct {
+	// Invocation limits the usage of a single invocation.
+	Invocation TokenUsage
+	// Session limits the usage accumulated by all invocations of the session.
+	Session TokenUsage
+}
+
+// Add returns the sum of two usages.
+func (u TokenUsage) Add(other TokenUsage) TokenUsage {
+	return TokenUsage{
+		InputTokens:       u.InputTokens + other.InputTokens,
+		OutputTokens:      u.OutputTokens + other.OutputTokens,
+		TotalTokens:       u.TotalTokens + other.TotalTokens,
+		CachedInputTokens: u.CachedInputTokens + other.CachedInputTokens,
+	}
+}
+
+// exceeds reports whether u is over any non-zero limit.
+func (u TokenUsage) exceeds(limit TokenUsage) bool {
+	return (limit.InputTokens > 0 && u.InputTokens > limit.InputTokens) ||
+		(limit.OutputTokens > 0 && u.OutputTokens > limit.OutputTokens) ||
+		(limit.TotalTokens > 0 && u.TotalTokens > limit.TotalTokens)
+}
+
+// SessionUsage returns the token usage accumulated by the session.
+func SessionUsage(session Session) TokenUsage {
+	var usage TokenUsage
+	if session != nil {
+		decodeState(session.State()[StateKeyUsage], &usage)
+	}
+	return usage
+}
+
+// MessageUsage returns the UsageReport attached to a final message by Runner.Run.
+func MessageUsage(message *Message) (UsageReport, bool) {
+	var report UsageReport
+	if message == nil || message.Metadata == nil {
+		return report, false
+	}
+	value, ok := message.Metadata[MetadataKeyUsage]
+	return report, ok && decodeState(value, &report)
+}
+
+// decodeState converts a state value into v. Values keep their Go type in
+// memory but become generic JSON values once a session is persisted.
+func decodeState[T any](value any, v *T) bool {
+	switch value := value.(type) {
+	case nil:
+		return false
+	case T:
+		*v = value
+		return true
+	case *T:
+		*v = *value
+		return true
+	}
+	data, err := json.Marshal(value)
+	return err == nil && json.Unmarshal(data, v) == nil
+}
+
+type ctxUsageKey struct{}
+
+// usageTracker accumulates the usage of an invocation and enforces its budget.
+type usageTracker struct {
+	mu      sync.Mutex
+	report  UsageReport
+	session TokenUsage // session usage before the invocation
+	budget  TokenBudget
+}
+
+func newUsageTracker(invocationID string, session Session, budget TokenBudget) *usageTracker {
+	return &usageTracker{
+		report:  UsageReport{InvocationID: invocationID},
+		session: SessionUsage(session),
+		budget:  budget,
+	}
+}
+
+// newUsageContext returns a context carrying a new usage tracker.
+func newUsageContext(ctx context.Context, tracker *usageTracker) context.Context {
+	return context.WithValue(ctx, ctxUsageKey{}, tracker)
+}
+
+func usageFromContext(ctx context.Context) (*usageTracker, bool) {
+	tracker, ok := ctx.Value(ctxUsageKey{}).(*usageTracker)
+	return tracker, ok
+}
+
+// check returns a TokenBudgetError if the usage so far is over budget.
+func (t *usageTracker) check() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.checkLocked()
+}
+
+func (t *usageTracker) checkLocked() error {
+	if t.report.Usage.exceeds(t.budget.Invocation) {
+		return &TokenBudgetError{Scope: BudgetScopeInvocation, Limit: t.budget.Invocation, Usage: t.report.Usage}
+	}
+	if total := t.session.Add(t.report.Usage); total.exceeds(t.budget.Session) {
+		return &TokenBudgetError{Scope: BudgetScopeSession, Limit: t.budget.Session, Usage: total}
+	}
+	return nil
+}
+
+// add records the usage of a model response and checks the budget.
+func (t *usageTracker) add(usage TokenUsage) error {
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.report.ModelCalls++
+	t.report.Usage = t.report.Usage.Add(usage)
+	return t.checkLocked()
+}
+
+func (t *usageTracker) snapshot() UsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.report
+}
+
+// save stores the invocation report and the new session total in the session.
+func (t *usageTracker) save(session Session) UsageReport {
+	report := t.snapshot()
+	if session != nil {
+		session.SetState(StateKeyUsage, t.session.Add(report.Usage))
+		session.SetState(StateKeyInvocationUsage, report)
+	}
+	return report
+}
@@ -0,0 +1,123 @@
+package blades
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	bladestools "github.com/go-kratos/blades/tools"
+)
+
+// usageModel wraps a model and reports a fixed token usage on every response.
+type usageModel struct {
+	ModelProvider
+	usage TokenUsage
+}
+
+func (m *usageModel) Generate(ctx context.Context, req *ModelRequest) (*ModelResponse, error) {
+	res, err := m.ModelProvider.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	res.Message.TokenUsage = m.usage
+	return res, nil
+}
+
+func (m *usageModel) NewStreaming(ctx context.Context, req *ModelRequest) Generator[*ModelResponse, error] {
+	return func(yield func(*ModelResponse, error) bool) {
+		for res, err := range m.ModelProvider.NewStreaming(ctx, req) {
+			if err == nil && res.Message.Status == StatusCompleted {
+				res.Message.TokenUsage = m.usage
+			}
+			if !yield(res, err) {
+				return
+			}
+		}
+	}
+}
+
+func TestRunnerUsageReport(t *testing.T) {
+	t.Parallel()
+
+	sub, err := NewAgent("sub", WithModel(&usageModel{
+		ModelProvider: &scriptedOutputModel{answers: []string{"sub answer"}},
+		usage:         TokenUsage{InputTokens: 1, OutputTokens: 1},
+	}))
+	if err != nil {
+		t.Fatalf("new sub agent: %v", err)
+	}
+	subTool := bladestools.NewTool("delete_file", "delegates to a sub-agent", NewAgentTool(sub))
+	root, err := NewAgent("root",
+		WithModel(&usageModel{ModelProvider: &approvalModel{}, usage: TokenUsage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15}}),
+		WithTools(subTool),
+	)
+	if err != nil {
+		t.Fatalf("new agent: %v", err)
+	}
+	session := NewSession()
+	runner := NewRunner(root)
+	output, err := runner.Run(context.Background(), UserMessage("go"), WithSession(session), WithInvocationID("inv-1"))
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	report, ok := MessageUsage(output)
+	if !ok {
+		t.Fatal("final message has no usage report")
+	}
+	// Two root iterations and one sub-agent call.
+	want := UsageReport{InvocationID: "inv-1", ModelCalls: 3, Usage: TokenUsage{InputTokens: 21, OutputTokens: 11, TotalTokens: 32}}
+	if report != want {
+		t.Fatalf("report = %+v, want %+v", report, want)
+	}
+	history, err := session.History(context.Background())
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if _, ok := MessageUsage(history[len(history)-1]); ok {
+		t.Fatal("usage report attached to the message in the session history")
+	}
+	if _, err := runner.Run(context.Background(), UserMessage("again"), WithSession(session)); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if got := SessionUsage(session); got.TotalTokens != 64 {
+		t.Fatalf("session usage = %+v, want 64 total tokens", got)
+	}
+}
+
+func TestRunnerTokenBudget(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		budget TokenBudget
+		scope  BudgetScope
+	}{
+		{name: "invocation", budget: TokenBudget{Invocation: TokenUsage{OutputTokens: 4}}, scope: BudgetScopeInvocation},
+		{name: "session", budget: TokenBudget{Session: TokenUsage{TotalTokens: 20}}, scope: BudgetScopeSession},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls int
+			model := &usageModel{ModelProvider: &approvalModel{}, usage: TokenUsage{InputTokens: 10, OutputTokens: 5}}
+			runner := NewRunner(newHookedAgent(t, model, &calls).rootAgent, WithTokenBudget(tt.budget))
+			session := NewSession()
+			session.SetState(StateKeyUsage, TokenUsage{TotalTokens: 10})
+			_, err := runner.Run(context.Background(), UserMessage("go"), WithSession(session))
+			var budgetErr *TokenBudgetError
+			if !errors.Is(err, ErrTokenBudgetExceeded) || !errors.As(err, &budgetErr) {
+				t.Fatalf("run error = %v, want TokenBudgetError", err)
+			}
+			if budgetErr.Scope != tt.scope {
+				t.Fatalf("scope = %q, want %q", budgetErr.Scope, tt.scope)
+			}
+			if calls != 0 {
+				t.Fatalf("tool calls = %d, want the loop stopped by the over-budget response", calls)
+			}
+			if got := SessionUsage(session).TotalTokens; got != 25 {
+				t.Fatalf("session usage = %d, want spent tokens recorded", got)
+			}
+		})
+	}
+}
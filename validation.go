@@ -0,0 +1,205 @@
+package blades
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-kratos/blades/tools"
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// schemaResolver caches resolved JSON schemas by schema pointer.
+type schemaResolver struct {
+	resolved sync.Map // map[*jsonschema.Schema]*jsonschema.Resolved
+}
+
+// resolve returns the resolved form of schema, resolving it on first use.
+func (r *schemaResolver) resolve(schema *jsonschema.Schema) (*jsonschema.Resolved, error) {
+	if v, ok := r.resolved.Load(schema); ok {
+		return v.(*jsonschema.Resolved), nil
+	}
+	resolved, err := schema.Resolve(&jsonschema.ResolveOptions{ValidateDefaults: true})
+	if err != nil {
+		return nil, err
+	}
+	r.resolved.Store(schema, resolved)
+	return resolved, nil
+}
+
+// toolArgumentError is the tool response reported to the model when the
+// arguments of a tool call do not match the tool's input schema.
+type toolArgumentError struct {
+	Error   string             `json:"error"`
+	Tool    string             `json:"tool"`
+	Details string             `json:"details"`
+	Hint    string             `json:"hint"`
+	Schema  *jsonschema.Schema `json:"schema,omitempty"`
+}
+
+func newToolArgumentError(tool tools.Tool, details string) string {
+	data, err := json.Marshal(toolArgumentError{
+		Error:   "invalid_arguments",
+		Tool:    tool.Name(),
+		Details: details,
+		Hint:    "Fix the arguments so they match the schema and call the tool again.",
+		Schema:  tool.InputSchema(),
+	})
+	if err != nil {
+		return "Tool error: invalid arguments for " + tool.Name() + ": " + details
+	}
+	return string(data)
+}
+
+// validateToolArguments checks the JSON arguments of a tool call against the
+// tool's input schema. It returns the arguments to pass to the tool, which
+// differ from the input only when coercion changed them. When the arguments
+// are invalid, the returned message is the model-readable tool response.
+func (a *agent) validateToolArguments(tool tools.Tool, arguments string) (string, string) {
+	schema := tool.InputSchema()
+	if schema == nil {
+		return arguments, ""
+	}
+	resolved, err := a.schemas.resolve(schema)
+	if err != nil {
+		// A broken schema is not something the model can fix.
+		return arguments, ""
+	}
+	raw := arguments
+	if strings.TrimSpace(raw) == "" {
+		raw = "{}"
+	}
+	var instance any
+	if err := json.Unmarshal([]byte(raw), &instance); err != nil {
+		return arguments, newToolArgumentError(tool, "arguments are not valid JSON: "+err.Error())
+	}
+	if a.toolCoercion {
+		instance = coerceValue(instance, schema)
+		if _, ok := instance.(map[string]any); ok {
+			if err := resolved.ApplyDefaults(&instance); err != nil {
+				return arguments, newToolArgumentError(tool, err.Error())
+			}
+		}
+	}
+	if err := resolved.Validate(instance); err != nil {
+		return arguments, newToolArgumentError(tool, err.Error())
+	}
+	if !a.toolCoercion {
+		return arguments, ""
+	}
+	data, err := json.Marshal(instance)
+	if err != nil {
+		return arguments, ""
+	}
+	return string(data), ""
+}
+
+// coerceValue converts string scalars to the number, integer or boolean type
+// required by the schema, recursing into object properties and array items.
+func coerceValue(value any, schema *jsonschema.Schema) any {
+	if schema == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		for name, property := range schema.Properties {
+			if pv, ok := v[name]; ok {
+				v[name] = coerceValue(pv, property)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = coerceValue(v[i], schema.Items)
+		}
+	case string:
+		if schemaAllows(schema, "string") {
+			return value
+		}
+		s := strings.TrimSpace(v)
+		switch {
+		case schemaAllows(schema, "integer"):
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return n
+			}
+		case schemaAllows(schema, "number"):
+			if n, err := strconv.ParseFloat(s, 64); err == nil {
+				return n
+			}
+		case schemaAllows(schema, "boolean"):
+			if b, err := strconv.ParseBool(s); err == nil {
+				return b
+			}
+		}
+	}
+	return value
+}
+
+func schemaAllows(schema *jsonschema.Schema, typ string) bool {
+	return schema.Type == typ || slices.Contains(schema.Types, typ)
+}
+
+// validateOutput checks that the text of the message is a JSON value that
+// conforms to the Agent's output schema.
+func (a *agent) validateOutput(message *Message) error {
+	resolved, err := a.schemas.resolve(a.outputSchema)
+	if err != nil {
+		return err
+	}
+	for _, part := range message.Parts {
+		if refusal, ok := part.(RefusalPart); ok {
+			return fmt.Errorf("%w: model refused: %s", ErrInvalidOutput, refusal.Text)
+		}
+	}
+	var instance any
+	if err := json.Unmarshal([]byte(outputJSON(message.Text())), &instance); err != nil {
+		return fmt.Errorf("%w: not valid JSON: %v", ErrInvalidOutput, err)
+	}
+	if err := resolved.Validate(instance); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOutput, err)
+	}
+	return nil
+}
+
+// outputRepairMessage asks the model to answer again in the required format.
+func outputRepairMessage(err error) *Message {
+	return UserMessage("Your previous response is invalid: " + err.Error() +
+		". Respond again with only a JSON value that conforms to the required output schema.")
+}
+
+// DecodeOutput decodes the JSON text of an Agent's final message into a value
+// of type T. Use it with agents configured by WithOutputSchema, whose final
+// message has been validated against the schema.
+func DecodeOutput[T any](message *Message) (T, error) {
+	var v T
+	if message == nil {
+		return v, ErrNoFinalResponse
+	}
+	if err := json.Unmarshal([]byte(outputJSON(message.Text())), &v); err != nil {
+		return v, fmt.Errorf("%w: %v", ErrInvalidOutput, err)
+	}
+	return v, nil
+}
+
+// outputJSON returns the JSON of an answer, without the Markdown code fence
+// models often wrap it in, such as ```json ... ```.
+func outputJSON(text string) string {
+	text = strings.TrimSpace(text)
+	body, ok := strings.CutPrefix(text, "```")
+	if !ok {
+		return text
+	}
+	body, ok = strings.CutSuffix(body, "```")
+	if !ok {
+		return text
+	}
+	// Drop the info string, such as json, on the opening line.
+	if i := strings.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	} else {
+		return text
+	}
+	return strings.TrimSpace(body)
+}
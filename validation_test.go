@@ -0,0 +1,123 @@
+package blades
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	bladestools "github.com/go-kratos/blades/tools"
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+type forecastRequest struct {
+	City  string `json:"city"`
+	Days  int    `json:"days"`
+	Units string `json:"units,omitempty"`
+}
+
+func newForecastTool(t *testing.T, got *string) bladestools.Tool {
+	t.Helper()
+	schema, err := jsonschema.For[forecastRequest](nil)
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	schema.Properties["units"].Default = json.RawMessage(`"metric"`)
+	return bladestools.NewTool("forecast", "weather forecast",
+		bladestools.HandleFunc(func(_ context.Context, input string) (string, error) {
+			*got = input
+			return "sunny", nil
+		}),
+		bladestools.WithInputSchema(schema),
+	)
+}
+
+func TestHandleToolsValidatesArguments(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		arguments string
+		details   string
+	}{
+		{name: "malformed json", arguments: `{"city":`, details: "not valid JSON"},
+		{name: "missing required", arguments: `{"city":"Paris"}`, details: "days"},
+		{name: "wrong type", arguments: `{"city":"Paris","days":"3"}`, details: "days"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got string
+			tool := newForecastTool(t, &got)
+			a := &agent{toolValidation: true}
+			part, err := a.handleTools(context.Background(), &Invocation{Tools: []bladestools.Tool{tool}},
+				NewToolPart("call_1", "forecast", tt.arguments))
+			if err != nil {
+				t.Fatalf("handleTools: %v", err)
+			}
+			if got != "" {
+				t.Fatalf("tool was invoked with %q", got)
+			}
+			var report toolArgumentError
+			if err := json.Unmarshal([]byte(part.Response), &report); err != nil {
+				t.Fatalf("response is not structured JSON: %q", part.Response)
+			}
+			if report.Error != "invalid_arguments" || report.Tool != "forecast" {
+				t.Fatalf("report = %+v", report)
+			}
+			if !strings.Contains(report.Details, tt.details) {
+				t.Fatalf("details = %q, want mention of %q", report.Details, tt.details)
+			}
+			if report.Schema == nil {
+				t.Fatal("report should include the input schema")
+			}
+		})
+	}
+}
+
+func TestHandleToolsPassesValidArgumentsUnchanged(t *testing.T) {
+	t.Parallel()
+
+	var got string
+	tool := newForecastTool(t, &got)
+	a := &agent{toolValidation: true}
+	arguments := `{"city":"Paris", "days":3}`
+	part, err := a.handleTools(context.Background(), &Invocation{Tools: []bladestools.Tool{tool}},
+		NewToolPart("call_1", "forecast", arguments))
+	if err != nil {
+		t.Fatalf("handleTools: %v", err)
+	}
+	if got != arguments {
+		t.Fatalf("tool input = %q, want %q", got, arguments)
+	}
+	if part.Response != "sunny" {
+		t.Fatalf("response = %q, want sunny", part.Response)
+	}
+}
+
+func TestHandleToolsCoercesArguments(t *testing.T) {
+	t.Parallel()
+
+	var got string
+	tool := newForecastTool(t, &got)
+	a := &agent{toolValidation: true, toolCoercion: true}
+	part, err := a.handleTools(context.Background(), &Invocation{Tools: []bladestools.Tool{tool}},
+		NewToolPart("call_1", "forecast", `{"city":"Paris","days":" 3 "}`))
+	if err != nil {
+		t.Fatalf("handleTools: %v", err)
+	}
+	if part.Response != "sunny" {
+		t.Fatalf("response = %q, want sunny", part.Response)
+	}
+	var req forecastRequest
+	if err := json.Unmarshal([]byte(got), &req); err != nil {
+		t.Fatalf("tool input %q: %v", got, err)
+	}
+	if req != (forecastRequest{City: "Paris", Days: 3, Units: "metric"}) {
+		t.Fatalf("coerced request = %+v", req)
+	}
+	if part.Request != `{"city":"Paris","days":" 3 "}` {
+		t.Fatalf("tool part request should keep the model's arguments, got %q", part.Request)
+	}
+}